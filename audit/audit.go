@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"go.uber.org/zap"
+)
+
+// event outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeFailure = "failure"
+)
+
+// MaxQueryLimit -> upper bound of the events returned by a single query
+const MaxQueryLimit = 500
+
+// ErrNotQueryable -> none of the configured sinks can be queried
+var ErrNotQueryable = errors.New("audit trail has no queryable sink")
+
+// Event -> a security-sensitive action done by the actor to the target
+type Event struct {
+	Time      time.Time `json:"time"`
+	ActorId   uint64    `json:"actorId"`
+	ActorRole string    `json:"actorRole"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Outcome   string    `json:"outcome"`
+	Ip        string    `json:"ip"`
+	RequestId string    `json:"requestId,omitempty"`
+}
+
+// Auditor -> record audit events, recording must not fail the request
+type Auditor interface {
+	Record(ctx context.Context, event Event)
+}
+
+// Sink -> a storage the audit events are appended to
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// Querier -> a sink the stored events can be read back from
+type Querier interface {
+	Query(ctx context.Context, filter Filter) ([]Event, error)
+}
+
+// Filter -> select the events of a query, the zero fields match any event
+//
+//   - Action -> an exact action or a prefix ending with ".", e.g. "orders."
+//   - From, To -> the time range, both inclusive
+//   - Limit -> the number of the newest matching events, MaxQueryLimit at most
+type Filter struct {
+	ActorId uint64
+	Action  string
+	Target  string
+	Outcome string
+	From    time.Time
+	To      time.Time
+	Limit   int
+}
+
+// Matches -> check whether the event is selected by the filter
+func (f Filter) Matches(event Event) bool {
+	if f.ActorId != 0 && event.ActorId != f.ActorId {
+		return false
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			if !strings.HasPrefix(event.Action, f.Action) {
+				return false
+			}
+		} else if event.Action != f.Action {
+			return false
+		}
+	}
+	if f.Target != "" && event.Target != f.Target {
+		return false
+	}
+	if f.Outcome != "" && event.Outcome != f.Outcome {
+		return false
+	}
+	if !f.From.IsZero() && event.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && event.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// limit -> the effective number of events to return
+func (f Filter) limit() int {
+	if f.Limit <= 0 || f.Limit > MaxQueryLimit {
+		return MaxQueryLimit
+	}
+	return f.Limit
+}
+
+// NewEvent -> an event of the action done by the customer of the request,
+// the outcome is a failure if err is set
+func NewEvent(r *http.Request, action, target string, err error) Event {
+	event := Event{
+		Action:    action,
+		Target:    target,
+		Outcome:   OutcomeSuccess,
+		Ip:        ClientIp(r),
+		RequestId: middlewares.GetRequestId(r.Context()),
+	}
+	if err != nil {
+		event.Outcome = OutcomeFailure
+	}
+	if customer, ok := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto); ok && customer != nil {
+		event.ActorId = customer.CustomerId
+		event.ActorRole = customer.Role
+	}
+	return event
+}
+
+// Target -> a "{kind}:{id}" reference of the affected resource
+func Target(kind string, id uint64) string {
+	return fmt.Sprintf("%s:%d", kind, id)
+}
+
+// ClientIp -> the request remote ip without the port
+func ClientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Trail -> the auditor fanning the events out to every sink,
+// a failed sink is logged and doesn't stop the others
+type Trail struct {
+	sinks  []Sink
+	logger *zap.Logger
+}
+
+func InitTrail(logger *zap.Logger, sinks ...Sink) *Trail {
+	return &Trail{
+		sinks:  sinks,
+		logger: logger,
+	}
+}
+
+func (t *Trail) Record(ctx context.Context, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Time = event.Time.UTC()
+	if event.RequestId == "" {
+		event.RequestId = middlewares.GetRequestId(ctx)
+	}
+
+	for _, sink := range t.sinks {
+		if err := sink.Write(ctx, event); err != nil {
+			t.logger.Error("failed to write an audit event",
+				zap.String("sink", fmt.Sprintf("%T", sink)),
+				zap.String("action", event.Action),
+				zap.Error(err))
+		}
+	}
+}
+
+// Query -> read the events back from the first queryable sink
+func (t *Trail) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	for _, sink := range t.sinks {
+		if querier, ok := sink.(Querier); ok {
+			return querier.Query(ctx, filter)
+		}
+	}
+	return nil, ErrNotQueryable
+}
@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"go.uber.org/zap"
+)
+
+// LogSink -> write the events to a zap logger
+type LogSink struct {
+	logger *zap.Logger
+}
+
+func InitLogSink(logger *zap.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Write(ctx context.Context, event Event) error {
+	s.logger.Info("audit",
+		zap.Time("time", event.Time),
+		zap.Uint64("actorId", event.ActorId),
+		zap.String("actorRole", event.ActorRole),
+		zap.String("action", event.Action),
+		zap.String("target", event.Target),
+		zap.String("outcome", event.Outcome),
+		zap.String("ip", event.Ip),
+		zap.String("requestId", event.RequestId))
+	return nil
+}
+
+// ############################################################
+
+// FileSink -> append the events to a json-lines file, one event per line,
+// the file is opened in append-only mode so the written events are never rewritten
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func InitFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{path: path, file: file}, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query -> scan the whole file, the newest matching events first
+func (s *FileSink) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	limit := filter.limit()
+	matched := []Event{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if !filter.Matches(event) {
+			continue
+		}
+		matched = append(matched, event)
+		if len(matched) > limit {
+			matched = matched[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched, nil
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ############################################################
+
+// redisQueryBatch -> number of the stream entries read at once by a query
+const redisQueryBatch = 1000
+
+// RedisSink -> append the events to a redis stream, capped by maxLen if it's set
+type RedisSink struct {
+	cacheService *cache.CacheService
+	maxLen       int64
+}
+
+func InitRedisSink(cacheService *cache.CacheService, maxLen int64) *RedisSink {
+	return &RedisSink{
+		cacheService: cacheService,
+		maxLen:       maxLen,
+	}
+}
+
+func (s *RedisSink) Write(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.cacheService.AppendAuditEvent(ctx, data, s.maxLen)
+}
+
+// Query -> walk the stream from the newest event until the limit is reached,
+// the stream is exhausted or the events get older than filter.From
+func (s *RedisSink) Query(ctx context.Context, filter Filter) ([]Event, error) {
+	limit := filter.limit()
+	matched := []Event{}
+
+	before := ""
+	for {
+		batch, next, err := s.cacheService.GetAuditEvents(ctx, before, redisQueryBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, data := range batch {
+			var event Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				continue
+			}
+			if !filter.From.IsZero() && event.Time.Before(filter.From) {
+				return matched, nil
+			}
+			if filter.Matches(event) {
+				matched = append(matched, event)
+				if len(matched) == limit {
+					return matched, nil
+				}
+			}
+		}
+
+		if len(batch) < redisQueryBatch || next == "" {
+			return matched, nil
+		}
+		before = next
+	}
+}
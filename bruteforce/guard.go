@@ -0,0 +1,171 @@
+package bruteforce
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"go.uber.org/zap"
+)
+
+// attempt scopes, counted apart from each other
+const (
+	SignIn  = "sign-in"
+	TwoStep = "two-step"
+)
+
+// BlockedError -> the attempt is rejected until RetryAfter passes
+type BlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *BlockedError) Error() string {
+	if e.Locked {
+		return "too many failed attempts, try again later"
+	}
+	return "too many attempts, slow down"
+}
+
+// Guard -> track failed attempts per email and per ip
+//
+// every failure after the first one delays the next attempt twice as long
+// (up to maxDelay), and maxAttempts failures within the window lock the key out
+type Guard struct {
+	store       *cache.CacheService
+	logger      *zap.Logger
+	metrics     *guardMetrics
+	maxAttempts int64
+	maxCodes    int64
+	window      time.Duration
+	lockout     time.Duration
+	maxDelay    time.Duration
+}
+
+func InitGuard(cfg config.AuthConfig, store *cache.CacheService, logger *zap.Logger) *Guard {
+	return &Guard{
+		store:       store,
+		logger:      logger,
+		metrics:     initGuardMetrics(),
+		maxAttempts: cfg.MaxFailedAttempts,
+		maxCodes:    cfg.MaxCodeAttempts,
+		window:      cfg.FailedAttemptsWindow,
+		lockout:     cfg.LockoutDuration,
+		maxDelay:    cfg.MaxAttemptDelay,
+	}
+}
+
+// EmailKey -> an attempts key of the customer email
+func EmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IpKey -> an attempts key of the client ip
+func IpKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check -> get a *BlockedError if any of the keys is delayed or locked out
+func (g *Guard) Check(ctx context.Context, scope string, keys ...string) error {
+	blocked := &BlockedError{}
+	for _, key := range keys {
+		ttl, locked, err := g.store.GetAttemptsBlock(ctx, scope+":"+key)
+		if err != nil {
+			return err
+		}
+		if ttl > blocked.RetryAfter {
+			blocked.RetryAfter = ttl
+		}
+		blocked.Locked = blocked.Locked || locked
+	}
+
+	if blocked.RetryAfter > 0 {
+		return blocked
+	}
+	return nil
+}
+
+// Fail -> register a failed attempt by every key
+func (g *Guard) Fail(ctx context.Context, scope string, keys ...string) error {
+	g.metrics.failures.WithLabelValues(scope).Inc()
+
+	for _, key := range keys {
+		count, err := g.store.RegisterFailedAttempt(ctx, scope+":"+key, g.window)
+		if err != nil {
+			return err
+		}
+
+		if count >= g.maxAttempts {
+			if err = g.store.BlockAttempts(ctx, scope+":"+key, g.lockout, true); err != nil {
+				return err
+			}
+			g.metrics.lockouts.WithLabelValues(scope, keyKind(key)).Inc()
+			g.logger.Warn("attempts locked out",
+				zap.String("scope", scope),
+				zap.String("key", key),
+				zap.Int64("failures", count),
+				zap.Duration("duration", g.lockout))
+			continue
+		}
+
+		if delay := g.delay(count); delay > 0 {
+			if err = g.store.BlockAttempts(ctx, scope+":"+key, delay, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reset -> forget the failed attempts after a success
+func (g *Guard) Reset(ctx context.Context, scope string, keys ...string) error {
+	for _, key := range keys {
+		if err := g.store.ClearAttempts(ctx, scope+":"+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FailCode -> register a wrong guess of the emailed code,
+// the code is invalidated after too many of them
+func (g *Guard) FailCode(ctx context.Context, email string) error {
+	count, err := g.store.Fail2FACode(ctx, email)
+	if err != nil {
+		return err
+	}
+	if count < g.maxCodes {
+		return nil
+	}
+
+	g.store.Clear2FACode(ctx, email)
+	g.metrics.lockouts.WithLabelValues(TwoStep, "code").Inc()
+	g.logger.Warn("two-step code invalidated",
+		zap.String("key", EmailKey(email)),
+		zap.Int64("failures", count))
+	return nil
+}
+
+// delay -> 1s after the second failure, doubled by every next one
+func (g *Guard) delay(count int64) time.Duration {
+	if count < 2 {
+		return 0
+	}
+	if count-2 >= 16 {
+		return g.maxDelay
+	}
+	return min(time.Second<<(count-2), g.maxDelay)
+}
+
+// RetryAfterSeconds -> a Retry-After header value of the blocked attempt
+func RetryAfterSeconds(err *BlockedError) string {
+	return fmt.Sprintf("%d", int64((err.RetryAfter+time.Second-1)/time.Second))
+}
+
+func keyKind(key string) string {
+	kind, _, _ := strings.Cut(key, ":")
+	return kind
+}
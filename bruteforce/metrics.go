@@ -0,0 +1,31 @@
+package bruteforce
+
+import (
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// guardMetrics -> failed attempts and lockouts, the lockout rate is the alerting signal
+type guardMetrics struct {
+	failures *prometheus.CounterVec
+	lockouts *prometheus.CounterVec
+}
+
+func initGuardMetrics() *guardMetrics {
+	failures := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_failed_attempts_total",
+			Help: "Total number of failed sign-in and two-step attempts",
+		},
+		[]string{"scope"},
+	))
+	lockouts := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_lockouts_total",
+			Help: "Total number of lockouts by scope and key kind",
+		},
+		[]string{"scope", "key"},
+	))
+
+	return &guardMetrics{failures: failures, lockouts: lockouts}
+}
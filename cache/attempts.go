@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
+)
+
+// RegisterFailedAttempt -> count a failed attempt by the key,
+// the counter is dropped when the window since the first failure ends
+func (s *CacheService) RegisterFailedAttempt(ctx context.Context, key string, window time.Duration) (int64, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+
+	counterKey := fmt.Sprintf("attempts:%s", key)
+
+	var count *redis.IntCmd
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, counterKey)
+		pipe.ExpireNX(ctx, counterKey, window)
+		return nil
+	})
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+	return count.Val(), nil
+}
+
+// BlockAttempts -> reject the next attempts by the key for a while,
+// a lockout is kept apart from a short delay between attempts
+func (s *CacheService) BlockAttempts(ctx context.Context, key string, ttl time.Duration, lockout bool) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	blockKey := fmt.Sprintf("attempts-delay:%s", key)
+	if lockout {
+		blockKey = fmt.Sprintf("attempts-lock:%s", key)
+	}
+
+	if err = client.Set(ctx, blockKey, 1, ttl).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetAttemptsBlock -> get how long the attempts by the key are rejected
+// and whether it's a lockout, zero if the attempts are allowed
+func (s *CacheService) GetAttemptsBlock(ctx context.Context, key string) (time.Duration, bool, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return 0, false, exceptions.HandleAnException(err)
+	}
+
+	ttl, err := client.PTTL(ctx, fmt.Sprintf("attempts-lock:%s", key)).Result()
+	if err != nil {
+		return 0, false, exceptions.HandleAnException(err)
+	}
+	if ttl > 0 {
+		return ttl, true, nil
+	}
+
+	ttl, err = client.PTTL(ctx, fmt.Sprintf("attempts-delay:%s", key)).Result()
+	if err != nil {
+		return 0, false, exceptions.HandleAnException(err)
+	}
+	if ttl > 0 {
+		return ttl, false, nil
+	}
+	return 0, false, nil
+}
+
+// ClearAttempts -> forget the failed attempts by the key after a success
+func (s *CacheService) ClearAttempts(ctx context.Context, key string) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	err = client.Del(ctx,
+		fmt.Sprintf("attempts:%s", key),
+		fmt.Sprintf("attempts-delay:%s", key),
+		fmt.Sprintf("attempts-lock:%s", key),
+	).Err()
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// Fail2FACode -> count a wrong guess of the emailed code,
+// the counter lives as long as the code and is reset with a new one
+func (s *CacheService) Fail2FACode(ctx context.Context, email string) (int64, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+
+	counterKey := fmt.Sprintf("2FA-attempts:%s", email)
+
+	var count *redis.IntCmd
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, counterKey)
+		pipe.ExpireNX(ctx, counterKey, s.timeout)
+		return nil
+	})
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+	return count.Val(), nil
+}
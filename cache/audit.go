@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
+)
+
+// auditStream -> the append-only stream of the audit events
+const auditStream = "audit:events"
+
+// AppendAuditEvent -> add an encoded audit event to the stream, the stream keeps
+// every event unless maxLen is set, then the oldest ones are trimmed past maxLen
+func (s *CacheService) AppendAuditEvent(ctx context.Context, event []byte, maxLen int64) error {
+	client, err := s.connectClient(ctx, "audit")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: auditStream,
+		Values: map[string]any{"event": event},
+	}
+	if maxLen > 0 {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+	if err = client.XAdd(ctx, args).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetAuditEvents -> read up to count encoded audit events, the newest first,
+// starting before the "before" stream id ("" to start from the newest one),
+// returns the events and the id to continue from
+func (s *CacheService) GetAuditEvents(ctx context.Context, before string, count int64) ([][]byte, string, error) {
+	client, err := s.connectClient(ctx, "audit")
+	if err != nil {
+		return nil, "", exceptions.HandleAnException(err)
+	}
+
+	end := "+"
+	if before != "" {
+		end = "(" + before
+	}
+
+	messages, err := client.XRevRangeN(ctx, auditStream, end, "-", count).Result()
+	if err != nil {
+		return nil, "", exceptions.HandleAnException(err)
+	}
+
+	events := make([][]byte, 0, len(messages))
+	next := ""
+	for _, message := range messages {
+		next = message.ID
+		if event, ok := message.Values["event"].(string); ok {
+			events = append(events, []byte(event))
+		}
+	}
+	return events, next, nil
+}
@@ -2,32 +2,36 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
 )
 
-func (s *CacheService) Set2FACode(email, code string) {
-	client, err := s.connectClient("2fa")
+func (s *CacheService) Set2FACode(ctx context.Context, email, code string) {
+	client, err := s.connectClient(ctx, "2fa")
 	if err != nil {
 		exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Set(context.Background(), fmt.Sprintf("2FA:%s", email), code, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("2FA:%s", email), code, s.timeout).Err(); err != nil {
+		exceptions.HandleAnException(err)
+	}
+	if err = client.Del(ctx, fmt.Sprintf("2FA-attempts:%s", email)).Err(); err != nil {
 		exceptions.HandleAnException(err)
 	}
 }
 
-func (s *CacheService) Get2FACode(email string) (string, error) {
-	client, err := s.connectClient("2fa")
+func (s *CacheService) Get2FACode(ctx context.Context, email string) (string, error) {
+	client, err := s.connectClient(ctx, "2fa")
 	if err != nil {
 		return "", exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	c, err := client.Get(context.Background(), fmt.Sprintf("2FA:%s", email)).Result()
+	c, err := client.Get(ctx, fmt.Sprintf("2FA:%s", email)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return "", errors.New("code not found")
@@ -42,14 +46,238 @@ func (s *CacheService) Get2FACode(email string) (string, error) {
 	return c, nil
 }
 
-func (s *CacheService) Clear2FACode(email string) {
-	client, err := s.connectClient("2fa")
+func (s *CacheService) Clear2FACode(ctx context.Context, email string) {
+	client, err := s.connectClient(ctx, "2fa")
 	if err != nil {
 		exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("2FA:%s", email)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("2FA:%s", email), fmt.Sprintf("2FA-attempts:%s", email)).Err(); err != nil {
 		exceptions.HandleAnException(err)
 	}
 }
+
+// PasswordReset -> a pending password reset request
+type PasswordReset struct {
+	CustomerId uint64 `json:"customerId"`
+	Email      string `json:"email"`
+}
+
+// SetPasswordReset -> save a reset request by its id,
+// a previous pending request of the customer is invalidated
+func (s *CacheService) SetPasswordReset(ctx context.Context, resetId string, reset *PasswordReset, ttl time.Duration) error {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	payload, err := json.Marshal(reset)
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	customerKey := fmt.Sprintf("pwd-reset-customer:%d", reset.CustomerId)
+
+	previous, err := client.Get(ctx, customerKey).Result()
+	if err != nil && err.Error() != "redis: nil" {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, fmt.Sprintf("pwd-reset:%s", previous))
+		}
+		pipe.Set(ctx, fmt.Sprintf("pwd-reset:%s", resetId), payload, ttl)
+		pipe.Set(ctx, customerKey, resetId, ttl)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// ConsumePasswordReset -> get and delete a reset request, so it can be used only once
+func (s *CacheService) ConsumePasswordReset(ctx context.Context, resetId string) (*PasswordReset, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	payload, err := client.GetDel(ctx, fmt.Sprintf("pwd-reset:%s", resetId)).Result()
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return nil, errors.New("reset request not found")
+		}
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	var reset *PasswordReset
+	if err = json.Unmarshal([]byte(payload), &reset); err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	client.Del(ctx, fmt.Sprintf("pwd-reset-customer:%d", reset.CustomerId))
+	return reset, nil
+}
+
+// ############################################################################
+// TOTP two-step verification
+
+// SetTwoStepMethod -> save the customer two-step verification method (email or totp)
+func (s *CacheService) SetTwoStepMethod(ctx context.Context, customerId uint64, method string) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Set(ctx, fmt.Sprintf("2fa-method:%d", customerId), method, 0).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetTwoStepMethod -> get the customer two-step verification method, email by default
+func (s *CacheService) GetTwoStepMethod(ctx context.Context, customerId uint64) (string, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return "", exceptions.HandleAnException(err)
+	}
+
+	method, err := client.Get(ctx, fmt.Sprintf("2fa-method:%d", customerId)).Result()
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return "email", nil
+		}
+		return "", exceptions.HandleAnException(err)
+	}
+	return method, nil
+}
+
+// SetTOTPSecret -> save the customer authenticator secret,
+// a pending secret expires unless it's confirmed by a code in time
+func (s *CacheService) SetTOTPSecret(ctx context.Context, customerId uint64, secret string, pending bool) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	key, ttl := fmt.Sprintf("totp:%d", customerId), time.Duration(0)
+	if pending {
+		key, ttl = fmt.Sprintf("totp-pending:%d", customerId), 10*time.Minute
+	}
+
+	if err = client.Set(ctx, key, secret, ttl).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetTOTPSecret -> get the customer confirmed or pending authenticator secret
+func (s *CacheService) GetTOTPSecret(ctx context.Context, customerId uint64, pending bool) (string, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return "", exceptions.HandleAnException(err)
+	}
+
+	key := fmt.Sprintf("totp:%d", customerId)
+	if pending {
+		key = fmt.Sprintf("totp-pending:%d", customerId)
+	}
+
+	secret, err := client.Get(ctx, key).Result()
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return "", errors.New("secret not found")
+		}
+		return "", exceptions.HandleAnException(err)
+	}
+	return secret, nil
+}
+
+// GetTOTPLastStep -> get the time step of the last accepted code
+func (s *CacheService) GetTOTPLastStep(ctx context.Context, customerId uint64) (int64, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+
+	step, err := client.Get(ctx, fmt.Sprintf("totp-step:%d", customerId)).Int64()
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return 0, nil
+		}
+		return 0, exceptions.HandleAnException(err)
+	}
+	return step, nil
+}
+
+// SetTOTPLastStep -> save the time step of the last accepted code to prevent its replay
+func (s *CacheService) SetTOTPLastStep(ctx context.Context, customerId uint64, step int64) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Set(ctx, fmt.Sprintf("totp-step:%d", customerId), step, 5*time.Minute).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// SetRecoveryCodes -> replace the customer recovery code hashes
+func (s *CacheService) SetRecoveryCodes(ctx context.Context, customerId uint64, hashes []string) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	key := fmt.Sprintf("recovery:%d", customerId)
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		for _, hash := range hashes {
+			pipe.SAdd(ctx, key, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// UseRecoveryCode -> remove the recovery code hash if it exists,
+// returns false if the code is unknown or was already used
+func (s *CacheService) UseRecoveryCode(ctx context.Context, customerId uint64, hash string) (bool, error) {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+
+	n, err := client.SRem(ctx, fmt.Sprintf("recovery:%d", customerId), hash).Result()
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+	return n > 0, nil
+}
+
+// ClearTOTP -> remove the authenticator secret, recovery codes and the chosen method
+func (s *CacheService) ClearTOTP(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "2fa")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	err = client.Del(ctx,
+		fmt.Sprintf("totp:%d", customerId),
+		fmt.Sprintf("totp-pending:%d", customerId),
+		fmt.Sprintf("totp-step:%d", customerId),
+		fmt.Sprintf("recovery:%d", customerId),
+		fmt.Sprintf("2fa-method:%d", customerId),
+	).Err()
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
@@ -10,14 +10,13 @@ import (
 )
 
 // ClearBlog -> clear a customer blog
-func (s *CacheService) ClearBlog(customerId uint64) error {
-	client, err := s.connectClient("blog")
+func (s *CacheService) ClearBlog(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "blog")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("blog:%d", customerId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("blog:%d", customerId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
@@ -25,18 +24,17 @@ func (s *CacheService) ClearBlog(customerId uint64) error {
 }
 
 // SetBlog -> set a customer blog ( a list of blog items)
-func (s *CacheService) SetBlog(customerId uint64, dto *blogPb.Blog) error {
-	client, err := s.connectClient("blog")
+func (s *CacheService) SetBlog(ctx context.Context, customerId uint64, dto *blogPb.Blog) error {
+	client, err := s.connectClient(ctx, "blog")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	if err = client.Set(context.Background(), fmt.Sprintf("blog:%d", customerId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("blog:%d", customerId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
@@ -44,14 +42,13 @@ func (s *CacheService) SetBlog(customerId uint64, dto *blogPb.Blog) error {
 }
 
 // GetBlog -> get a customer blog ( a list of blog items)
-func (s *CacheService) GetBlog(customerId uint64) (*blogPb.Blog, error) {
-	client, err := s.connectClient("blog")
+func (s *CacheService) GetBlog(ctx context.Context, customerId uint64) (*blogPb.Blog, error) {
+	client, err := s.connectClient(ctx, "blog")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("blog:%d", customerId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("blog:%d", customerId)).Result()
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/noo8xl/anvil-common/exceptions"
+)
+
+// chatMessagesPattern -> the pub/sub channels of the new chat messages, one per chat
+const chatMessagesPattern = "chat:messages:*"
+
+// PublishChatMessage -> push an encoded message to the chat's channel,
+// every gateway instance gets it to fan it out to the chat's connections
+func (s *CacheService) PublishChatMessage(ctx context.Context, chatId uint64, message []byte) error {
+	client, err := s.connectClient(ctx, "chat")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Publish(ctx, fmt.Sprintf("chat:messages:%d", chatId), message).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// ListenChatMessages -> call handle for every message published to any chat,
+// the subscription is confirmed before ready is called, it lasts until ctx is done
+// or the connection is lost
+func (s *CacheService) ListenChatMessages(ctx context.Context, ready func(), handle func(chatId uint64, message []byte)) error {
+	client, err := s.connectClient(ctx, "chat")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	pubsub := client.PSubscribe(ctx, chatMessagesPattern)
+	defer pubsub.Close()
+
+	if _, err = pubsub.Receive(ctx); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	ready()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return exceptions.HandleAnException(errors.New("chat subscription is closed"))
+			}
+			chatId, err := strconv.ParseUint(strings.TrimPrefix(message.Channel, "chat:messages:"), 10, 64)
+			if err != nil {
+				continue
+			}
+			handle(chatId, []byte(message.Payload))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/noo8xl/anvil-common/exceptions"
@@ -14,45 +15,119 @@ type CacheService struct {
 	timeout time.Duration
 }
 
+// pools -> a connection pool of every cache, shared by all the CacheService instances
+// and kept open until Close
+var pools = struct {
+	sync.Mutex
+	clients map[string]*redis.Client
+}{clients: make(map[string]*redis.Client)}
+
 func InitCacheService() *CacheService {
 	return &CacheService{
 		timeout: time.Millisecond * 300000, // 5 minutes
 	}
 }
 
-func (c *CacheService) connectClient(svcName string) (*redis.Client, error) {
+// connectTimeout -> upper bound of the first ping of a pool,
+// so the callers fail fast while redis is down
+const connectTimeout = 2 * time.Second
+
+// connectClient -> the pooled client of the cache, the pool is opened
+// and checked with a ping on the first use
+//
+// the ping runs outside the lock, so an unreachable redis doesn't queue
+// the callers of every cache behind it, the first pool stored is kept
+// if a few callers open one at once
+func (c *CacheService) connectClient(ctx context.Context, svcName string) (*redis.Client, error) {
+	pools.Lock()
+	client, ok := pools.clients[svcName]
+	pools.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	opts, err := redisOptions(svcName)
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	client = redis.NewClient(opts)
+	client.AddHook(newTracingHook(svcName, opts))
+	client.AddHook(newMetricsHook(svcName))
+
+	pingCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if _, err = client.Ping(pingCtx).Result(); err != nil {
+		client.Close()
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	pools.Lock()
+	defer pools.Unlock()
 
-	var client *redis.Client
-	var opts *redis.Options
-	var err error
+	if pooled, ok := pools.clients[svcName]; ok {
+		client.Close()
+		return pooled, nil
+	}
+	pools.clients[svcName] = client
+	return client, nil
+}
 
+// redisOptions -> the redis config of the cache
+func redisOptions(svcName string) (*redis.Options, error) {
 	switch svcName {
 	case "offers":
-		opts = config.GetOffersRedisConfig()
+		return config.GetOffersRedisConfig(), nil
 	case "profile":
-		opts = config.GetProfileRedisConfig()
+		return config.GetProfileRedisConfig(), nil
 	case "orders":
-		opts = config.GetOrdersRedisConfig()
+		return config.GetOrdersRedisConfig(), nil
 	case "reviews":
-		opts = config.GetReviewsRedisConfig()
+		return config.GetReviewsRedisConfig(), nil
 	case "blog":
-		opts = config.GetBlogRedisConfig()
+		return config.GetBlogRedisConfig(), nil
 	case "promo":
-		opts = config.GetPromoRedisConfig()
+		return config.GetPromoRedisConfig(), nil
 	case "2fa":
-		opts = config.Get2FARedisConfig()
+		return config.Get2FARedisConfig(), nil
 	case "notifications":
-		opts = config.GetNotificationsRedisConfig()
+		return config.GetNotificationsRedisConfig(), nil
+	case "sessions":
+		return config.GetSessionsRedisConfig(), nil
+	case "ratelimit":
+		return config.GetRateLimitRedisConfig(), nil
+	case "audit":
+		return config.GetAuditRedisConfig(), nil
+	case "outbox":
+		return config.GetOutboxRedisConfig(), nil
+	case "chat":
+		return config.GetChatRedisConfig(), nil
 	// case "payments":
-	// 	opts = config.GetPaymentsRedisConfig()
+	// 	return config.GetPaymentsRedisConfig(), nil
 	default:
-		return nil, exceptions.HandleAnException(errors.New("gateway: invalid service name to get redis config"))
+		return nil, errors.New("gateway: invalid service name to get redis config")
 	}
+}
 
-	client = redis.NewClient(opts)
-	_, err = client.Ping(context.Background()).Result()
+// Close -> close the connection pool of every cache, a later call opens a new one,
+// so it's called once every caller is stopped
+func Close() error {
+	pools.Lock()
+	defer pools.Unlock()
+
+	var errs []error
+	for svcName, client := range pools.clients {
+		errs = append(errs, client.Close())
+		delete(pools.clients, svcName)
+	}
+	return errors.Join(errs...)
+}
+
+// Ping -> check the redis server is reachable, every cache shares the same server
+func (c *CacheService) Ping(ctx context.Context) error {
+	client, err := c.connectClient(ctx, "sessions")
 	if err != nil {
-		return nil, exceptions.HandleAnException(err)
+		return err
 	}
-	return client, nil
+	return client.Ping(ctx).Err()
 }
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// lookupCommands -> the commands reading a cached value, a missing key is a cache miss
+var lookupCommands = map[string]bool{
+	"get":    true,
+	"getdel": true,
+}
+
+// cacheMetrics -> latency of every redis command and the hit ratio of the cached values
+type cacheMetrics struct {
+	lookups  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var sharedCacheMetrics = initCacheMetrics()
+
+func initCacheMetrics() *cacheMetrics {
+	lookups := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_lookups_total",
+			Help: "Total number of cached value lookups by cache and result (hit, miss)",
+		},
+		[]string{"cache", "result"},
+	))
+	duration := metrics.Register(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cache_command_duration_seconds",
+			Help:    "Redis command duration in seconds",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"cache", "command"},
+	))
+
+	return &cacheMetrics{lookups: lookups, duration: duration}
+}
+
+// metricsHook -> observe the commands sent to the redis db of one cache
+type metricsHook struct {
+	cache   string
+	metrics *cacheMetrics
+}
+
+func newMetricsHook(svcName string) *metricsHook {
+	return &metricsHook{cache: svcName, metrics: sharedCacheMetrics}
+}
+
+func (h *metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.metrics.duration.WithLabelValues(h.cache, cmd.Name()).Observe(time.Since(start).Seconds())
+
+		if lookupCommands[cmd.Name()] {
+			switch {
+			case err == nil:
+				h.metrics.lookups.WithLabelValues(h.cache, "hit").Inc()
+			case errors.Is(err, redis.Nil):
+				h.metrics.lookups.WithLabelValues(h.cache, "miss").Inc()
+			}
+		}
+		return err
+	}
+}
+
+func (h *metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.metrics.duration.WithLabelValues(h.cache, "pipeline").Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -3,10 +3,15 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	notificationPb "github.com/noo8xl/anvil-api/main/notifications"
 	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
 )
 
 // ###########################################
@@ -15,47 +20,57 @@ import (
 // if customer got a new notification
 // ###########################################
 
-func (s *CacheService) ClearNotifications(notificationId uint64) error {
-	client, err := s.connectClient("notifications")
+// ClearNotifications -> drop every cached list page of the customer
+func (s *CacheService) ClearNotifications(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "notifications")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("notifications:%d", notificationId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("notifications:%d", customerId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetNotificationsList(list *notificationPb.GetNotificationsListResponse) error {
-	client, err := s.connectClient("notifications")
+// SetNotificationsList -> cache a list page of the customer, the pages
+// share a key so a new notification drops all of them at once
+func (s *CacheService) SetNotificationsList(ctx context.Context, customerId uint64, skip uint32, list *notificationPb.GetNotificationsListResponse) error {
+	client, err := s.connectClient(ctx, "notifications")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(list)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("notifications:%d", list.List[0].CustomerId), payload, s.timeout).Err(); err != nil {
+	key := fmt.Sprintf("notifications:%d", customerId)
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, strconv.FormatUint(uint64(skip), 10), payload)
+		pipe.Expire(ctx, key, s.timeout)
+		return nil
+	})
+	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) GetNotificationsList(customerId uint64) (*notificationPb.GetNotificationsListResponse, error) {
-	client, err := s.connectClient("notifications")
+// GetNotificationsList -> a cached list page of the customer or nil if it's not cached
+func (s *CacheService) GetNotificationsList(ctx context.Context, customerId uint64, skip uint32) (*notificationPb.GetNotificationsListResponse, error) {
+	client, err := s.connectClient(ctx, "notifications")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("notifications:%d", customerId)).Result()
+	result, err := client.HGet(ctx, fmt.Sprintf("notifications:%d", customerId), strconv.FormatUint(uint64(skip), 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
@@ -68,3 +83,328 @@ func (s *CacheService) GetNotificationsList(customerId uint64) (*notificationPb.
 	return &list, nil
 
 }
+
+// read state
+//
+// the notifications service doesn't track what is read, so the gateway keeps
+// the ids of the read notifications and an unread counter of every customer:
+//
+//   - notifications:read:{customerId} -> a set of the read notification ids
+//   - notifications:unread:{customerId} -> the unread counter, changed on create, read and delete,
+//     a missing one is unknown and recounted from the list
+//
+// the counter expires to be recounted now and then, so a drift doesn't last
+
+// unreadCounterTTL -> how long the unread counter is trusted before it's recounted
+const unreadCounterTTL = 24 * time.Hour
+
+// readStateTTL -> how long the read notifications of a customer are kept after
+// the last one is read, the set is also pruned to the listed ones on a recount
+const readStateTTL = 30 * 24 * time.Hour
+
+// countNewScript -> count a new notification if the counter is known
+var countNewScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("INCR", KEYS[1])
+`)
+
+// markReadScript -> add the ids to the read ones and uncount the newly read ones,
+// the counter doesn't go below zero, ARGV[1] is the ttl of the read ones
+var markReadScript = redis.NewScript(`
+local added = redis.call("SADD", KEYS[2], unpack(ARGV, 2))
+redis.call("PEXPIRE", KEYS[2], ARGV[1])
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+local unread = redis.call("DECRBY", KEYS[1], added)
+if unread < 0 then
+	unread = redis.call("INCRBY", KEYS[1], -unread)
+end
+return unread
+`)
+
+// forgetScript -> drop a deleted notification, an unread one is uncounted
+var forgetScript = redis.NewScript(`
+local read = redis.call("SREM", KEYS[2], ARGV[1])
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+if read == 1 then
+	return tonumber(redis.call("GET", KEYS[1]))
+end
+local unread = redis.call("DECR", KEYS[1])
+if unread < 0 then
+	unread = redis.call("INCRBY", KEYS[1], -unread)
+end
+return unread
+`)
+
+// pruneReadScript -> keep only the listed ids among the read ones,
+// ARGV[1] is the ttl of the read ones
+var pruneReadScript = redis.NewScript(`
+local listed = {}
+for i = 2, #ARGV do
+	listed[ARGV[i]] = true
+end
+for _, id in ipairs(redis.call("SMEMBERS", KEYS[1])) do
+	if not listed[id] then
+		redis.call("SREM", KEYS[1], id)
+	end
+end
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+func unreadKey(customerId uint64) string {
+	return fmt.Sprintf("notifications:unread:%d", customerId)
+}
+
+func readKey(customerId uint64) string {
+	return fmt.Sprintf("notifications:read:%d", customerId)
+}
+
+// CountNewNotification -> count a notification created for the customer
+// and drop the cached list pages
+func (s *CacheService) CountNewNotification(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = countNewScript.Run(ctx, client, []string{unreadKey(customerId)}).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	if err = client.Del(ctx, fmt.Sprintf("notifications:%d", customerId)).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// MarkNotificationsRead -> mark the notifications of the customer read,
+// the unread counter is returned, -1 if it's unknown
+func (s *CacheService) MarkNotificationsRead(ctx context.Context, customerId uint64, notificationIds ...uint64) (int64, error) {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+	if len(notificationIds) == 0 {
+		unread, known, err := s.GetUnreadNotifications(ctx, customerId)
+		if !known {
+			unread = -1
+		}
+		return unread, err
+	}
+
+	ids := make([]any, 0, len(notificationIds)+1)
+	ids = append(ids, readStateTTL.Milliseconds())
+	for _, id := range notificationIds {
+		ids = append(ids, id)
+	}
+
+	unread, err := markReadScript.Run(ctx, client, []string{unreadKey(customerId), readKey(customerId)}, ids...).Int64()
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+	return unread, nil
+}
+
+// MarkAllNotificationsRead -> mark every listed notification of the customer read
+// and reset the unread counter
+func (s *CacheService) MarkAllNotificationsRead(ctx context.Context, customerId uint64, notificationIds ...uint64) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(notificationIds) > 0 {
+			ids := make([]any, 0, len(notificationIds))
+			for _, id := range notificationIds {
+				ids = append(ids, id)
+			}
+			pipe.SAdd(ctx, readKey(customerId), ids...)
+			pipe.Expire(ctx, readKey(customerId), readStateTTL)
+		}
+		pipe.Set(ctx, unreadKey(customerId), 0, unreadCounterTTL)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// ForgetNotification -> drop the read state of a deleted notification and the cached list pages
+func (s *CacheService) ForgetNotification(ctx context.Context, customerId, notificationId uint64) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = forgetScript.Run(ctx, client, []string{unreadKey(customerId), readKey(customerId)}, notificationId).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	if err = client.Del(ctx, fmt.Sprintf("notifications:%d", customerId)).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// ResetNotifications -> drop the read state and the cached list pages
+// of the customer whose notifications are cleared
+func (s *CacheService) ResetNotifications(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, readKey(customerId), fmt.Sprintf("notifications:%d", customerId))
+		pipe.Set(ctx, unreadKey(customerId), 0, unreadCounterTTL)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetUnreadNotifications -> the unread counter of the customer,
+// false if it's unknown and has to be recounted
+func (s *CacheService) GetUnreadNotifications(ctx context.Context, customerId uint64) (int64, bool, error) {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return 0, false, exceptions.HandleAnException(err)
+	}
+
+	unread, err := client.Get(ctx, unreadKey(customerId)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, exceptions.HandleAnException(err)
+	}
+	return unread, true, nil
+}
+
+// SetUnreadNotifications -> save the recounted unread counter unless
+// it's been set meanwhile, the counter kept is returned
+func (s *CacheService) SetUnreadNotifications(ctx context.Context, customerId uint64, unread int64) (int64, error) {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+
+	saved, err := client.SetNX(ctx, unreadKey(customerId), unread, unreadCounterTTL).Result()
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+	if saved {
+		return unread, nil
+	}
+	current, _, err := s.GetUnreadNotifications(ctx, customerId)
+	return current, err
+}
+
+// PruneReadNotifications -> forget the read state of the notifications
+// that are no longer listed, e.g. deleted by the notifications service
+func (s *CacheService) PruneReadNotifications(ctx context.Context, customerId uint64, notificationIds ...uint64) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	ids := make([]any, 0, len(notificationIds)+1)
+	ids = append(ids, readStateTTL.Milliseconds())
+	for _, id := range notificationIds {
+		ids = append(ids, id)
+	}
+
+	if err = pruneReadScript.Run(ctx, client, []string{readKey(customerId)}, ids...).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetReadNotifications -> which of the notifications of the customer are read
+func (s *CacheService) GetReadNotifications(ctx context.Context, customerId uint64, notificationIds ...uint64) (map[uint64]bool, error) {
+	read := make(map[uint64]bool, len(notificationIds))
+	if len(notificationIds) == 0 {
+		return read, nil
+	}
+
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	ids := make([]any, 0, len(notificationIds))
+	for _, id := range notificationIds {
+		ids = append(ids, id)
+	}
+
+	members, err := client.SMIsMember(ctx, readKey(customerId), ids...).Result()
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+	for i, id := range notificationIds {
+		read[id] = members[i]
+	}
+	return read, nil
+}
+
+// notificationsPattern -> the pub/sub channels of the new notifications, one per customer
+const notificationsPattern = "notifications:customer:*"
+
+// PublishNotification -> push an encoded notification to the customer's channel,
+// every gateway instance gets it to fan it out to the customer's connections
+func (s *CacheService) PublishNotification(ctx context.Context, customerId uint64, event []byte) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Publish(ctx, fmt.Sprintf("notifications:customer:%d", customerId), event).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// ListenNotifications -> call handle for every notification published to any customer,
+// the subscription is confirmed before ready is called, it lasts until ctx is done
+// or the connection is lost
+func (s *CacheService) ListenNotifications(ctx context.Context, ready func(), handle func(customerId uint64, event []byte)) error {
+	client, err := s.connectClient(ctx, "notifications")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	pubsub := client.PSubscribe(ctx, notificationsPattern)
+	defer pubsub.Close()
+
+	if _, err = pubsub.Receive(ctx); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	ready()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return exceptions.HandleAnException(errors.New("notifications subscription is closed"))
+			}
+			customerId, err := strconv.ParseUint(strings.TrimPrefix(message.Channel, "notifications:customer:"), 10, 64)
+			if err != nil {
+				continue
+			}
+			handle(customerId, []byte(message.Payload))
+		}
+	}
+}
@@ -10,19 +10,18 @@ import (
 )
 
 // SetOfferDetails -> set offer detailed data
-func (s *CacheService) SetOfferDetails(offerId uint64, dto *offers.Offer) error {
-	client, err := s.connectClient("offers")
+func (s *CacheService) SetOfferDetails(ctx context.Context, offerId uint64, dto *offers.Offer) error {
+	client, err := s.connectClient(ctx, "offers")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("offers:%d", dto.OfferId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("offers:%d", dto.OfferId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
@@ -30,14 +29,13 @@ func (s *CacheService) SetOfferDetails(offerId uint64, dto *offers.Offer) error
 }
 
 // GetOfferDetails -> get offer detailed data
-func (s *CacheService) GetOfferDetails(offerId uint64) (*offers.Offer, error) {
-	client, err := s.connectClient("offers")
+func (s *CacheService) GetOfferDetails(ctx context.Context, offerId uint64) (*offers.Offer, error) {
+	client, err := s.connectClient(ctx, "offers")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("offers:%d", offerId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("offers:%d", offerId)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return nil, nil
@@ -54,28 +52,26 @@ func (s *CacheService) GetOfferDetails(offerId uint64) (*offers.Offer, error) {
 	return &dto, nil
 }
 
-func (s *CacheService) ClearOfferDetails(offerId uint64) error {
-	client, err := s.connectClient("offers")
+func (s *CacheService) ClearOfferDetails(ctx context.Context, offerId uint64) error {
+	client, err := s.connectClient(ctx, "offers")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("offers:%d", offerId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("offers:%d", offerId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) ClearApplicantsList(offerId uint64) error {
-	client, err := s.connectClient("offers")
+func (s *CacheService) ClearApplicantsList(ctx context.Context, offerId uint64) error {
+	client, err := s.connectClient(ctx, "offers")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("applicants:%d", offerId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("applicants:%d", offerId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
@@ -83,19 +79,18 @@ func (s *CacheService) ClearApplicantsList(offerId uint64) error {
 }
 
 // SetApplicantsList -> set applicants list
-func (s *CacheService) SetApplicantsList(offerId uint64, dto *offers.ApplicantsList) error {
-	client, err := s.connectClient("offers")
+func (s *CacheService) SetApplicantsList(ctx context.Context, offerId uint64, dto *offers.ApplicantsList) error {
+	client, err := s.connectClient(ctx, "offers")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("applicants:%d", offerId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("applicants:%d", offerId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
@@ -103,14 +98,13 @@ func (s *CacheService) SetApplicantsList(offerId uint64, dto *offers.ApplicantsL
 }
 
 // GetApplicantsList -> get applicants list
-func (s *CacheService) GetApplicantsList(offerId uint64) (*offers.ApplicantsList, error) {
-	client, err := s.connectClient("offers")
+func (s *CacheService) GetApplicantsList(ctx context.Context, offerId uint64) (*offers.ApplicantsList, error) {
+	client, err := s.connectClient(ctx, "offers")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("applicants:%d", offerId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("applicants:%d", offerId)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return nil, nil
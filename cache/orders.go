@@ -9,47 +9,44 @@ import (
 	"github.com/noo8xl/anvil-common/exceptions"
 )
 
-func (s *CacheService) ClearOrderDetails(orderId uint64) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) ClearOrderDetails(ctx context.Context, orderId uint64) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("orders:%d", orderId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("orders:%d", orderId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetOrderDetails(orderId uint64, dto *pb.Order) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) SetOrderDetails(ctx context.Context, orderId uint64, dto *pb.Order) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("orders:%d", orderId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("orders:%d", orderId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) GetOrderDetails(orderId uint64) (*pb.Order, error) {
-	client, err := s.connectClient("orders")
+func (s *CacheService) GetOrderDetails(ctx context.Context, orderId uint64) (*pb.Order, error) {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("orders:%d", orderId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("orders:%d", orderId)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return nil, nil
@@ -66,26 +63,24 @@ func (s *CacheService) GetOrderDetails(orderId uint64) (*pb.Order, error) {
 	return &dto, nil
 }
 
-func (s *CacheService) ClearOrdersList(orderId uint64) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) ClearOrdersList(ctx context.Context, orderId uint64) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("orders_list:%d", orderId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("orders_list:%d", orderId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetOrdersList(dto *pb.OrdersList) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) SetOrdersList(ctx context.Context, dto *pb.OrdersList) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
@@ -93,7 +88,7 @@ func (s *CacheService) SetOrdersList(dto *pb.OrdersList) error {
 	}
 
 	for _, order := range dto.OrdersList {
-		if err = client.Set(context.Background(), fmt.Sprintf("orders_list:%d", order.OrderId), payload, s.timeout).Err(); err != nil {
+		if err = client.Set(ctx, fmt.Sprintf("orders_list:%d", order.OrderId), payload, s.timeout).Err(); err != nil {
 			return exceptions.HandleAnException(err)
 		}
 	}
@@ -101,14 +96,13 @@ func (s *CacheService) SetOrdersList(dto *pb.OrdersList) error {
 	return nil
 }
 
-func (s *CacheService) GetOrdersList(orderId uint64) (*pb.OrdersList, error) {
-	client, err := s.connectClient("orders")
+func (s *CacheService) GetOrdersList(ctx context.Context, orderId uint64) (*pb.OrdersList, error) {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("orders_list:%d", orderId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("orders_list:%d", orderId)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return nil, nil
@@ -125,47 +119,44 @@ func (s *CacheService) GetOrdersList(orderId uint64) (*pb.OrdersList, error) {
 	return &dto, nil
 }
 
-func (s *CacheService) ClearFilteredOrdersList(customerId uint64) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) ClearFilteredOrdersList(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("filtered_orders_list:%d", customerId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("filtered_orders_list:%d", customerId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetFilteredOrdersList(customerId uint64, dto *pb.OrdersList) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) SetFilteredOrdersList(ctx context.Context, customerId uint64, dto *pb.OrdersList) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("filtered_orders_list:%d", customerId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("filtered_orders_list:%d", customerId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) GetFilteredOrdersList(customerId uint64) (*pb.OrdersList, error) {
-	client, err := s.connectClient("orders")
+func (s *CacheService) GetFilteredOrdersList(ctx context.Context, customerId uint64) (*pb.OrdersList, error) {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("filtered_orders_list:%d", customerId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("filtered_orders_list:%d", customerId)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return nil, nil
@@ -184,47 +175,44 @@ func (s *CacheService) GetFilteredOrdersList(customerId uint64) (*pb.OrdersList,
 
 // ############################## orders compliance area
 
-func (s *CacheService) ClearComplianceRequestsList(customerId uint64) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) ClearComplianceRequestsList(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("compliance_requests_list:%d", customerId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("compliance_requests_list:%d", customerId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetComplianceRequestsList(customerId uint64, dto *pb.ComplianceRequestsList) error {
-	client, err := s.connectClient("orders")
+func (s *CacheService) SetComplianceRequestsList(ctx context.Context, customerId uint64, dto *pb.ComplianceRequestsList) error {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("compliance_requests_list:%d", customerId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("compliance_requests_list:%d", customerId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) GetComplianceRequestsList(customerId uint64) (*pb.ComplianceRequestsList, error) {
-	client, err := s.connectClient("orders")
+func (s *CacheService) GetComplianceRequestsList(ctx context.Context, customerId uint64) (*pb.ComplianceRequestsList, error) {
+	client, err := s.connectClient(ctx, "orders")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("compliance_requests_list:%d", customerId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("compliance_requests_list:%d", customerId)).Result()
 	if err != nil {
 		if err.Error() == "redis: nil" {
 			return nil, nil
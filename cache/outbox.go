@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// outboxStream -> the queue of the messages waiting for a delivery,
+	// a delivered message is removed from the stream
+	outboxStream = "outbox:messages"
+	// outboxGroup -> the consumer group shared by the workers of every gateway instance
+	outboxGroup = "dispatchers"
+	// outboxRetries -> the failed messages scored by the time of their next attempt
+	outboxRetries = "outbox:retries"
+	// outboxDeadLetters -> the messages given up on, kept for an inspection
+	outboxDeadLetters = "outbox:dead"
+)
+
+// OutboxEntry -> a message read from the outbox queue by its stream id
+type OutboxEntry struct {
+	Id      string
+	Message []byte
+}
+
+// OutboxDepth -> number of the messages in every part of the outbox
+type OutboxDepth struct {
+	Pending     int64
+	Retrying    int64
+	DeadLetters int64
+}
+
+// promoteRetriesScript -> move up to ARGV[2] retries due by ARGV[1] ms back to the queue,
+// a single script so a retry is never moved twice by the gateway instances
+var promoteRetriesScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, tonumber(ARGV[2]))
+for _, message in ipairs(due) do
+	redis.call("ZREM", KEYS[1], message)
+	redis.call("XADD", KEYS[2], "*", "message", message)
+end
+return #due
+`)
+
+// AppendOutboxMessages -> add the encoded messages to the outbox queue at once
+func (s *CacheService) AppendOutboxMessages(ctx context.Context, messages ...[]byte) error {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, message := range messages {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: outboxStream,
+				Values: map[string]any{"message": message},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// CreateOutboxGroup -> create the consumer group of the queue if it doesn't exist,
+// the group starts from the first message so nothing queued before is skipped
+func (s *CacheService) CreateOutboxGroup(ctx context.Context) error {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	err = client.XGroupCreateMkStream(ctx, outboxStream, outboxGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// ReadOutboxMessages -> take up to count new messages for the consumer,
+// waiting up to block for them, the messages stay pending until acknowledged
+func (s *CacheService) ReadOutboxMessages(ctx context.Context, consumer string, count int64, block time.Duration) ([]OutboxEntry, error) {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    outboxGroup,
+		Consumer: consumer,
+		Streams:  []string{outboxStream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	entries := []OutboxEntry{}
+	for _, stream := range streams {
+		entries = append(entries, toOutboxEntries(stream.Messages)...)
+	}
+	return entries, nil
+}
+
+// ClaimOutboxMessages -> take over up to count messages left unacknowledged
+// for longer than minIdle, e.g. by a worker of a crashed gateway
+func (s *CacheService) ClaimOutboxMessages(ctx context.Context, consumer string, minIdle time.Duration, count int64) ([]OutboxEntry, error) {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	messages, _, err := client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   outboxStream,
+		Group:    outboxGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    count,
+	}).Result()
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+	return toOutboxEntries(messages), nil
+}
+
+// AckOutboxMessage -> remove a delivered message from the queue
+func (s *CacheService) AckOutboxMessage(ctx context.Context, id string) error {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, outboxStream, outboxGroup, id)
+		pipe.XDel(ctx, outboxStream, id)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// RetryOutboxMessage -> replace a failed message of the queue by its next attempt
+// scheduled at the given time
+func (s *CacheService) RetryOutboxMessage(ctx context.Context, id string, message []byte, at time.Time) error {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, outboxRetries, redis.Z{Score: float64(at.UnixMilli()), Member: message})
+		pipe.XAck(ctx, outboxStream, outboxGroup, id)
+		pipe.XDel(ctx, outboxStream, id)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// PromoteOutboxRetries -> move up to count retries due by now back to the queue,
+// returns the number of the moved ones
+func (s *CacheService) PromoteOutboxRetries(ctx context.Context, now time.Time, count int64) (int64, error) {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+
+	moved, err := promoteRetriesScript.Run(ctx, client,
+		[]string{outboxRetries, outboxStream},
+		now.UnixMilli(), count,
+	).Int64()
+	if err != nil {
+		return 0, exceptions.HandleAnException(err)
+	}
+	return moved, nil
+}
+
+// DeadLetterOutboxMessage -> move a message given up on from the queue
+// to the dead letters with the reason of the last failure
+func (s *CacheService) DeadLetterOutboxMessage(ctx context.Context, id string, message []byte, reason string) error {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: outboxDeadLetters,
+			Values: map[string]any{"message": message, "error": reason},
+		})
+		pipe.XAck(ctx, outboxStream, outboxGroup, id)
+		pipe.XDel(ctx, outboxStream, id)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetOutboxDepth -> count the queued, retrying and dead-lettered messages
+func (s *CacheService) GetOutboxDepth(ctx context.Context) (OutboxDepth, error) {
+	client, err := s.connectClient(ctx, "outbox")
+	if err != nil {
+		return OutboxDepth{}, exceptions.HandleAnException(err)
+	}
+
+	var pending, retrying, dead *redis.IntCmd
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pending = pipe.XLen(ctx, outboxStream)
+		retrying = pipe.ZCard(ctx, outboxRetries)
+		dead = pipe.XLen(ctx, outboxDeadLetters)
+		return nil
+	})
+	if err != nil {
+		return OutboxDepth{}, exceptions.HandleAnException(err)
+	}
+
+	return OutboxDepth{
+		Pending:     pending.Val(),
+		Retrying:    retrying.Val(),
+		DeadLetters: dead.Val(),
+	}, nil
+}
+
+// toOutboxEntries -> the entries of the stream messages, a malformed message
+// gets an empty body so it's dead-lettered instead of staying pending forever
+func toOutboxEntries(messages []redis.XMessage) []OutboxEntry {
+	entries := make([]OutboxEntry, 0, len(messages))
+	for _, message := range messages {
+		value, _ := message.Values["message"].(string)
+		entries = append(entries, OutboxEntry{Id: message.ID, Message: []byte(value)})
+	}
+	return entries
+}
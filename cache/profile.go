@@ -2,22 +2,23 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	profilePb "github.com/noo8xl/anvil-api/main/profile"
 	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/protobuf/proto"
 )
 
 // customer profile
-func (c *CacheService) ClearCustomerProfile(customerId uint64) error {
-	client, err := c.connectClient("profile")
+func (c *CacheService) ClearCustomerProfile(ctx context.Context, customerId uint64) error {
+	client, err := c.connectClient(ctx, "profile")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	status := client.Del(context.Background(), fmt.Sprintf("customer:%d", customerId))
+	status := client.Del(ctx, fmt.Sprintf("customer:%d", customerId))
 	if status.Err() != nil {
 		return exceptions.HandleAnException(status.Err())
 	}
@@ -25,32 +26,30 @@ func (c *CacheService) ClearCustomerProfile(customerId uint64) error {
 	return nil
 }
 
-func (c *CacheService) SetCustomerProfile(customerId uint64, customer *profilePb.CustomerResponse) error {
-	client, err := c.connectClient("profile")
+func (c *CacheService) SetCustomerProfile(ctx context.Context, customerId uint64, customer *profilePb.CustomerResponse) error {
+	client, err := c.connectClient(ctx, "profile")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	bytes, err := proto.Marshal(customer.ProtoReflect().Interface())
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if status := client.Set(context.Background(), fmt.Sprintf("customer:%d", customerId), bytes, c.timeout); status.Err() != nil {
+	if status := client.Set(ctx, fmt.Sprintf("customer:%d", customerId), bytes, c.timeout); status.Err() != nil {
 		return exceptions.HandleAnException(status.Err())
 	}
 
 	return nil
 }
 
-func (c *CacheService) GetCustomerProfile(customerId uint64) (*profilePb.CustomerResponse, error) {
-	client, err := c.connectClient("profile")
+func (c *CacheService) GetCustomerProfile(ctx context.Context, customerId uint64) (*profilePb.CustomerResponse, error) {
+	client, err := c.connectClient(ctx, "profile")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
-	customer := client.Get(context.Background(), fmt.Sprintf("customer:%d", customerId))
+	customer := client.Get(ctx, fmt.Sprintf("customer:%d", customerId))
 	if customer.Err() != nil {
 		if customer.Err().Error() == "redis: nil" {
 			return nil, nil
@@ -69,14 +68,13 @@ func (c *CacheService) GetCustomerProfile(customerId uint64) (*profilePb.Custome
 }
 
 // public profile
-func (c *CacheService) ClearPublicProfile(customerId uint64) error {
-	client, err := c.connectClient("profile")
+func (c *CacheService) ClearPublicProfile(ctx context.Context, customerId uint64) error {
+	client, err := c.connectClient(ctx, "profile")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	status := client.Del(context.Background(), fmt.Sprintf("public_profile:%d", customerId))
+	status := client.Del(ctx, fmt.Sprintf("public_profile:%d", customerId))
 	if status.Err() != nil {
 		return exceptions.HandleAnException(status.Err())
 	}
@@ -84,33 +82,31 @@ func (c *CacheService) ClearPublicProfile(customerId uint64) error {
 	return nil
 }
 
-func (c *CacheService) SetPublicProfile(customerId uint64, profile *profilePb.PublicProfileResponse) error {
-	client, err := c.connectClient("profile")
+func (c *CacheService) SetPublicProfile(ctx context.Context, customerId uint64, profile *profilePb.PublicProfileResponse) error {
+	client, err := c.connectClient(ctx, "profile")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	bytes, err := proto.Marshal(profile.ProtoReflect().Interface())
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if status := client.Set(context.Background(), fmt.Sprintf("public_profile:%d", customerId), bytes, c.timeout); status.Err() != nil {
+	if status := client.Set(ctx, fmt.Sprintf("public_profile:%d", customerId), bytes, c.timeout); status.Err() != nil {
 		return exceptions.HandleAnException(status.Err())
 	}
 
 	return nil
 }
 
-func (c *CacheService) GetPublicProfile(customerId uint64) (*profilePb.PublicProfileResponse, error) {
-	client, err := c.connectClient("profile")
+func (c *CacheService) GetPublicProfile(ctx context.Context, customerId uint64) (*profilePb.PublicProfileResponse, error) {
+	client, err := c.connectClient(ctx, "profile")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	profile := client.Get(context.Background(), fmt.Sprintf("public_profile:%d", customerId))
+	profile := client.Get(ctx, fmt.Sprintf("public_profile:%d", customerId))
 	if profile.Err() != nil {
 		if profile.Err().Error() == "redis: nil" {
 			return nil, nil
@@ -127,3 +123,68 @@ func (c *CacheService) GetPublicProfile(customerId uint64) (*profilePb.PublicPro
 	}
 	return profilePb, nil
 }
+
+// customer preferences
+
+// SetCustomerLocale -> save the locale the customer gets the notifications in,
+// the profile service has no such field so it's kept by the gateway without an expiration
+func (c *CacheService) SetCustomerLocale(ctx context.Context, customerId uint64, locale string) error {
+	client, err := c.connectClient(ctx, "profile")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Set(ctx, fmt.Sprintf("locale:%d", customerId), locale, 0).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetCustomerLocale -> the locale chosen by the customer or "" if it's not set
+func (c *CacheService) GetCustomerLocale(ctx context.Context, customerId uint64) (string, error) {
+	client, err := c.connectClient(ctx, "profile")
+	if err != nil {
+		return "", exceptions.HandleAnException(err)
+	}
+
+	locale, err := client.Get(ctx, fmt.Sprintf("locale:%d", customerId)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", exceptions.HandleAnException(err)
+	}
+	return locale, nil
+}
+
+// SetNotificationPreferences -> save the encoded notification preferences of the customer,
+// kept by the gateway without an expiration like the locale
+func (c *CacheService) SetNotificationPreferences(ctx context.Context, customerId uint64, preferences []byte) error {
+	client, err := c.connectClient(ctx, "profile")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Set(ctx, fmt.Sprintf("notification-preferences:%d", customerId), preferences, 0).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetNotificationPreferences -> the encoded notification preferences of the customer
+// or nil if they're not set
+func (c *CacheService) GetNotificationPreferences(ctx context.Context, customerId uint64) ([]byte, error) {
+	client, err := c.connectClient(ctx, "profile")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	preferences, err := client.Get(ctx, fmt.Sprintf("notification-preferences:%d", customerId)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+	return preferences, nil
+}
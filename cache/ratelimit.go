@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript -> drop the requests out of the window and count the request
+// if the limit is not reached, returns {allowed, count, oldest request ms}
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {allowed, count, tonumber(oldest[2])}
+`)
+
+// TakeRateLimit -> count a request by the key within the sliding window,
+// returns whether it's allowed, the requests in the window and the oldest one
+func (s *CacheService) TakeRateLimit(ctx context.Context, key string, limit config.RateLimit) (bool, int64, time.Time, error) {
+	client, err := s.connectClient(ctx, "ratelimit")
+	if err != nil {
+		return false, 0, time.Time{}, exceptions.HandleAnException(err)
+	}
+
+	now := time.Now()
+	member := fmt.Sprintf("%d-%s", now.UnixNano(), rand.Text()[:8])
+
+	result, err := slidingWindowScript.Run(ctx, client,
+		[]string{fmt.Sprintf("ratelimit:%s", key)},
+		now.UnixMilli(), limit.Window.Milliseconds(), limit.Requests, member,
+	).Int64Slice()
+	if err != nil {
+		return false, 0, time.Time{}, exceptions.HandleAnException(err)
+	}
+
+	return result[0] == 1, result[1], time.UnixMilli(result[2]), nil
+}
@@ -9,47 +9,44 @@ import (
 	"github.com/noo8xl/anvil-common/exceptions"
 )
 
-func (s *CacheService) ClearReviewCommentsList(reviewId uint64) error {
-	client, err := s.connectClient("reviews")
+func (s *CacheService) ClearReviewCommentsList(ctx context.Context, reviewId uint64) error {
+	client, err := s.connectClient(ctx, "reviews")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("reviews_comments:%d", reviewId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("reviews_comments:%d", reviewId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetReviewCommentsList(reviewId uint64, dto *pb.ReviewCommentsList) error {
-	client, err := s.connectClient("reviews")
+func (s *CacheService) SetReviewCommentsList(ctx context.Context, reviewId uint64, dto *pb.ReviewCommentsList) error {
+	client, err := s.connectClient(ctx, "reviews")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("reviews_comments:%d", reviewId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("reviews_comments:%d", reviewId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) GetReviewCommentsList(reviewId uint64) (*pb.ReviewCommentsList, error) {
-	client, err := s.connectClient("reviews")
+func (s *CacheService) GetReviewCommentsList(ctx context.Context, reviewId uint64) (*pb.ReviewCommentsList, error) {
+	client, err := s.connectClient(ctx, "reviews")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("reviews_comments:%d", reviewId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("reviews_comments:%d", reviewId)).Result()
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
@@ -63,47 +60,44 @@ func (s *CacheService) GetReviewCommentsList(reviewId uint64) (*pb.ReviewComment
 	return &dto, nil
 }
 
-func (s *CacheService) ClearReviewDetails(reviewId uint64) error {
-	client, err := s.connectClient("reviews")
+func (s *CacheService) ClearReviewDetails(ctx context.Context, reviewId uint64) error {
+	client, err := s.connectClient(ctx, "reviews")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	if err = client.Del(context.Background(), fmt.Sprintf("reviews:%d", reviewId)).Err(); err != nil {
+	if err = client.Del(ctx, fmt.Sprintf("reviews:%d", reviewId)).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) SetReviewDetails(reviewId uint64, dto *pb.ReviewResponse) error {
-	client, err := s.connectClient("reviews")
+func (s *CacheService) SetReviewDetails(ctx context.Context, reviewId uint64, dto *pb.ReviewResponse) error {
+	client, err := s.connectClient(ctx, "reviews")
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
 	payload, err := json.Marshal(dto)
 	if err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
-	if err = client.Set(context.Background(), fmt.Sprintf("reviews:%d", reviewId), payload, s.timeout).Err(); err != nil {
+	if err = client.Set(ctx, fmt.Sprintf("reviews:%d", reviewId), payload, s.timeout).Err(); err != nil {
 		return exceptions.HandleAnException(err)
 	}
 
 	return nil
 }
 
-func (s *CacheService) GetReviewDetails(reviewId uint64) (*pb.ReviewResponse, error) {
-	client, err := s.connectClient("reviews")
+func (s *CacheService) GetReviewDetails(ctx context.Context, reviewId uint64) (*pb.ReviewResponse, error) {
+	client, err := s.connectClient(ctx, "reviews")
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
-	defer client.Close()
 
-	result, err := client.Get(context.Background(), fmt.Sprintf("reviews:%d", reviewId)).Result()
+	result, err := client.Get(ctx, fmt.Sprintf("reviews:%d", reviewId)).Result()
 	if err != nil {
 		return nil, exceptions.HandleAnException(err)
 	}
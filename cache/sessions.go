@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/redis/go-redis/v9"
+)
+
+// RevokeToken -> put the token id to the revocation list until the token expires
+func (s *CacheService) RevokeToken(ctx context.Context, tokenId string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Set(ctx, fmt.Sprintf("revoked:%s", tokenId), 1, ttl).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// IsTokenRevoked -> check if the token id is in the revocation list
+func (s *CacheService) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+
+	n, err := client.Exists(ctx, fmt.Sprintf("revoked:%s", tokenId)).Result()
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+	return n > 0, nil
+}
+
+// BlockCustomer -> put the customer to the blocked list until unblocked
+func (s *CacheService) BlockCustomer(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Set(ctx, fmt.Sprintf("blocked:%d", customerId), 1, 0).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// UnblockCustomer -> remove the customer from the blocked list
+func (s *CacheService) UnblockCustomer(ctx context.Context, customerId uint64) error {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	if err = client.Del(ctx, fmt.Sprintf("blocked:%d", customerId)).Err(); err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// IsCustomerBlocked -> check if the customer is in the blocked list
+func (s *CacheService) IsCustomerBlocked(ctx context.Context, customerId uint64) (bool, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+
+	n, err := client.Exists(ctx, fmt.Sprintf("blocked:%d", customerId)).Result()
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+	return n > 0, nil
+}
+
+// ############################################################################
+
+// Session -> an active sign-in of a customer, every session is
+// a single refresh token family that is rotated on each refresh
+type Session struct {
+	SessionId  string    `json:"sessionId"`
+	CustomerId uint64    `json:"customerId"`
+	Email      string    `json:"email"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// rotateRefreshScript -> replace the current refresh token of the session
+// only if the presented one is still the current one
+var rotateRefreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+redis.call("SET", KEYS[2], ARGV[4], "PX", ARGV[3])
+return 1
+`)
+
+// SetSession -> save the session and its current refresh token hash
+func (s *CacheService) SetSession(ctx context.Context, session *Session, refreshHash string, ttl time.Duration) error {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	customerKey := fmt.Sprintf("customer-sessions:%d", session.CustomerId)
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, fmt.Sprintf("session:%s", session.SessionId), payload, ttl)
+		pipe.Set(ctx, fmt.Sprintf("session:%s:refresh", session.SessionId), refreshHash, ttl)
+		pipe.Set(ctx, fmt.Sprintf("refresh:%s", refreshHash), session.SessionId, ttl)
+		pipe.SAdd(ctx, customerKey, session.SessionId)
+		pipe.Expire(ctx, customerKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// UpdateSession -> save the session details keeping its expiration
+func (s *CacheService) UpdateSession(ctx context.Context, session *Session) error {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	err = client.SetArgs(ctx, fmt.Sprintf("session:%s", session.SessionId), payload, redis.SetArgs{KeepTTL: true, Mode: "XX"}).Err()
+	if err != nil && err.Error() != "redis: nil" {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetSession -> get an active session by its id
+func (s *CacheService) GetSession(ctx context.Context, sessionId string) (*Session, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	payload, err := client.Get(ctx, fmt.Sprintf("session:%s", sessionId)).Result()
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return nil, errors.New("session not found")
+		}
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	var session *Session
+	if err = json.Unmarshal([]byte(payload), &session); err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+	return session, nil
+}
+
+// IsSessionActive -> check if the session was neither revoked nor expired
+func (s *CacheService) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+
+	n, err := client.Exists(ctx, fmt.Sprintf("session:%s", sessionId)).Result()
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+	return n > 0, nil
+}
+
+// GetCustomerSessions -> get every active session of the customer
+func (s *CacheService) GetCustomerSessions(ctx context.Context, customerId uint64) ([]*Session, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	customerKey := fmt.Sprintf("customer-sessions:%d", customerId)
+
+	ids, err := client.SMembers(ctx, customerKey).Result()
+	if err != nil {
+		return nil, exceptions.HandleAnException(err)
+	}
+
+	sessions := make([]*Session, 0, len(ids))
+	for _, id := range ids {
+		payload, err := client.Get(ctx, fmt.Sprintf("session:%s", id)).Result()
+		if err != nil {
+			if err.Error() == "redis: nil" {
+				// the session has expired, drop it from the customer set
+				client.SRem(ctx, customerKey, id)
+				continue
+			}
+			return nil, exceptions.HandleAnException(err)
+		}
+
+		var session *Session
+		if err = json.Unmarshal([]byte(payload), &session); err != nil {
+			return nil, exceptions.HandleAnException(err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// DeleteSession -> revoke the session and its refresh token family
+func (s *CacheService) DeleteSession(ctx context.Context, customerId uint64, sessionId string) error {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, fmt.Sprintf("session:%s", sessionId), fmt.Sprintf("session:%s:refresh", sessionId))
+		pipe.SRem(ctx, fmt.Sprintf("customer-sessions:%d", customerId), sessionId)
+		return nil
+	})
+	if err != nil {
+		return exceptions.HandleAnException(err)
+	}
+	return nil
+}
+
+// GetRefreshTokenSession -> get the session id the refresh token was issued for,
+// rotated tokens of the family are kept to detect their reuse
+func (s *CacheService) GetRefreshTokenSession(ctx context.Context, refreshHash string) (string, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return "", exceptions.HandleAnException(err)
+	}
+
+	sessionId, err := client.Get(ctx, fmt.Sprintf("refresh:%s", refreshHash)).Result()
+	if err != nil {
+		if err.Error() == "redis: nil" {
+			return "", errors.New("refresh token not found")
+		}
+		return "", exceptions.HandleAnException(err)
+	}
+	return sessionId, nil
+}
+
+// RotateRefreshToken -> replace the current refresh token of the session,
+// returns false if the presented token is not the current one anymore
+func (s *CacheService) RotateRefreshToken(ctx context.Context, sessionId, oldHash, newHash string, ttl time.Duration) (bool, error) {
+	client, err := s.connectClient(ctx, "sessions")
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+
+	keys := []string{
+		fmt.Sprintf("session:%s:refresh", sessionId),
+		fmt.Sprintf("refresh:%s", newHash),
+	}
+	rotated, err := rotateRefreshScript.Run(ctx, client, keys, oldHash, newHash, ttl.Milliseconds(), sessionId).Int()
+	if err != nil {
+		return false, exceptions.HandleAnException(err)
+	}
+	return rotated == 1, nil
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/noo8xl/anvil-gateway/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingHook -> a client span of every redis command or pipeline,
+// the command arguments are never recorded as they may hold tokens or personal data
+type tracingHook struct {
+	attrs []attribute.KeyValue
+}
+
+func newTracingHook(svcName string, opts *redis.Options) *tracingHook {
+	return &tracingHook{
+		attrs: []attribute.KeyValue{
+			semconv.DBSystemRedis,
+			semconv.DBNamespace(svcName),
+			attribute.Int("db.redis.database_index", opts.DB),
+			semconv.ServerAddress(opts.Addr),
+		},
+	}
+}
+
+func (h *tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := h.start(ctx, "redis."+cmd.Name(), semconv.DBOperationName(cmd.Name()))
+		defer span.End()
+
+		err := next(ctx, cmd)
+		recordError(span, err)
+		return err
+	}
+}
+
+func (h *tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+		}
+
+		ctx, span := h.start(ctx, "redis.pipeline",
+			semconv.DBOperationName(strings.Join(names, " ")),
+			attribute.Int("db.redis.num_cmd", len(cmds)))
+		defer span.End()
+
+		err := next(ctx, cmds)
+		recordError(span, err)
+		return err
+	}
+}
+
+// start -> a child span of the command, the commands run outside of a trace
+// (e.g. the outbox workers polling the queue) aren't traced as they'd flood the traces
+func (h *tracingHook) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(h.attrs...),
+		trace.WithAttributes(attrs...))
+}
+
+// recordError -> mark the span as failed, a missing key is a regular cache miss
+func recordError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
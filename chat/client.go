@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// the kinds of the resources a chat is opened about
+const (
+	SubjectOrder = "order"
+	SubjectOffer = "offer"
+)
+
+// PageSize -> number of the chats or the messages of a list page
+const PageSize = 20
+
+// MaxMessageLength -> upper bound of the message body in characters
+const MaxMessageLength = 4000
+
+var (
+	ErrNotFound       = errors.New("chat not found")
+	ErrInvalidSubject = errors.New("invalid chat subject")
+	ErrInvalidMessage = errors.New("invalid chat message")
+)
+
+// Chat -> a conversation of the owner of an order or an offer with one of its participants
+type Chat struct {
+	Id            uint64   `json:"id"`
+	Subject       string   `json:"subject"`
+	SubjectId     uint64   `json:"subjectId"`
+	Participants  []uint64 `json:"participants"`
+	CreatedAt     string   `json:"createdAt"`
+	LastMessageAt string   `json:"lastMessageAt,omitempty"`
+}
+
+// Message -> a message sent to a chat
+type Message struct {
+	Id        uint64 `json:"id"`
+	ChatId    uint64 `json:"chatId"`
+	SenderId  uint64 `json:"senderId"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Client -> the chat backend
+//
+// anvil-api has no chat service yet, so the contract is kept by the gateway,
+// a grpc client is expected to implement it once the service is there
+type Client interface {
+	// CreateChat -> open a chat of the participants about the subject,
+	// the existing one is returned if they have it already
+	CreateChat(ctx context.Context, subject string, subjectId uint64, participants []uint64) (*Chat, error)
+	// GetChat -> the chat by its id or ErrNotFound
+	GetChat(ctx context.Context, chatId uint64) (*Chat, error)
+	// GetChatsList -> a page of the customer's chats, the recently active first
+	GetChatsList(ctx context.Context, customerId uint64, skip uint32) ([]*Chat, error)
+	// SendMessage -> append a message of the sender to the chat
+	SendMessage(ctx context.Context, chatId, senderId uint64, body string) (*Message, error)
+	// GetMessagesList -> a page of the chat's messages, the newest first
+	GetMessagesList(ctx context.Context, chatId uint64, skip uint32) ([]*Message, error)
+}
+
+// ValidateSubject -> check the chat may be opened about the kind of resource
+func ValidateSubject(subject string) error {
+	if subject != SubjectOrder && subject != SubjectOffer {
+		return fmt.Errorf("%w: %q", ErrInvalidSubject, subject)
+	}
+	return nil
+}
+
+// NormalizeBody -> the trimmed message body or ErrInvalidMessage
+// if it's empty or too long
+func NormalizeBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("%w: the body is empty", ErrInvalidMessage)
+	}
+	if utf8.RuneCountInString(body) > MaxMessageLength {
+		return "", fmt.Errorf("%w: up to %d characters are allowed", ErrInvalidMessage, MaxMessageLength)
+	}
+	return body, nil
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/fanout"
+	"go.uber.org/zap"
+)
+
+// Sender -> send a message of the stream's customer to its chat
+type Sender func(ctx context.Context, body string) error
+
+// incoming -> a message sent by the client over the stream
+type incoming struct {
+	Body string `json:"body"`
+}
+
+// Subscription -> the messages of a single chat stream of a customer
+type Subscription = fanout.Subscription[Message]
+
+// Hub -> the chat message streams opened to a gateway instance,
+// the messages are published to the chat's redis channel
+type Hub struct {
+	*fanout.Hub[Message]
+}
+
+func InitHub(cfg config.StreamConfig, store *cache.CacheService, logger *zap.Logger) *Hub {
+	kind := fanout.Kind{
+		Name:    "chat",
+		Items:   "messages",
+		Publish: store.PublishChatMessage,
+		Listen:  store.ListenChatMessages,
+	}
+	return &Hub{Hub: fanout.InitHub[Message](cfg, kind, logger)}
+}
+
+// Publish -> push the message to every stream of its chat on any instance
+func (h *Hub) Publish(ctx context.Context, message *Message) error {
+	return h.Hub.Publish(ctx, message.ChatId, *message)
+}
+
+// ServeWebSocket -> push the chat messages over a WebSocket and send the ones
+// of the client by send
+//
+// the client sends {"body": string}, a message that can't be sent is answered
+// with {"error": string}, the sent one comes back as any other chat message
+func (h *Hub) ServeWebSocket(w http.ResponseWriter, r *http.Request, s *Subscription, send Sender) {
+	h.Hub.ServeWebSocket(w, r, s, func(ctx context.Context, payload []byte) error {
+		var message incoming
+		if err := json.Unmarshal(payload, &message); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		return send(ctx, message.Body)
+	})
+}
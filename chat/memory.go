@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryClient -> a chat backend kept in the memory of a single instance,
+// a stand-in for the chat service in development and tests, the chats are lost
+// with the process
+type MemoryClient struct {
+	mu       sync.Mutex
+	lastId   uint64
+	chats    map[uint64]*Chat
+	messages map[uint64][]*Message
+}
+
+func InitMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		chats:    make(map[uint64]*Chat),
+		messages: make(map[uint64][]*Message),
+	}
+}
+
+func (c *MemoryClient) CreateChat(ctx context.Context, subject string, subjectId uint64, participants []uint64) (*Chat, error) {
+	if err := ValidateSubject(subject); err != nil {
+		return nil, err
+	}
+
+	participants = slices.Clone(participants)
+	slices.Sort(participants)
+	participants = slices.Compact(participants)
+	if len(participants) < 2 || participants[0] == 0 {
+		return nil, fmt.Errorf("%w: a chat needs two participants", ErrInvalidSubject)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, chat := range c.chats {
+		if chat.Subject == subject && chat.SubjectId == subjectId && slices.Equal(chat.Participants, participants) {
+			return clone(chat), nil
+		}
+	}
+
+	c.lastId++
+	chat := &Chat{
+		Id:           c.lastId,
+		Subject:      subject,
+		SubjectId:    subjectId,
+		Participants: participants,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	}
+	c.chats[chat.Id] = chat
+	return clone(chat), nil
+}
+
+func (c *MemoryClient) GetChat(ctx context.Context, chatId uint64) (*Chat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chat, ok := c.chats[chatId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(chat), nil
+}
+
+func (c *MemoryClient) GetChatsList(ctx context.Context, customerId uint64, skip uint32) ([]*Chat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := []*Chat{}
+	for _, chat := range c.chats {
+		if slices.Contains(chat.Participants, customerId) {
+			list = append(list, clone(chat))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].LastMessageAt != list[j].LastMessageAt {
+			return list[i].LastMessageAt > list[j].LastMessageAt
+		}
+		return list[i].Id > list[j].Id
+	})
+	return page(list, skip), nil
+}
+
+func (c *MemoryClient) SendMessage(ctx context.Context, chatId, senderId uint64, body string) (*Message, error) {
+	body, err := NormalizeBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chat, ok := c.chats[chatId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c.lastId++
+	message := &Message{
+		Id:        c.lastId,
+		ChatId:    chatId,
+		SenderId:  senderId,
+		Body:      body,
+		CreatedAt: time.Now().Format(time.RFC3339Nano),
+	}
+	c.messages[chatId] = append(c.messages[chatId], message)
+	chat.LastMessageAt = message.CreatedAt
+
+	copied := *message
+	return &copied, nil
+}
+
+func (c *MemoryClient) GetMessagesList(ctx context.Context, chatId uint64, skip uint32) ([]*Message, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.chats[chatId]; !ok {
+		return nil, ErrNotFound
+	}
+
+	messages := c.messages[chatId]
+	list := make([]*Message, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		copied := *messages[i]
+		list = append(list, &copied)
+	}
+	return page(list, skip), nil
+}
+
+// clone -> a copy of the chat, so the callers can't change the stored one
+func clone(chat *Chat) *Chat {
+	copied := *chat
+	copied.Participants = slices.Clone(chat.Participants)
+	return &copied
+}
+
+// page -> the list page starting at skip
+func page[T any](list []T, skip uint32) []T {
+	start := min(int(skip), len(list))
+	end := min(start+PageSize, len(list))
+	return list[start:end]
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -24,10 +26,24 @@ import (
 	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
 
 	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/bruteforce"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/chat"
 	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/health"
+	"github.com/noo8xl/anvil-gateway/metrics"
 	"github.com/noo8xl/anvil-gateway/middlewares"
-
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/noo8xl/anvil-gateway/outbox"
+	"github.com/noo8xl/anvil-gateway/preferences"
+	"github.com/noo8xl/anvil-gateway/rbac"
+	"github.com/noo8xl/anvil-gateway/realtime"
+	"github.com/noo8xl/anvil-gateway/router"
+	"github.com/noo8xl/anvil-gateway/shutdown"
+	"github.com/noo8xl/anvil-gateway/templates"
+	"github.com/noo8xl/anvil-gateway/tokens"
+	"github.com/noo8xl/anvil-gateway/tracing"
+	loggers "github.com/noo8xl/anvil-gateway/utils/loggers"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -46,21 +62,91 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	clients := initClients(cfg, logger)
-	defer func() {
-		for _, client := range clients {
-			if conn, ok := client.(*grpc.ClientConn); ok {
-				if err := conn.Close(); err != nil {
-					logger.Error("failed to close gRPC connection", zap.Error(err))
-				}
-			}
-		}
-	}()
+	shutdownTracing, err := tracing.Init(ctx, config.GetTracingConfig())
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+
+	// the background work keeps running through the drain, while the load balancer
+	// still routes to the instance, and it's stopped once the server is shut down
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var listeners sync.WaitGroup
+
+	clients, conns := initClients(cfg, logger)
+
+	watcher := health.InitWatcher()
+	for service, conn := range conns {
+		watcher.Watch(background, service, conn)
+	}
+
+	rbacCfg, err := config.GetRBACConfig()
+	if err != nil {
+		logger.Fatal("failed to load rbac config", zap.Error(err))
+	}
+	auditor, closeAudit := initAuditTrail(config.GetAuditConfig(), logger.Named("audit"))
+	authorizer := rbac.InitAuthorizer(rbacCfg, auditor)
 
 	mux := http.NewServeMux()
+	policies := middlewares.InitPolicies()
+	policies.UsePermissions(authorizer)
+
+	api := router.InitRouter(mux, policies)
+	api.UseRateLimiter(middlewares.InitRateLimiter(config.GetRateLimitConfig(), cache.InitCacheService()))
+	api.UseAvailability(watcher)
+	checker := initHealthChecker(config.GetHealthConfig(), clients, conns, watcher)
+	api.Handle("/livez", middlewares.Public(), health.LiveHandler())
+	api.Handle("/readyz", middlewares.Public(), checker.ReadyHandler())
+	api.Handle("/health", middlewares.Public(), checker.ReadyHandler())
+	api.Handle("/health/", middlewares.Public(), checker.ReadyHandler())
+	api.Handle("/health/details", middlewares.Public(), checker.DetailsHandler())
+	api.Handle("/metrics/", middlewares.Public(), metrics.Handler())
+
+	authCfg := config.GetAuthConfig()
+	if authCfg.SessionSecret == "" && os.Getenv("GO_ENV") == "production" {
+		logger.Fatal("AUTH_SESSION_SECRET is required in production")
+	}
 
-	mux.Handle("/health/", healthCheckHandler(clients))
-	mux.Handle("/metrics/", promhttp.Handler())
+	verifier, err := tokens.InitVerifier(authCfg, cache.InitCacheService(), clients["auth"].(authPb.AuthServiceClient))
+	if err != nil {
+		logger.Fatal("failed to initialize token verifier", zap.Error(err))
+	}
+	if verifier.Keys().IsConfigured() {
+		go verifier.Keys().Run(background, authCfg.KeysRefreshInterval)
+	}
+	sessions := tokens.InitSessions(authCfg, verifier, cache.InitCacheService())
+	resets := tokens.InitPasswordResets(authCfg, verifier, cache.InitCacheService())
+	jobs := shutdown.InitJobs()
+	notifications := outbox.InitOutbox(cache.InitCacheService())
+	catalog, err := templates.Load(config.GetTemplatesConfig())
+	if err != nil {
+		logger.Fatal("failed to load the templates", zap.Error(err))
+	}
+	hub := realtime.InitHub(config.GetStreamConfig(), cache.InitCacheService(), logger.Named("realtime"))
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		hub.Run(background)
+	}()
+	notificationPreferences := preferences.InitStore(cache.InitCacheService())
+	// there is no chat service in anvil-api yet, so the chat routes are served
+	// only by the in-memory stand-in of a development run
+	var chatClient chat.Client
+	if config.GetChatConfig().InMemory {
+		logger.Warn("the chats are kept in memory of the instance, for development only")
+		chatClient = chat.InitMemoryClient()
+	} else {
+		logger.Info("there is no chat service client, the chat routes are disabled")
+	}
+	chats := chat.InitHub(config.GetStreamConfig(), cache.InitCacheService(), logger.Named("chat"))
+	if chatClient != nil {
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			chats.Run(background)
+		}()
+	}
+	guard := bruteforce.InitGuard(authCfg, cache.InitCacheService(), logger.Named("security"))
 
 	userHandler := userRoutes.InitHandler(
 		clients["auth"].(authPb.AuthServiceClient),
@@ -72,6 +158,19 @@ func main() {
 		// clients["payments"].(paymentsPb.PaymentsServiceClient),
 		clients["offers"].(offersPb.OffersServiceClient),
 		clients["notifications"].(notificationsPb.NotificationsServiceClient),
+		sessions,
+		resets,
+		authCfg.PasswordResetUrl,
+		guard,
+		authorizer,
+		auditor,
+		jobs,
+		notifications,
+		catalog,
+		hub,
+		notificationPreferences,
+		chatClient,
+		chats,
 	)
 
 	adminHandler := adminRoutes.InitAdminHandler(
@@ -84,19 +183,48 @@ func main() {
 		// clients["payments"].(paymentsPb.PaymentsServiceClient),
 		clients["offers"].(offersPb.OffersServiceClient),
 		clients["notifications"].(notificationsPb.NotificationsServiceClient),
+		sessions,
+		auditor,
+		hub,
+		notificationPreferences,
+		userHandler.Owners(),
 	)
 
-	if err := registerRoutes(mux, userHandler, adminHandler); err != nil {
+	if err := registerRoutes(api, userHandler, adminHandler); err != nil {
 		logger.Fatal("failed to register routes", zap.Error(err))
 	}
 
+	dispatcher := outbox.InitDispatcher(
+		config.GetOutboxConfig(),
+		cache.InitCacheService(),
+		clients["notifications"].(notificationsPb.NotificationsServiceClient),
+		logger.Named("outbox"),
+	)
+	dispatcher.UsePublisher(hub)
+	dispatcher.UsePreferences(notificationPreferences)
+	if err := dispatcher.Start(); err != nil {
+		logger.Fatal("failed to start the outbox dispatcher", zap.Error(err))
+	}
+
 	// Apply middlewares in order
-	serverHandler := middlewares.Logger(
-		middlewares.MetricsMiddleware(
-			middlewares.AuthMiddleware(mux, clients["auth"].(authPb.AuthServiceClient)),
-		),
+	serverHandler := middlewares.MetricsMiddleware(mux,
+		middlewares.AuthMiddleware(mux, policies, verifier),
 	)
+	closeAccessLog := func() error { return nil }
+	if accessLogCfg := config.GetAccessLogConfig(); accessLogCfg.Enabled {
+		var accessLog *zap.Logger
+		accessLog, closeAccessLog, err = loggers.InitAccessLog(accessLogCfg)
+		if err != nil {
+			logger.Fatal("failed to initialize the access log", zap.Error(err))
+		}
+		serverHandler = middlewares.InitAccessLogger(accessLog, accessLogCfg).Handler(serverHandler)
+	}
+	serverHandler = middlewares.Tracing(mux, serverHandler)
+	serverHandler = middlewares.RequestId(serverHandler)
 	server := config.GetServerConfig(httpServerAddress, serverHandler)
+	// the open notification and chat streams are closed, so the server doesn't wait for them
+	server.RegisterOnShutdown(hub.Close)
+	server.RegisterOnShutdown(chats.Close)
 
 	go func() {
 		log.Printf("gateway server is running successfully on %s", httpServerAddress)
@@ -107,12 +235,61 @@ func main() {
 	}()
 
 	<-ctx.Done()
-
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// a second signal stops the gateway at once
+	stop()
 
 	log.Println("shutting down gracefully...")
-	if err := server.Shutdown(shutdownCtx); err != nil {
+	shutdownCfg := config.GetShutdownConfig()
+	coordinator := shutdown.InitCoordinator(logger.Named("shutdown"))
+
+	coordinator.Add("readiness", shutdownCfg.PreStopDelay+time.Second, func(ctx context.Context) error {
+		checker.Drain()
+		return shutdown.Delay(ctx, shutdownCfg.PreStopDelay)
+	})
+	coordinator.Add("http", shutdownCfg.DrainTimeout, func(ctx context.Context) error {
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			return err
+		}
+		return nil
+	})
+	coordinator.Add("background", shutdownCfg.CloseTimeout, func(ctx context.Context) error {
+		stopBackground()
+		done := make(chan struct{})
+		go func() {
+			listeners.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	coordinator.Add("jobs", shutdownCfg.JobsTimeout, jobs.Wait)
+	coordinator.Add("outbox", shutdownCfg.JobsTimeout, dispatcher.Stop)
+	coordinator.Add("grpc", shutdownCfg.CloseTimeout, func(ctx context.Context) error {
+		var errs []error
+		for _, conn := range conns {
+			errs = append(errs, conn.Close())
+		}
+		return errors.Join(errs...)
+	})
+	coordinator.Add("redis", shutdownCfg.CloseTimeout, func(ctx context.Context) error {
+		return cache.Close()
+	})
+	coordinator.Add("audit", shutdownCfg.CloseTimeout, func(ctx context.Context) error {
+		closeAudit()
+		return nil
+	})
+	coordinator.Add("tracing", shutdownCfg.CloseTimeout, shutdownTracing)
+	coordinator.Add("logs", shutdownCfg.CloseTimeout, func(ctx context.Context) error {
+		return closeAccessLog()
+	})
+
+	if err := coordinator.Shutdown(context.Background()); err != nil {
 		msg := fmt.Errorf("server forced to shutdown: %v", err)
 		exceptions.HandleAnException(msg)
 	}
@@ -120,8 +297,46 @@ func main() {
 	log.Println("server exited properly")
 }
 
-func initClients(cfg *config.Config, logger *zap.Logger) map[string]any {
+// initAuditTrail -> build the audit trail from the configured sinks,
+// the returned func closes the sinks holding a file
+func initAuditTrail(cfg config.AuditConfig, logger *zap.Logger) (*audit.Trail, func()) {
+	sinks := []audit.Sink{}
+	closers := []func() error{}
+
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "log":
+			sinks = append(sinks, audit.InitLogSink(logger))
+		case "file":
+			sink, err := audit.InitFileSink(cfg.FilePath)
+			if err != nil {
+				logger.Fatal("failed to open the audit file", zap.String("path", cfg.FilePath), zap.Error(err))
+			}
+			sinks = append(sinks, sink)
+			closers = append(closers, sink.Close)
+		case "redis":
+			if cfg.StreamMaxLen > 0 {
+				logger.Warn("the redis audit sink deletes the oldest events", zap.Int64("maxLen", cfg.StreamMaxLen))
+			}
+			sinks = append(sinks, audit.InitRedisSink(cache.InitCacheService(), cfg.StreamMaxLen))
+		default:
+			logger.Fatal("unknown audit sink", zap.String("sink", name))
+		}
+	}
+
+	return audit.InitTrail(logger, sinks...), func() {
+		for _, closeSink := range closers {
+			if err := closeSink(); err != nil {
+				logger.Error("failed to close an audit sink", zap.Error(err))
+			}
+		}
+	}
+}
+
+// initClients -> the service clients and their connections by the service name
+func initClients(cfg *config.Config, logger *zap.Logger) (map[string]any, map[string]*grpc.ClientConn) {
 	clients := make(map[string]any)
+	conns := make(map[string]*grpc.ClientConn)
 
 	env := os.Getenv("GO_ENV")
 	retryOpts := []grpc_retry.CallOption{
@@ -147,10 +362,10 @@ func initClients(cfg *config.Config, logger *zap.Logger) map[string]any {
 
 		client := initServiceClient(service, conn)
 		clients[service] = client
-
+		conns[service] = conn
 	}
 
-	return clients
+	return clients, conns
 }
 
 func initClientWithRetry(address string, retryOpts []grpc_retry.CallOption, tlsConfig config.TLSConfig) (*grpc.ClientConn, error) {
@@ -173,7 +388,11 @@ func initClientWithRetry(address string, retryOpts []grpc_retry.CallOption, tlsC
 	return grpc.NewClient(
 		address,
 		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(middlewares.ClientTracing()),
 		grpc.WithChainUnaryInterceptor(
+			middlewares.UnaryClientRequestId,
+			middlewares.UnaryClientMetrics(),
+			middlewares.UnaryClientFailFast,
 			grpc_retry.UnaryClientInterceptor(retryOpts...),
 		),
 	)
@@ -187,33 +406,53 @@ func initClientWithRetry(address string, retryOpts []grpc_retry.CallOption, tlsC
 // 	}
 // }
 
-func registerRoutes(mux *http.ServeMux, userHandler *userRoutes.Handler, adminHandler *adminRoutes.AdminHandler) error {
+func registerRoutes(api *router.Router, userHandler *userRoutes.Handler, adminHandler *adminRoutes.AdminHandler) error {
+	v1 := api.Version("v1")
+
 	// register user routes
-	userHandler.RegisterAuthRoutes(mux)
-	userHandler.RegisterProfileRoutes(mux)
-	userHandler.RegisterBlogRoutes(mux)
-	userHandler.RegisterOffersRoutes(mux)
-	userHandler.RegisterOrdersRoutes(mux)
-	userHandler.RegisterReviewsRoutes(mux)
-	userHandler.RegisterPaymentsRoutes(mux)
-	userHandler.RegisterNotificationsRoutes(mux)
+	userHandler.RegisterAuthRoutes(v1)
+	userHandler.RegisterProfileRoutes(v1)
+	userHandler.RegisterBlogRoutes(v1)
+	userHandler.RegisterOffersRoutes(v1)
+	userHandler.RegisterOrdersRoutes(v1)
+	userHandler.RegisterReviewsRoutes(v1)
+	userHandler.RegisterPaymentsRoutes(v1)
+	userHandler.RegisterNotificationsRoutes(v1)
+	userHandler.RegisterChatRoutes(v1)
 
 	// register admin routes
-	adminHandler.RegisterAdminRoutes(mux)
+	adminHandler.RegisterAdminRoutes(v1)
+
+	// the next version should inherit v1 and override only the changed routes:
+	//
+	//	v2 := api.Version("v2").Inherit(v1)
+	//	v2.HandleFunc("GET /profile/get/", middlewares.Authenticated(), userHandler.GetCustomerProfileV2Handler)
+	//	v1.Deprecate(router.Deprecation{Since: ..., Sunset: ...})
 
-	return nil
+	return api.Mount()
 }
 
-func healthCheckHandler(clients map[string]any) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		for service, client := range clients {
-			if !isServiceHealthy(client) {
-				http.Error(w, fmt.Sprintf("%s service unhealthy", service), http.StatusServiceUnavailable)
-				return
-			}
+// initHealthChecker -> check redis and every backend service with a client,
+// the payments service has no client yet and is not checked
+//
+// the backends are checked by the standard grpc health protocol,
+// those not implementing it by their own HealthCheck rpc
+func initHealthChecker(cfg config.HealthConfig, clients map[string]any, conns map[string]*grpc.ClientConn, watcher *health.Watcher) *health.Checker {
+	checker := health.InitChecker(cfg)
+	checker.UseWatcher(watcher)
+
+	cacheService := cache.InitCacheService()
+	checker.Add("redis", cacheService.Ping)
+
+	for service, client := range clients {
+		if client == nil {
+			continue
 		}
-		w.WriteHeader(http.StatusOK)
-	})
+		checker.Add(service, health.GrpcProbe(conns[service], func(ctx context.Context) error {
+			return checkService(ctx, client)
+		}))
+	}
+	return checker
 }
 
 func initClient(serverAddress string, retryOpts []grpc_retry.CallOption) (*grpc.ClientConn, error) {
@@ -225,7 +464,11 @@ func initClient(serverAddress string, retryOpts []grpc_retry.CallOption) (*grpc.
 	conn, err := grpc.NewClient(
 		serverAddress,
 		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(middlewares.ClientTracing()),
 		grpc.WithChainUnaryInterceptor(
+			middlewares.UnaryClientRequestId,
+			middlewares.UnaryClientMetrics(),
+			middlewares.UnaryClientFailFast,
 			grpc_retry.UnaryClientInterceptor(retryOpts...),
 		),
 	)
@@ -264,36 +507,34 @@ func initServiceClient(service string, conn *grpc.ClientConn) any {
 	}
 }
 
-func isServiceHealthy(client interface{}) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
+// checkService -> call the custom health check rpc of the backend service
+func checkService(ctx context.Context, client any) error {
 	switch c := client.(type) {
 	case authPb.AuthServiceClient:
 		_, err := c.HealthCheck(ctx, &authPb.HealthCheckRequest{})
-		return err == nil
+		return err
 	case profilePb.ProfileServiceClient:
 		_, err := c.HealthCheck(ctx, &profilePb.HealthCheckRequest{})
-		return err == nil
+		return err
 	case ordersPb.OrdersServiceClient:
 		_, err := c.HealthCheck(ctx, &ordersPb.HealthCheckRequest{})
-		return err == nil
+		return err
 	case reviewsPb.ReviewsServiceClient:
 		_, err := c.HealthCheck(ctx, &reviewsPb.HealthCheckRequest{})
-		return err == nil
+		return err
 	case blogPb.BlogServiceClient:
 		_, err := c.HealthCheck(ctx, &blogPb.HealthCheckRequest{})
-		return err == nil
+		return err
 	// case paymentsPb.PaymentsServiceClient:
 	// 	_, err := c.HealthCheck(ctx, &paymentsPb.HealthCheckRequest{})
-	// 	return err == nil
+	// 	return err
 	case offersPb.OffersServiceClient:
 		_, err := c.HealthCheck(ctx, &offersPb.HealthCheckRequest{})
-		return err == nil
+		return err
 	case notificationsPb.NotificationsServiceClient:
 		_, err := c.HealthCheck(ctx, &notificationsPb.HealthCheckRequest{})
-		return err == nil
+		return err
 	default:
-		return false
+		return fmt.Errorf("unknown service client %T", client)
 	}
 }
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// AuditConfig -> where the audit events are written to
+//
+//   - Sinks -> comma separated sinks from AUDIT_SINKS: "log", "file", "redis",
+//     "log,redis" by default, the first queryable one serves the admin queries
+//   - FilePath -> the json-lines file of the "file" sink (AUDIT_FILE)
+//   - StreamMaxLen -> approximate number of events kept by the "redis" sink, the oldest
+//     ones are deleted past it, 0 keeps every event and is the default (AUDIT_STREAM_MAX_LEN)
+type AuditConfig struct {
+	Sinks        []string
+	FilePath     string
+	StreamMaxLen int64
+}
+
+// GetAuditConfig -> get the audit trail settings from env
+func GetAuditConfig() AuditConfig {
+	return AuditConfig{
+		Sinks:        getEnvList("AUDIT_SINKS", "log,redis"),
+		FilePath:     getEnvString("AUDIT_FILE", "../LOGS/audit.jsonl"),
+		StreamMaxLen: getEnvInt("AUDIT_STREAM_MAX_LEN", 0),
+	}
+}
+
+// getEnvString -> the env value or the default one if it's unset
+func getEnvString(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// AuthConfig -> access token verification settings
+//
+//   - JWKSUrl -> an endpoint to fetch the auth service signing keys from
+//   - KeyFile -> a PEM file with public keys, used when JWKSUrl is not set
+//   - Issuer, Audience -> expected "iss" and "aud" claims, skipped if empty
+//   - KeysRefreshInterval -> how often the signing keys are reloaded
+//   - TokenCacheTTL -> how long a verified token is served from memory
+//   - RemoteFallback -> validate by the auth service when a token can't be verified locally
+//   - SessionSecret -> a secret to sign the gateway issued access tokens with
+//   - AccessTokenTTL, RefreshTokenTTL -> lifetime of the gateway issued tokens,
+//     a session can't outlive its first refresh token
+//   - PasswordResetTTL -> lifetime of a password reset link
+//   - PasswordResetUrl -> a client page the reset token is appended to
+//   - MaxFailedAttempts -> failed sign-in or code attempts per email or ip
+//     within FailedAttemptsWindow before a temporary LockoutDuration
+//   - MaxAttemptDelay -> upper bound of the growing delay between failed attempts
+//   - MaxCodeAttempts -> wrong guesses of an emailed code before it is invalidated
+type AuthConfig struct {
+	JWKSUrl             string
+	KeyFile             string
+	Issuer              string
+	Audience            string
+	KeysRefreshInterval time.Duration
+	TokenCacheTTL       time.Duration
+	RemoteFallback      bool
+	SessionSecret       string
+	AccessTokenTTL      time.Duration
+	RefreshTokenTTL     time.Duration
+	PasswordResetTTL    time.Duration
+	PasswordResetUrl    string
+
+	MaxFailedAttempts    int64
+	FailedAttemptsWindow time.Duration
+	LockoutDuration      time.Duration
+	MaxAttemptDelay      time.Duration
+	MaxCodeAttempts      int64
+}
+
+// GetAuthConfig -> get the access token verification settings from env
+func GetAuthConfig() AuthConfig {
+	return AuthConfig{
+		JWKSUrl:             os.Getenv("AUTH_JWKS_URL"),
+		KeyFile:             os.Getenv("AUTH_KEY_FILE"),
+		Issuer:              os.Getenv("AUTH_TOKEN_ISSUER"),
+		Audience:            os.Getenv("AUTH_TOKEN_AUDIENCE"),
+		KeysRefreshInterval: getEnvDuration("AUTH_KEYS_REFRESH_INTERVAL", 15*time.Minute),
+		TokenCacheTTL:       getEnvDuration("AUTH_TOKEN_CACHE_TTL", 30*time.Second),
+		RemoteFallback:      getEnvBool("AUTH_REMOTE_FALLBACK", true),
+		SessionSecret:       os.Getenv("AUTH_SESSION_SECRET"),
+		AccessTokenTTL:      getEnvDuration("AUTH_ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:     getEnvDuration("AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordResetTTL:    getEnvDuration("AUTH_PASSWORD_RESET_TTL", 30*time.Minute),
+		PasswordResetUrl:    os.Getenv("AUTH_PASSWORD_RESET_URL"),
+
+		MaxFailedAttempts:    getEnvInt("AUTH_MAX_FAILED_ATTEMPTS", 5),
+		FailedAttemptsWindow: getEnvDuration("AUTH_FAILED_ATTEMPTS_WINDOW", 15*time.Minute),
+		LockoutDuration:      getEnvDuration("AUTH_LOCKOUT_DURATION", 15*time.Minute),
+		MaxAttemptDelay:      getEnvDuration("AUTH_MAX_ATTEMPT_DELAY", 30*time.Second),
+		MaxCodeAttempts:      getEnvInt("AUTH_MAX_CODE_ATTEMPTS", 3),
+	}
+}
+
+// getEnvDuration -> parse a duration env value like "30s" or return the default one
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// getEnvInt -> parse a positive int env value or return the default one
+func getEnvInt(key string, def int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// getEnvBool -> parse a bool env value or return the default one
+func getEnvBool(key string, def bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
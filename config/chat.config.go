@@ -0,0 +1,19 @@
+package config
+
+import "os"
+
+// ChatConfig -> the chats of the customers
+//
+//   - InMemory -> keep the chats in memory of the instance, as there is no chat service yet,
+//     they're neither shared by the instances nor kept over a restart, so it's ignored
+//     in production and the chat routes aren't served there (CHAT_IN_MEMORY)
+type ChatConfig struct {
+	InMemory bool
+}
+
+// GetChatConfig -> get the chats settings from env
+func GetChatConfig() ChatConfig {
+	return ChatConfig{
+		InMemory: os.Getenv("GO_ENV") != "production" && getEnvBool("CHAT_IN_MEMORY", false),
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// HealthConfig -> the dependency health checks settings
+//
+//   - CheckTimeout -> how long a single dependency check may take (HEALTH_CHECK_TIMEOUT)
+//   - CacheTTL -> how long a report is reused before the dependencies are checked again,
+//     so frequent probes don't hit the backends on every call (HEALTH_CACHE_TTL)
+//   - Critical -> comma separated dependencies the gateway can't serve without,
+//     "auth,redis" by default (HEALTH_CRITICAL), the rest are optional
+//     and don't fail the readiness check
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	CacheTTL     time.Duration
+	Critical     []string
+}
+
+// GetHealthConfig -> get the health checks settings from env
+func GetHealthConfig() HealthConfig {
+	return HealthConfig{
+		CheckTimeout: getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		CacheTTL:     getEnvDuration("HEALTH_CACHE_TTL", 5*time.Second),
+		Critical:     getEnvList("HEALTH_CRITICAL", "auth,redis"),
+	}
+}
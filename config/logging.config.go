@@ -0,0 +1,71 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessLogConfig -> the request access log settings
+//
+//   - Sinks -> comma separated sinks from ACCESS_LOG_SINKS: "stdout" (default), "file"
+//   - FilePath -> the file of the "file" sink (ACCESS_LOG_FILE)
+//   - MaxSize -> the file is rotated once it grows over it (ACCESS_LOG_MAX_SIZE_MB)
+//   - MaxBackups, MaxAge -> how many rotated files are kept and for how long
+//     (ACCESS_LOG_MAX_BACKUPS, ACCESS_LOG_MAX_AGE)
+//   - Redact -> query parameters logged as "[REDACTED]" (ACCESS_LOG_REDACT)
+//   - Sampling -> log 1 of every N successful requests of the route pattern,
+//     set as "{pattern}={N}" pairs, e.g. "/health/=100,/metrics/=100" (ACCESS_LOG_SAMPLING),
+//     failed requests are always logged
+type AccessLogConfig struct {
+	Enabled    bool
+	Sinks      []string
+	FilePath   string
+	MaxSize    int64
+	MaxBackups int
+	MaxAge     time.Duration
+	Redact     []string
+	Sampling   map[string]uint64
+}
+
+// GetAccessLogConfig -> get the access log settings from env
+func GetAccessLogConfig() AccessLogConfig {
+	return AccessLogConfig{
+		Enabled:    getEnvBool("ACCESS_LOG_ENABLED", true),
+		Sinks:      getEnvList("ACCESS_LOG_SINKS", "stdout"),
+		FilePath:   getEnvString("ACCESS_LOG_FILE", "../LOGS/access.log"),
+		MaxSize:    getEnvInt("ACCESS_LOG_MAX_SIZE_MB", 100) << 20,
+		MaxBackups: int(getEnvInt("ACCESS_LOG_MAX_BACKUPS", 7)),
+		MaxAge:     getEnvDuration("ACCESS_LOG_MAX_AGE", 7*24*time.Hour),
+		Redact:     getEnvList("ACCESS_LOG_REDACT", "token,refreshToken,code,password,secret,email"),
+		Sampling:   getEnvSampling("ACCESS_LOG_SAMPLING", "/livez=100,/readyz=100,/health=100,/health/=100,/metrics/=100"),
+	}
+}
+
+// getEnvList -> split a comma separated env value, the default one is used if it's unset
+func getEnvList(key, def string) []string {
+	list := []string{}
+	for _, item := range strings.Split(getEnvString(key, def), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvSampling -> parse "{pattern}={N}" pairs, the invalid ones are skipped
+func getEnvSampling(key, def string) map[string]uint64 {
+	sampling := make(map[string]uint64)
+	for _, pair := range getEnvList(key, def) {
+		pattern, n, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		every, err := strconv.ParseUint(strings.TrimSpace(n), 10, 64)
+		if err != nil || every == 0 {
+			continue
+		}
+		sampling[strings.TrimSpace(pattern)] = every
+	}
+	return sampling
+}
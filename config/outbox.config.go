@@ -0,0 +1,37 @@
+package config
+
+import "time"
+
+// OutboxConfig -> the delivery of the notifications written to the outbox
+//
+//   - Workers -> number of the workers delivering the messages (OUTBOX_WORKERS)
+//   - PollInterval -> how long a worker waits for new messages and how often
+//     the due retries are moved back to the queue (OUTBOX_POLL_INTERVAL)
+//   - DeliveryTimeout -> the deadline of a single delivery attempt (OUTBOX_DELIVERY_TIMEOUT)
+//   - MaxAttempts -> delivery attempts before a message is dead-lettered (OUTBOX_MAX_ATTEMPTS)
+//   - RetryBackoff -> the delay before the first retry, doubled on every next one (OUTBOX_RETRY_BACKOFF)
+//   - MaxBackoff -> the upper bound of the retry delay (OUTBOX_MAX_BACKOFF)
+//   - ClaimIdle -> how long a message may stay unacknowledged before another worker
+//     takes it over, e.g. after a gateway crash (OUTBOX_CLAIM_IDLE)
+type OutboxConfig struct {
+	Workers         int64
+	PollInterval    time.Duration
+	DeliveryTimeout time.Duration
+	MaxAttempts     int64
+	RetryBackoff    time.Duration
+	MaxBackoff      time.Duration
+	ClaimIdle       time.Duration
+}
+
+// GetOutboxConfig -> get the outbox delivery settings from env
+func GetOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		Workers:         getEnvInt("OUTBOX_WORKERS", 4),
+		PollInterval:    getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		DeliveryTimeout: getEnvDuration("OUTBOX_DELIVERY_TIMEOUT", 5*time.Second),
+		MaxAttempts:     getEnvInt("OUTBOX_MAX_ATTEMPTS", 8),
+		RetryBackoff:    getEnvDuration("OUTBOX_RETRY_BACKOFF", time.Second),
+		MaxBackoff:      getEnvDuration("OUTBOX_MAX_BACKOFF", 5*time.Minute),
+		ClaimIdle:       getEnvDuration("OUTBOX_CLAIM_IDLE", time.Minute),
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit -> a number of requests allowed within a sliding window
+type RateLimit struct {
+	Requests int64
+	Window   time.Duration
+}
+
+// RateLimitConfig -> request quotas per route group
+//
+//   - Enabled -> turn the limiter on, enabled by default
+//   - Store -> "redis" to share the quotas between instances or "memory" for a single one,
+//     the memory store is also used while redis is unavailable
+//   - Groups -> a quota of every route group, set as "{requests}/{window}", e.g. "10/1m":
+//     "default" (RATE_LIMIT_DEFAULT), "auth" (RATE_LIMIT_AUTH), "email" (RATE_LIMIT_EMAIL)
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string
+	Groups  map[string]RateLimit
+}
+
+// GetRateLimitConfig -> get the rate limiter settings from env
+func GetRateLimitConfig() RateLimitConfig {
+	store := os.Getenv("RATE_LIMIT_STORE")
+	if store != "memory" {
+		store = "redis"
+	}
+
+	return RateLimitConfig{
+		Enabled: getEnvBool("RATE_LIMIT_ENABLED", true),
+		Store:   store,
+		Groups: map[string]RateLimit{
+			"default": getEnvRateLimit("RATE_LIMIT_DEFAULT", RateLimit{Requests: 300, Window: time.Minute}),
+			"auth":    getEnvRateLimit("RATE_LIMIT_AUTH", RateLimit{Requests: 10, Window: time.Minute}),
+			"email":   getEnvRateLimit("RATE_LIMIT_EMAIL", RateLimit{Requests: 5, Window: 15 * time.Minute}),
+		},
+	}
+}
+
+// getEnvRateLimit -> parse a "{requests}/{window}" env value or return the default one
+func getEnvRateLimit(key string, def RateLimit) RateLimit {
+	requests, window, ok := strings.Cut(os.Getenv(key), "/")
+	if !ok {
+		return def
+	}
+
+	limit := RateLimit{}
+	var err error
+	if limit.Requests, err = strconv.ParseInt(strings.TrimSpace(requests), 10, 64); err != nil || limit.Requests <= 0 {
+		return def
+	}
+	if limit.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil || limit.Window <= 0 {
+		return def
+	}
+	return limit
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// RBACConfig -> permissions granted to every role
+//
+// loaded from a JSON file set by RBAC_CONFIG_FILE, e.g.
+//
+//	{
+//		"ADMIN": ["*"],
+//		"SUPERVISOR": ["admin:access", "orders:*"]
+//	}
+//
+// a role without an entry has no permissions
+type RBACConfig struct {
+	Roles map[string][]string
+}
+
+// GetRBACConfig -> get the role permissions from the file or the default ones
+func GetRBACConfig() (RBACConfig, error) {
+	path := os.Getenv("RBAC_CONFIG_FILE")
+	if path == "" {
+		return RBACConfig{Roles: defaultRolePermissions()}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RBACConfig{}, fmt.Errorf("cannot read rbac config: %w", err)
+	}
+
+	var roles map[string][]string
+	if err = json.Unmarshal(data, &roles); err != nil {
+		return RBACConfig{}, fmt.Errorf("cannot parse rbac config: %w", err)
+	}
+	return RBACConfig{Roles: roles}, nil
+}
+
+// defaultRolePermissions -> the permissions the hard-coded role checks used to grant,
+// supervisors also get the read-only and moderation parts of the admin api
+func defaultRolePermissions() map[string][]string {
+	return map[string][]string{
+		"ADMIN": {"*"},
+		"SUPERVISOR": {
+			"admin:access",
+			"orders:update_applied",
+			"orders:delete_applied",
+			"customers:read",
+			"orders:read",
+			"reviews:moderate",
+			"blog:moderate",
+		},
+	}
+}
@@ -239,3 +239,148 @@ func GetNotificationsRedisConfig() *redis.Options {
 
 	return opts
 }
+
+func GetSessionsRedisConfig() *redis.Options {
+	var opts *redis.Options
+	env := os.Getenv("GO_ENV")
+
+	switch env {
+	case "development":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   8,
+		}
+	case "production":
+		opts = &redis.Options{
+			Addr:     os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+			Username: os.Getenv("REDIS_USERNAME"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       8,
+		}
+	case "test":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   8,
+		}
+	default:
+		return nil
+	}
+
+	return opts
+}
+
+func GetRateLimitRedisConfig() *redis.Options {
+	var opts *redis.Options
+	env := os.Getenv("GO_ENV")
+
+	switch env {
+	case "development":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   9,
+		}
+	case "production":
+		opts = &redis.Options{
+			Addr:     os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+			Username: os.Getenv("REDIS_USERNAME"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       9,
+		}
+	case "test":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   9,
+		}
+	default:
+		return nil
+	}
+
+	return opts
+}
+
+func GetAuditRedisConfig() *redis.Options {
+	var opts *redis.Options
+	env := os.Getenv("GO_ENV")
+
+	switch env {
+	case "development":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   10,
+		}
+	case "production":
+		opts = &redis.Options{
+			Addr:     os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+			Username: os.Getenv("REDIS_USERNAME"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       10,
+		}
+	case "test":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   10,
+		}
+	default:
+		return nil
+	}
+
+	return opts
+}
+
+func GetOutboxRedisConfig() *redis.Options {
+	var opts *redis.Options
+	env := os.Getenv("GO_ENV")
+
+	switch env {
+	case "development":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   11,
+		}
+	case "production":
+		opts = &redis.Options{
+			Addr:     os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+			Username: os.Getenv("REDIS_USERNAME"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       11,
+		}
+	case "test":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   11,
+		}
+	default:
+		return nil
+	}
+
+	return opts
+}
+
+func GetChatRedisConfig() *redis.Options {
+	var opts *redis.Options
+	env := os.Getenv("GO_ENV")
+
+	switch env {
+	case "development":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   12,
+		}
+	case "production":
+		opts = &redis.Options{
+			Addr:     os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
+			Username: os.Getenv("REDIS_USERNAME"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       12,
+		}
+	case "test":
+		opts = &redis.Options{
+			Addr: "127.0.0.1" + ":" + "6379",
+			DB:   12,
+		}
+	default:
+		return nil
+	}
+
+	return opts
+}
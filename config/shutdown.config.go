@@ -0,0 +1,27 @@
+package config
+
+import "time"
+
+// ShutdownConfig -> the timeouts of the graceful shutdown phases
+//
+//   - PreStopDelay -> how long the gateway keeps serving once the readiness check fails,
+//     so the load balancer stops sending new requests first (SHUTDOWN_PRE_STOP_DELAY)
+//   - DrainTimeout -> how long the in-flight requests are waited for (SHUTDOWN_DRAIN_TIMEOUT)
+//   - JobsTimeout -> how long the background jobs are waited for (SHUTDOWN_JOBS_TIMEOUT)
+//   - CloseTimeout -> how long closing the connections and flushing the logs may take (SHUTDOWN_CLOSE_TIMEOUT)
+type ShutdownConfig struct {
+	PreStopDelay time.Duration
+	DrainTimeout time.Duration
+	JobsTimeout  time.Duration
+	CloseTimeout time.Duration
+}
+
+// GetShutdownConfig -> get the graceful shutdown settings from env
+func GetShutdownConfig() ShutdownConfig {
+	return ShutdownConfig{
+		PreStopDelay: getEnvDuration("SHUTDOWN_PRE_STOP_DELAY", 5*time.Second),
+		DrainTimeout: getEnvDuration("SHUTDOWN_DRAIN_TIMEOUT", 20*time.Second),
+		JobsTimeout:  getEnvDuration("SHUTDOWN_JOBS_TIMEOUT", 10*time.Second),
+		CloseTimeout: getEnvDuration("SHUTDOWN_CLOSE_TIMEOUT", 5*time.Second),
+	}
+}
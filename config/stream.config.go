@@ -0,0 +1,36 @@
+package config
+
+import "time"
+
+// StreamConfig -> the real-time notification and chat streams
+//
+//   - Heartbeat -> how often an idle stream is pinged, so the proxies don't close it (STREAM_HEARTBEAT)
+//   - RetryDelay -> the reconnection delay suggested to the SSE clients (STREAM_RETRY_DELAY)
+//   - BufferSize -> number of the events queued for a slow connection,
+//     the next ones are dropped until it catches up (STREAM_BUFFER_SIZE)
+//   - MaxConnections -> number of the streams of each kind a customer may keep open
+//     on a gateway instance (STREAM_MAX_CONNECTIONS)
+//   - WebSocket -> serve the notification streams over WebSocket besides SSE,
+//     the chat streams are WebSocket only (STREAM_WEBSOCKET_ENABLED)
+//   - OriginPatterns -> the cross-origin hosts allowed to open a WebSocket,
+//     the same host only by default (STREAM_WEBSOCKET_ORIGINS)
+type StreamConfig struct {
+	Heartbeat      time.Duration
+	RetryDelay     time.Duration
+	BufferSize     int64
+	MaxConnections int64
+	WebSocket      bool
+	OriginPatterns []string
+}
+
+// GetStreamConfig -> get the streams settings from env
+func GetStreamConfig() StreamConfig {
+	return StreamConfig{
+		Heartbeat:      getEnvDuration("STREAM_HEARTBEAT", 25*time.Second),
+		RetryDelay:     getEnvDuration("STREAM_RETRY_DELAY", 3*time.Second),
+		BufferSize:     getEnvInt("STREAM_BUFFER_SIZE", 16),
+		MaxConnections: getEnvInt("STREAM_MAX_CONNECTIONS", 5),
+		WebSocket:      getEnvBool("STREAM_WEBSOCKET_ENABLED", false),
+		OriginPatterns: getEnvList("STREAM_WEBSOCKET_ORIGINS", ""),
+	}
+}
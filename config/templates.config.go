@@ -0,0 +1,25 @@
+package config
+
+import "strings"
+
+// TemplatesConfig -> the notification and email templates
+//
+//   - Dir -> a directory with "{locale}/{name}.tmpl" files overriding or adding
+//     to the embedded templates (TEMPLATES_DIR), unset by default
+//   - DefaultLocale -> the locale used when the customer's one has no template (TEMPLATES_DEFAULT_LOCALE)
+//   - EmailFormat -> the variant sent as the email body: "text" (default) or "html"
+//     (TEMPLATES_EMAIL_FORMAT), the notifications service takes a single body
+type TemplatesConfig struct {
+	Dir           string
+	DefaultLocale string
+	EmailFormat   string
+}
+
+// GetTemplatesConfig -> get the templates settings from env
+func GetTemplatesConfig() TemplatesConfig {
+	return TemplatesConfig{
+		Dir:           getEnvString("TEMPLATES_DIR", ""),
+		DefaultLocale: strings.ToLower(getEnvString("TEMPLATES_DEFAULT_LOCALE", "en")),
+		EmailFormat:   strings.ToLower(getEnvString("TEMPLATES_EMAIL_FORMAT", "text")),
+	}
+}
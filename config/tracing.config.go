@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TracingConfig -> the opentelemetry tracing settings
+//
+//   - Exporter -> where the spans are exported to from TRACING_EXPORTER:
+//     "none" (default), "stdout", "otlp"
+//   - OtlpEndpoint -> the grpc endpoint of the otlp collector (TRACING_OTLP_ENDPOINT)
+//   - OtlpInsecure -> connect to the collector without tls (TRACING_OTLP_INSECURE)
+//   - SampleRatio -> the share of the root spans sampled, from 0 to 1 (TRACING_SAMPLE_RATIO),
+//     the child spans follow the decision of their parent
+//   - ServiceName -> the service.name resource attribute of every span (TRACING_SERVICE_NAME)
+type TracingConfig struct {
+	Exporter     string
+	OtlpEndpoint string
+	OtlpInsecure bool
+	SampleRatio  float64
+	ServiceName  string
+}
+
+// GetTracingConfig -> get the tracing settings from env
+func GetTracingConfig() TracingConfig {
+	return TracingConfig{
+		Exporter:     strings.ToLower(getEnvString("TRACING_EXPORTER", "none")),
+		OtlpEndpoint: getEnvString("TRACING_OTLP_ENDPOINT", "localhost:4317"),
+		OtlpInsecure: getEnvBool("TRACING_OTLP_INSECURE", false),
+		SampleRatio:  getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		ServiceName:  getEnvString("TRACING_SERVICE_NAME", "anvil-gateway"),
+	}
+}
+
+// getEnvFloat -> parse a non-negative float env value or return the default one
+func getEnvFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value < 0 {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,206 @@
+package fanout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/shutdown"
+	"go.uber.org/zap"
+)
+
+// listenRetryDelay -> the pause before the lost subscription is renewed
+const listenRetryDelay = time.Second
+
+// ErrTooManyStreams -> the owner has every allowed stream open already
+var ErrTooManyStreams = errors.New("too many open streams")
+
+// ErrClosed -> the hub is closed by the shutdown
+var ErrClosed = errors.New("streams are closed")
+
+// Kind -> what a hub streams and the redis pub/sub it's published by, a channel per id,
+// the names prefix the hub metrics, e.g. the "chat" streams of "messages"
+type Kind struct {
+	Name    string
+	Items   string
+	Publish func(ctx context.Context, id uint64, payload []byte) error
+	Listen  func(ctx context.Context, ready func(), handle func(id uint64, payload []byte)) error
+}
+
+// Hub -> the streams of the json encoded items opened to a gateway instance
+//
+// the items are published to the redis channel of their id (a customer, a chat),
+// so every instance gets them and pushes them to the streams it holds,
+// the limit of the streams is of their owner to any channel
+type Hub[T any] struct {
+	cfg       config.StreamConfig
+	kind      Kind
+	logger    *zap.Logger
+	metrics   *hubMetrics
+	listening atomic.Bool
+
+	mu      sync.Mutex
+	streams map[uint64]map[*Subscription[T]]struct{}
+	owners  map[uint64]int64
+	closed  bool
+}
+
+func InitHub[T any](cfg config.StreamConfig, kind Kind, logger *zap.Logger) *Hub[T] {
+	return &Hub[T]{
+		cfg:     cfg,
+		kind:    kind,
+		logger:  logger,
+		metrics: initHubMetrics(kind),
+		streams: make(map[uint64]map[*Subscription[T]]struct{}),
+		owners:  make(map[uint64]int64),
+	}
+}
+
+// Subscription -> the items of a single stream of the owner to a channel
+type Subscription[T any] struct {
+	hub       *Hub[T]
+	channelId uint64
+	ownerId   uint64
+	items     chan T
+	closed    bool
+}
+
+// ChannelId -> the channel the stream is opened to
+func (s *Subscription[T]) ChannelId() uint64 {
+	return s.channelId
+}
+
+// OwnerId -> the customer the stream is opened by
+func (s *Subscription[T]) OwnerId() uint64 {
+	return s.ownerId
+}
+
+// Items -> the channel's new items, closed with the subscription
+func (s *Subscription[T]) Items() <-chan T {
+	return s.items
+}
+
+// Close -> stop getting the items
+func (s *Subscription[T]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Run -> receive the items published by every gateway instance until ctx is done,
+// the subscription is renewed once it's lost
+func (h *Hub[T]) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := h.kind.Listen(ctx, func() { h.listening.Store(true) }, h.dispatch)
+		h.listening.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		h.logger.Error("the subscription is lost", zap.Error(err))
+		shutdown.Delay(ctx, listenRetryDelay)
+	}
+}
+
+// Listening -> whether the items of the other instances are received
+func (h *Hub[T]) Listening() bool {
+	return h.listening.Load()
+}
+
+// AllowsWebSocket -> whether the streams are served over WebSocket besides SSE
+func (h *Hub[T]) AllowsWebSocket() bool {
+	return h.cfg.WebSocket
+}
+
+// Publish -> push the item to every stream of the channel on any instance
+func (h *Hub[T]) Publish(ctx context.Context, channelId uint64, item T) error {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if err = h.kind.Publish(ctx, channelId, payload); err != nil {
+		return err
+	}
+	h.metrics.published.Inc()
+	return nil
+}
+
+// Subscribe -> open a stream of the channel's items for the owner,
+// the caller checks the owner has access to the channel
+func (h *Hub[T]) Subscribe(channelId, ownerId uint64) (*Subscription[T], error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.owners[ownerId] >= h.cfg.MaxConnections {
+		return nil, ErrTooManyStreams
+	}
+
+	s := &Subscription[T]{hub: h, channelId: channelId, ownerId: ownerId, items: make(chan T, h.cfg.BufferSize)}
+	if h.streams[channelId] == nil {
+		h.streams[channelId] = make(map[*Subscription[T]]struct{})
+	}
+	h.streams[channelId][s] = struct{}{}
+	h.owners[ownerId]++
+	return s, nil
+}
+
+// Close -> close every stream and refuse the new ones,
+// the open streams hold their connections until closed, so it's called
+// once the http server starts shutting down
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, streams := range h.streams {
+		for s := range streams {
+			h.remove(s)
+		}
+	}
+}
+
+// dispatch -> push a published item to the channel's streams of this instance,
+// a stream not keeping up loses the item instead of holding the others
+func (h *Hub[T]) dispatch(channelId uint64, payload []byte) {
+	var item T
+	if err := json.Unmarshal(payload, &item); err != nil {
+		h.logger.Warn("a malformed item is skipped", zap.Uint64("channelId", channelId), zap.Error(err))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.streams[channelId] {
+		select {
+		case s.items <- item:
+			h.metrics.items.WithLabelValues(resultDelivered).Inc()
+		default:
+			h.metrics.items.WithLabelValues(resultDropped).Inc()
+		}
+	}
+}
+
+// remove -> unsubscribe the stream, the caller holds the lock
+func (h *Hub[T]) remove(s *Subscription[T]) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.items)
+
+	delete(h.streams[s.channelId], s)
+	if len(h.streams[s.channelId]) == 0 {
+		delete(h.streams, s.channelId)
+	}
+	h.owners[s.ownerId]--
+	if h.owners[s.ownerId] <= 0 {
+		delete(h.owners, s.ownerId)
+	}
+}
@@ -0,0 +1,47 @@
+package fanout
+
+import (
+	"fmt"
+
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// results of pushing an item to a stream
+const (
+	resultDelivered = "delivered"
+	resultDropped   = "dropped"
+)
+
+// hubMetrics -> the open streams and the items pushed to them,
+// the dropped items are the ones lost by the slow connections
+type hubMetrics struct {
+	published   prometheus.Counter
+	items       *prometheus.CounterVec
+	connections *prometheus.GaugeVec
+}
+
+func initHubMetrics(kind Kind) *hubMetrics {
+	published := metrics.Register(prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_%s_published_total", kind.Name, kind.Items),
+			Help: fmt.Sprintf("Total number of %s %s published to the channels", kind.Name, kind.Items),
+		},
+	))
+	items := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_%s_total", kind.Name, kind.Items),
+			Help: fmt.Sprintf("Total number of %s %s pushed to the open streams by result", kind.Name, kind.Items),
+		},
+		[]string{"result"},
+	))
+	connections := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_connections", kind.Name),
+			Help: fmt.Sprintf("Number of open %s streams by transport", kind.Name),
+		},
+		[]string{"transport"},
+	))
+
+	return &hubMetrics{published: published, items: items, connections: connections}
+}
@@ -0,0 +1,182 @@
+package fanout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+)
+
+// transports of the streams
+const (
+	transportSSE       = "sse"
+	transportWebSocket = "websocket"
+)
+
+// Receiver -> handle a json text message of the client,
+// the returned error is sent back to the client as {"error": string}
+type Receiver func(ctx context.Context, payload []byte) error
+
+// errNotText -> the client sent a binary message
+var errNotText = errors.New("a json text message is expected")
+
+// ServeSSE -> push the subscription items as server-sent events of the event type
+// until the client disconnects or the hub is closed, an idle stream gets
+// a heartbeat comment, id gives the event id of an item
+//
+// the server write timeout is replaced by a deadline renewed on every write,
+// so a stream lives as long as the client reads it
+func (h *Hub[T]) ServeSSE(w http.ResponseWriter, r *http.Request, s *Subscription[T], event string, id func(T) string) {
+	rc := http.NewResponseController(w)
+	extendDeadline := func() {
+		rc.SetWriteDeadline(time.Now().Add(2 * h.cfg.Heartbeat))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx buffers the proxied responses otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	extendDeadline()
+	fmt.Fprintf(w, "retry: %d\n\n", h.cfg.RetryDelay.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("the response doesn't support streaming", zap.Error(err))
+		return
+	}
+
+	connections := h.metrics.connections.WithLabelValues(transportSSE)
+	connections.Inc()
+	defer connections.Dec()
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case item, ok := <-s.Items():
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(item)
+			if err != nil {
+				continue
+			}
+			extendDeadline()
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id(item), event, payload)
+		case <-heartbeat.C:
+			extendDeadline()
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// ServeWebSocket -> upgrade the connection and push the subscription items
+// as json text messages until the client disconnects or the hub is closed,
+// an idle connection is pinged every heartbeat
+//
+// the messages of the client are handled by receive, the stream
+// without it is one-way and a message of the client closes it
+func (h *Hub[T]) ServeWebSocket(w http.ResponseWriter, r *http.Request, s *Subscription[T], receive Receiver) {
+	// the hijacked connection keeps the server timeouts otherwise
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: h.cfg.OriginPatterns})
+	if err != nil {
+		// Accept has responded already
+		h.logger.Warn("failed to accept a websocket", zap.Error(err))
+		return
+	}
+	defer conn.CloseNow()
+
+	connections := h.metrics.connections.WithLabelValues(transportWebSocket)
+	connections.Inc()
+	defer connections.Dec()
+
+	var ctx context.Context
+	if receive == nil {
+		ctx = conn.CloseRead(r.Context())
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			defer cancel()
+			h.receive(ctx, conn, receive)
+		}()
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item, ok := <-s.Items():
+			if !ok {
+				conn.Close(websocket.StatusGoingAway, "the server is shutting down")
+				return
+			}
+			payload, err := json.Marshal(item)
+			if err != nil {
+				continue
+			}
+			if err = h.write(ctx, conn, payload); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			pingCtx, cancel := context.WithTimeout(ctx, h.cfg.Heartbeat)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// receive -> handle the messages of the client until the connection is closed
+func (h *Hub[T]) receive(ctx context.Context, conn *websocket.Conn, receive Receiver) {
+	for {
+		kind, payload, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		if kind != websocket.MessageText {
+			err = errNotText
+		} else {
+			err = receive(ctx, payload)
+		}
+		if err == nil {
+			continue
+		}
+
+		reply, _ := json.Marshal(map[string]string{"error": err.Error()})
+		if err = h.write(ctx, conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// write -> send a message within the heartbeat, a client not reading it is dropped
+func (h *Hub[T]) write(ctx context.Context, conn *websocket.Conn, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Heartbeat)
+	defer cancel()
+	return conn.Write(ctx, websocket.MessageText, payload)
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthPb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// connectivityStates -> every state of a grpc connection, exported as a gauge each
+var connectivityStates = []connectivity.State{
+	connectivity.Idle,
+	connectivity.Connecting,
+	connectivity.Ready,
+	connectivity.TransientFailure,
+	connectivity.Shutdown,
+}
+
+// GrpcProbe -> check the backend with the standard grpc.health.v1 protocol,
+// the fallback probe (e.g. the custom HealthCheck rpc) is used from the moment
+// the backend answers it doesn't implement the protocol
+func GrpcProbe(conn grpc.ClientConnInterface, fallback Probe) Probe {
+	client := healthPb.NewHealthClient(conn)
+	var unimplemented atomic.Bool
+
+	return func(ctx context.Context) error {
+		if unimplemented.Load() && fallback != nil {
+			return fallback(ctx)
+		}
+
+		resp, err := client.Check(ctx, &healthPb.HealthCheckRequest{})
+		if status.Code(err) == codes.Unimplemented && fallback != nil {
+			unimplemented.Store(true)
+			return fallback(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthPb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("service is %s", resp.GetStatus())
+		}
+		return nil
+	}
+}
+
+// Watcher -> track the connectivity state of the backend connections in the background
+type Watcher struct {
+	mu     sync.RWMutex
+	states map[string]connectivity.State
+	gauge  *prometheus.GaugeVec
+}
+
+func InitWatcher() *Watcher {
+	gauge := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "grpc_client_connection_state",
+			Help: "Connectivity state of the backend connections, 1 for the current state",
+		},
+		[]string{"service", "state"},
+	))
+
+	return &Watcher{
+		states: make(map[string]connectivity.State),
+		gauge:  gauge,
+	}
+}
+
+// Watch -> follow the state of the connection until ctx is done or the connection is closed,
+// the connection is asked to connect so its state doesn't wait for the first call
+func (w *Watcher) Watch(ctx context.Context, service string, conn *grpc.ClientConn) {
+	conn.Connect()
+	state := conn.GetState()
+	w.set(service, state)
+
+	go func() {
+		for state != connectivity.Shutdown && conn.WaitForStateChange(ctx, state) {
+			state = conn.GetState()
+			w.set(service, state)
+		}
+	}()
+}
+
+// State -> the last known state of the service connection
+func (w *Watcher) State(service string) (connectivity.State, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	state, ok := w.states[service]
+	return state, ok
+}
+
+// Available -> whether a call to the service may succeed, an idle connection
+// reconnects on the next call so only a failed or closed one is unavailable
+func (w *Watcher) Available(service string) bool {
+	state, ok := w.State(service)
+	return !ok || (state != connectivity.TransientFailure && state != connectivity.Shutdown)
+}
+
+func (w *Watcher) set(service string, state connectivity.State) {
+	w.mu.Lock()
+	w.states[service] = state
+	w.mu.Unlock()
+
+	for _, s := range connectivityStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		w.gauge.WithLabelValues(service, s.String()).Set(value)
+	}
+}
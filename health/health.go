@@ -0,0 +1,205 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/config"
+)
+
+// report and dependency statuses
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+	StatusDraining = "draining"
+)
+
+// Probe -> check a single dependency, the context carries the check timeout
+type Probe func(ctx context.Context) error
+
+// Check -> a dependency of the gateway, a critical one fails the readiness check
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    Probe
+}
+
+// Result -> the outcome of a single dependency check
+type Result struct {
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+	Status   string `json:"status"`
+	State    string `json:"state,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Latency  string `json:"latency"`
+}
+
+// Report -> the outcome of every dependency check
+//
+//   - Status -> "up" if every dependency is up, "degraded" if only optional ones are down,
+//     "down" if a critical one is down, "draining" once the gateway is shutting down
+type Report struct {
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Ready -> whether every critical dependency is up and the gateway isn't shutting down
+func (r *Report) Ready() bool {
+	return r.Status == StatusUp || r.Status == StatusDegraded
+}
+
+// Checker -> run the dependency checks concurrently, the report is reused for cacheTTL
+type Checker struct {
+	checks   []Check
+	critical []string
+	timeout  time.Duration
+	ttl      time.Duration
+	watcher  *Watcher
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	report *Report
+}
+
+func InitChecker(cfg config.HealthConfig) *Checker {
+	return &Checker{
+		critical: cfg.Critical,
+		timeout:  cfg.CheckTimeout,
+		ttl:      cfg.CacheTTL,
+	}
+}
+
+// Add -> register a dependency check, it's critical if listed in the config
+func (c *Checker) Add(name string, probe Probe) {
+	c.checks = append(c.checks, Check{Name: name, Critical: slices.Contains(c.critical, name), Probe: probe})
+}
+
+// UseWatcher -> add the connectivity state of the watched backends to their results
+func (c *Checker) UseWatcher(watcher *Watcher) {
+	c.watcher = watcher
+}
+
+// Drain -> fail the readiness check from now on, the first step of the shutdown
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Report -> the cached report or a fresh one once the cached report expires,
+// concurrent callers share a single run of the checks
+func (c *Checker) Report(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining.Load() {
+		return &Report{Status: StatusDraining, CheckedAt: time.Now().UTC(), Checks: []Result{}}
+	}
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.ttl {
+		return c.report
+	}
+
+	report := c.run(ctx)
+	// the checks interrupted by a gone caller say nothing about the dependencies
+	if ctx.Err() == nil {
+		c.report = report
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context) *Report {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.probe(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusUp, CheckedAt: time.Now().UTC(), Checks: results}
+	for _, result := range results {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusDown
+			break
+		}
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+func (c *Checker) probe(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+
+	result := Result{
+		Name:     check.Name,
+		Critical: check.Critical,
+		Status:   StatusUp,
+		Latency:  time.Since(start).Round(time.Microsecond).String(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	if c.watcher != nil {
+		if state, ok := c.watcher.State(check.Name); ok {
+			result.State = state.String()
+		}
+	}
+	return result
+}
+
+// LiveHandler -> the process is up and serving, the dependencies are not checked
+// so a backend outage doesn't get the gateway restarted
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": StatusUp})
+	})
+}
+
+// ReadyHandler -> 200 if every critical dependency is up, 503 otherwise
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Report(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": report.Status})
+	})
+}
+
+// DetailsHandler -> the status of every dependency, answered with the readiness status code
+func (c *Checker) DetailsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Report(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
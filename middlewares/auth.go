@@ -13,52 +13,103 @@ type contextKey string
 
 const CustomerKey contextKey = "customerDto"
 
-func AuthMiddleware(next http.Handler, authClient authPb.AuthServiceClient) http.Handler {
+// ScopesKey -> scopes granted to the access token of the current request
+const ScopesKey contextKey = "tokenScopes"
+
+// SessionKey -> session id of the access token of the current request
+const SessionKey contextKey = "sessionId"
+
+// TokenGrant -> what the access token grants besides the customer identity
+type TokenGrant struct {
+	Scopes    []string
+	SessionId string
+}
+
+// TokenValidator -> resolve an access token to the customer and its grant
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (*authPb.CustomerDto, TokenGrant, error)
+}
+
+// AuthMiddleware -> enforce the access policy declared for the matched route
+//
+// the route is resolved by the same mux that serves the request,
+// so the policy is looked up by the registered pattern instead of the raw url
+func AuthMiddleware(mux *http.ServeMux, policies *Policies, validator TokenValidator) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		// authless routes
-		if strings.Contains(r.URL.Path, "/auth/") || strings.Contains(r.URL.Path, "/health") {
-			next.ServeHTTP(w, r)
+		_, pattern := mux.Handler(r)
+		entry := accessEntryOf(r)
+		entry.pattern = pattern
+		policy, ok := policies.Lookup(pattern)
+		if !ok {
+			// not found, method not allowed and trailing slash redirects are answered by the mux itself
+			if pattern == "" || pattern == r.URL.Path+"/" {
+				mux.ServeHTTP(w, r)
+				return
+			}
+			denyRequest(w, http.StatusForbidden, "Forbidden")
 			return
 		}
 
-		token := r.Header.Get("Authorization")
-		if token == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if policy.IsPublic() {
+			mux.ServeHTTP(w, r)
 			return
 		}
 
-		t := strings.TrimSuffix(strings.Split(token, "Bearer ")[1], `"`)
-
-		customer, err := authClient.ValidateToken(r.Context(), &authPb.ValidateTokenRequest{
-			Token: t,
-		})
+		token, ok := BearerToken(r)
+		if !ok && policy.queryToken {
+			token, ok = QueryToken(r)
+		}
+		if !ok {
+			denyRequest(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
 
+		customer, grant, err := validator.ValidateToken(r.Context(), token)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": err.Error(),
-			})
+			denyRequest(w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		// admin routes guard
-		if strings.Contains(r.URL.Path, "/admin/") {
-			if customer.Role != "ADMIN" && customer.Role != "SUPERVISOR" {
-				w.WriteHeader(http.StatusForbidden)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error": "Forbidden",
-				})
-				return
-			}
-			next.ServeHTTP(w, r)
+		if !policy.allowsRole(customer.Role) || !policy.allowsScopes(grant.Scopes) || !policies.allowsPermissions(r, customer, policy) {
+			denyRequest(w, http.StatusForbidden, "Forbidden")
 			return
 		}
 
-		// Use custom key type instead of string
+		entry.customerId = customer.CustomerId
+
 		ctx := context.WithValue(r.Context(), CustomerKey, customer)
+		ctx = context.WithValue(ctx, ScopesKey, grant.Scopes)
+		ctx = context.WithValue(ctx, SessionKey, grant.SessionId)
 		r = r.WithContext(ctx)
 
-		next.ServeHTTP(w, r)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// BearerToken -> get a token from the Authorization header
+func BearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSuffix(strings.TrimSpace(token), `"`)
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// QueryToken -> get a token from the "token" query parameter
+func QueryToken(r *http.Request) (string, bool) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func denyRequest(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": msg,
 	})
 }
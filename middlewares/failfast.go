@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientFailFast -> fail the call at once while its connection is in a transient failure,
+// so the retries don't keep the request waiting on a backend that is down
+func UnaryClientFailFast(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if cc != nil && cc.GetState() == connectivity.TransientFailure {
+		return status.Errorf(codes.Unavailable, "%s: backend service is unavailable", method)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
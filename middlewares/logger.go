@@ -1,31 +1,140 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	utils "github.com/noo8xl/anvil-gateway/utils/loggers"
+	"github.com/noo8xl/anvil-gateway/config"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
-// Logger is a middleware that logs the request
-func Logger(next http.Handler) http.Handler {
+// accessEntryKey -> the access log entry of the current request
+const accessEntryKey contextKey = "accessEntry"
+
+// redactedValue -> replaces the values of the redacted query parameters
+const redactedValue = "[REDACTED]"
+
+// accessEntry -> what's known about the request only to the inner middlewares,
+// filled by AuthMiddleware once the route and the customer are resolved
+type accessEntry struct {
+	pattern    string
+	customerId uint64
+}
+
+// accessEntryOf -> the access log entry of the request or a detached one
+// if the request is not logged
+func accessEntryOf(r *http.Request) *accessEntry {
+	if entry, ok := r.Context().Value(accessEntryKey).(*accessEntry); ok {
+		return entry
+	}
+	return &accessEntry{}
+}
+
+// AccessLogger -> a structured access log of every request
+//
+// the request body and headers are never logged,
+// the configured query parameters are redacted
+type AccessLogger struct {
+	logger   *zap.Logger
+	redact   map[string]bool
+	sampling map[string]uint64
+	counters sync.Map
+}
+
+func InitAccessLogger(logger *zap.Logger, cfg config.AccessLogConfig) *AccessLogger {
+	redact := make(map[string]bool, len(cfg.Redact))
+	for _, param := range cfg.Redact {
+		redact[strings.ToLower(param)] = true
+	}
+
+	return &AccessLogger{
+		logger:   logger,
+		redact:   redact,
+		sampling: cfg.Sampling,
+	}
+}
+
+// Handler -> log the request once it's served
+func (l *AccessLogger) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessEntry{}
+		rw := &responseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessEntryKey, entry)))
 
-		if strings.Contains(r.URL.Path, "/auth/") {
-			next.ServeHTTP(w, r)
+		status := rw.statusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if !l.sampled(entry.pattern, status) {
 			return
 		}
 
-		loggerDto := utils.LoggerDto{
-			Ip:        r.RemoteAddr,
-			Method:    r.Method,
-			Url:       r.URL.Path,
-			UserAgent: r.UserAgent(),
-			Referer:   r.Referer(),
-			Host:      r.Host,
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("query", l.redactQuery(r.URL.RawQuery)),
+			zap.String("route", entry.pattern),
+			zap.Int("status", status),
+			zap.Int64("bytes", rw.bytes),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("ip", r.RemoteAddr),
+			zap.String("userAgent", r.UserAgent()),
+			zap.String("requestId", GetRequestId(r.Context())),
+			zap.String("traceId", traceIdOf(r)),
+			zap.Uint64("customerId", entry.customerId),
 		}
 
-		utils.RequestLoggerUtil(loggerDto)
-		next.ServeHTTP(w, r)
+		switch {
+		case status >= http.StatusInternalServerError:
+			l.logger.Error("request", fields...)
+		case status >= http.StatusBadRequest:
+			l.logger.Warn("request", fields...)
+		default:
+			l.logger.Info("request", fields...)
+		}
 	})
 }
+
+// sampled -> keep 1 of every N successful requests of a sampled route,
+// the failed ones are always logged
+func (l *AccessLogger) sampled(pattern string, status int) bool {
+	every, ok := l.sampling[pattern]
+	if !ok || every <= 1 || status >= http.StatusBadRequest {
+		return true
+	}
+
+	counter, _ := l.counters.LoadOrStore(pattern, new(atomic.Uint64))
+	return counter.(*atomic.Uint64).Add(1)%every == 1
+}
+
+// traceIdOf -> the id of the trace the request belongs to, empty if it's not traced
+func traceIdOf(r *http.Request) string {
+	if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
+// redactQuery -> the raw query with the values of the sensitive parameters replaced
+func (l *AccessLogger) redactQuery(rawQuery string) string {
+	if rawQuery == "" || len(l.redact) == 0 {
+		return rawQuery
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && l.redact[strings.ToLower(name)] {
+			params[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
@@ -1,57 +1,166 @@
 package middlewares
 
 import (
-	"fmt"
+	"context"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/noo8xl/anvil-gateway/metrics"
 	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
-func MetricsMiddleware(next http.Handler) http.Handler {
+// unmatchedRoute -> the route label of the requests not matched by any pattern,
+// so scanners probing random urls don't create a series per url
+const unmatchedRoute = "unmatched"
 
-	registry := prometheus.NewRegistry()
+// MetricsMiddleware -> count, time and size every request by its route pattern
+//
+// the route is resolved by the same mux that serves the request,
+// it's what r.Pattern holds once the mux dispatches the request
+func MetricsMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
 
-	httpRequestsTotal := prometheus.NewCounterVec(
+	httpRequestsTotal := metrics.Register(prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
 			Help: "Total number of HTTP requests",
 		},
-		[]string{"method", "endpoint", "status"},
-	)
-	httpRequestDuration := prometheus.NewHistogramVec(
+		[]string{"method", "route", "status"},
+	))
+	httpRequestDuration := metrics.Register(prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name: "http_request_duration_seconds",
-			Help: "HTTP request duration in seconds",
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds",
+			Buckets: prometheus.DefBuckets,
 		},
-		[]string{"method", "endpoint"},
-	)
-
-	registry.MustRegister(httpRequestsTotal, httpRequestDuration)
+		[]string{"method", "route"},
+	))
+	httpResponseSize := metrics.Register(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body size in bytes",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"method", "route"},
+	))
+	httpRequestsInFlight := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests being served",
+		},
+		[]string{"route"},
+	))
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		route := unmatchedRoute
+		if _, pattern := mux.Handler(r); pattern != "" {
+			_, route = splitPattern(pattern)
+		}
+
+		inFlight := httpRequestsInFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
 		// Create a custom response writer to capture the status code
 		rw := &responseWriter{ResponseWriter: w}
 
 		next.ServeHTTP(rw, r)
 
-		duration := time.Since(start).Seconds()
+		// a handler that never writes responds with 200
+		status := rw.statusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
 
 		// Record metrics
-		httpRequestsTotal.WithLabelValues(r.Method, r.URL.Path, fmt.Sprintf("%d", rw.statusCode)).Inc()
-		httpRequestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(duration)
+		httpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		httpResponseSize.WithLabelValues(r.Method, route).Observe(float64(rw.bytes))
 	})
 }
 
-// Custom response writer to capture status code
+// UnaryClientMetrics -> count and time every backend call by its service and method,
+// the retries of a call are timed as a part of it
+func UnaryClientMetrics() grpc.UnaryClientInterceptor {
+
+	grpcRequestsTotal := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_client_requests_total",
+			Help: "Total number of gRPC calls to the backend services",
+		},
+		[]string{"service", "method", "code"},
+	))
+	grpcRequestDuration := metrics.Register(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_client_request_duration_seconds",
+			Help:    "gRPC call duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "method"},
+	))
+	grpcRequestsInFlight := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "grpc_client_requests_in_flight",
+			Help: "Number of gRPC calls waiting for the backend services",
+		},
+		[]string{"service"},
+	))
+
+	return func(ctx context.Context, fullMethod string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		service, method := splitMethod(fullMethod)
+
+		inFlight := grpcRequestsInFlight.WithLabelValues(service)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		err := invoker(ctx, fullMethod, req, reply, cc, opts...)
+
+		grpcRequestsTotal.WithLabelValues(service, method, status.Code(err).String()).Inc()
+		grpcRequestDuration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// splitMethod -> the service and the method of a full grpc method name like "/auth.AuthService/SignIn"
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", fullMethod
+	}
+	return service, method
+}
+
+// Custom response writer to capture status code and the body size
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if rw.statusCode == 0 {
+		rw.statusCode = code
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap -> the wrapped writer, so http.ResponseController reaches
+// the flusher and the hijacker of the connection
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
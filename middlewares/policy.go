@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+)
+
+// Policy -> an access policy declared by every route registration
+//
+// use Public, Authenticated, RequireRoles or RequirePermissions to declare one,
+// a zero Policy is treated as an undeclared one
+type Policy struct {
+	declared    bool
+	public      bool
+	roles       []string
+	permissions []string
+	scopes      []string
+	rateGroup   string
+	queryToken  bool
+}
+
+// Public -> the route is available without an access token
+func Public() Policy {
+	return Policy{declared: true, public: true}
+}
+
+// Authenticated -> the route requires a valid access token
+func Authenticated() Policy {
+	return Policy{declared: true}
+}
+
+// RequireRoles -> the route requires a valid access token
+// and the customer to have one of the given roles
+func RequireRoles(roles ...string) Policy {
+	return Policy{declared: true, roles: roles}
+}
+
+// RequirePermissions -> the route requires a valid access token
+// and the customer role to be granted every given permission
+func RequirePermissions(permissions ...string) Policy {
+	return Policy{declared: true, permissions: permissions}
+}
+
+// WithScopes -> additionally require every given token scope
+func (p Policy) WithScopes(scopes ...string) Policy {
+	p.scopes = append(slices.Clone(p.scopes), scopes...)
+	return p
+}
+
+// WithRateLimit -> count the route requests in the given rate limit group
+// instead of the default one
+func (p Policy) WithRateLimit(group string) Policy {
+	p.rateGroup = group
+	return p
+}
+
+// WithQueryToken -> accept the access token from the "token" query parameter
+// when the request has no Authorization header, the browsers can't set it
+// on an EventSource or a WebSocket
+func (p Policy) WithQueryToken() Policy {
+	p.queryToken = true
+	return p
+}
+
+// RateLimitGroup -> get the rate limit group of the route
+func (p Policy) RateLimitGroup() string {
+	if p.rateGroup == "" {
+		return RateGroupDefault
+	}
+	return p.rateGroup
+}
+
+// IsDeclared -> check if the policy was declared by one of the constructors
+func (p Policy) IsDeclared() bool {
+	return p.declared
+}
+
+// IsPublic -> check if the route is available without an access token
+func (p Policy) IsPublic() bool {
+	return p.public
+}
+
+// allowsRole -> check if the customer role satisfies the policy
+func (p Policy) allowsRole(role string) bool {
+	return len(p.roles) == 0 || slices.Contains(p.roles, role)
+}
+
+// allowsScopes -> check if the granted scopes satisfy the policy
+func (p Policy) allowsScopes(granted []string) bool {
+	for _, scope := range p.scopes {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// PermissionChecker -> resolve the customer role permissions
+type PermissionChecker interface {
+	HasPermissions(r *http.Request, customer *authPb.CustomerDto, permissions ...string) bool
+}
+
+// Policies -> a table of route policies by the http.ServeMux pattern
+type Policies struct {
+	table       map[string]Policy
+	permissions PermissionChecker
+}
+
+func InitPolicies() *Policies {
+	return &Policies{
+		table: make(map[string]Policy),
+	}
+}
+
+// Declare -> set a policy of the route by its http.ServeMux pattern
+func (p *Policies) Declare(pattern string, policy Policy) {
+	p.table[pattern] = policy
+}
+
+// UsePermissions -> set the checker of the RequirePermissions policies,
+// without it such routes are denied
+func (p *Policies) UsePermissions(checker PermissionChecker) {
+	p.permissions = checker
+}
+
+// allowsPermissions -> check if the customer satisfies the policy permissions
+func (p *Policies) allowsPermissions(r *http.Request, customer *authPb.CustomerDto, policy Policy) bool {
+	if len(policy.permissions) == 0 {
+		return true
+	}
+	if p.permissions == nil {
+		return false
+	}
+	return p.permissions.HasPermissions(r, customer, policy.permissions...)
+}
+
+// Lookup -> get a declared policy of the route by its http.ServeMux pattern
+func (p *Policies) Lookup(pattern string) (Policy, bool) {
+	policy, ok := p.table[pattern]
+	if !ok || !policy.declared {
+		return Policy{}, false
+	}
+	return policy, true
+}
+
+// Validate -> make sure every registered route has a declared policy
+func (p *Policies) Validate() error {
+	var undeclared []string
+	for pattern, policy := range p.table {
+		if !policy.declared {
+			undeclared = append(undeclared, pattern)
+		}
+	}
+
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return fmt.Errorf("routes without a declared access policy: %s", strings.Join(undeclared, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+)
+
+// rate limit groups, their quotas are set by config.RateLimitConfig
+const (
+	RateGroupDefault = "default"
+	RateGroupAuth    = "auth"
+	RateGroupEmail   = "email"
+)
+
+// maxMemoryWindows -> upper bound of the keys kept by the memory store
+const maxMemoryWindows = 100000
+
+// RateLimiter -> sliding window request quotas per route group,
+// counted per customer for authenticated requests and per ip otherwise
+type RateLimiter struct {
+	enabled bool
+	groups  map[string]config.RateLimit
+	redis   *cache.CacheService
+	memory  *memoryWindows
+}
+
+// InitRateLimiter -> create a limiter, a nil store keeps the quotas in memory
+func InitRateLimiter(cfg config.RateLimitConfig, store *cache.CacheService) *RateLimiter {
+	if cfg.Store == "memory" {
+		store = nil
+	}
+	return &RateLimiter{
+		enabled: cfg.Enabled,
+		groups:  cfg.Groups,
+		redis:   store,
+		memory:  &memoryWindows{windows: make(map[string][]time.Time)},
+	}
+}
+
+// Limit -> apply the quota of the group to the handler
+//
+// the RateLimit-* headers are set on every response,
+// a rejected request gets 429 with the Retry-After header
+func (l *RateLimiter) Limit(group string, next http.Handler) http.Handler {
+	limit, ok := l.groups[group]
+	if !ok {
+		limit, ok = l.groups[RateGroupDefault]
+	}
+	if !l.enabled || !ok {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := group + ":" + rateLimitKey(r)
+
+		allowed, count, oldest := l.take(r.Context(), key, limit)
+		reset := max(time.Until(oldest.Add(limit.Window)), 0)
+
+		w.Header().Set("RateLimit-Limit", strconv.FormatInt(limit.Requests, 10))
+		w.Header().Set("RateLimit-Remaining", strconv.FormatInt(max(limit.Requests-count, 0), 10))
+		w.Header().Set("RateLimit-Reset", seconds(reset))
+
+		if !allowed {
+			w.Header().Set("Retry-After", seconds(reset))
+			denyRequest(w, http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take -> count the request in redis, or in memory if redis is not used or unavailable
+func (l *RateLimiter) take(ctx context.Context, key string, limit config.RateLimit) (bool, int64, time.Time) {
+	if l.redis != nil {
+		allowed, count, oldest, err := l.redis.TakeRateLimit(ctx, key, limit)
+		if err == nil {
+			return allowed, count, oldest
+		}
+	}
+	return l.memory.take(key, limit, time.Now())
+}
+
+// rateLimitKey -> the customer id set by the AuthMiddleware or the client ip
+func rateLimitKey(r *http.Request) string {
+	if customer, ok := r.Context().Value(CustomerKey).(*authPb.CustomerDto); ok && customer != nil {
+		return fmt.Sprintf("customer:%d", customer.CustomerId)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// memoryWindows -> a single instance sliding window store
+type memoryWindows struct {
+	mu      sync.Mutex
+	windows map[string][]time.Time
+}
+
+func (m *memoryWindows) take(key string, limit config.RateLimit, now time.Time) (bool, int64, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.windows) >= maxMemoryWindows {
+		m.prune(now, limit.Window)
+	}
+
+	window := m.windows[key]
+	start := now.Add(-limit.Window)
+	for len(window) > 0 && !window[0].After(start) {
+		window = window[1:]
+	}
+
+	allowed := int64(len(window)) < limit.Requests
+	if allowed {
+		window = append(window, now)
+	}
+	m.windows[key] = window
+
+	return allowed, int64(len(window)), window[0]
+}
+
+// prune -> drop the keys without requests in the window
+func (m *memoryWindows) prune(now time.Time, window time.Duration) {
+	for key, requests := range m.windows {
+		if len(requests) == 0 || !requests[len(requests)-1].After(now.Add(-window)) {
+			delete(m.windows, key)
+		}
+	}
+}
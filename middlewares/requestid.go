@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIdKey -> id of the current request
+const RequestIdKey contextKey = "requestId"
+
+// RequestIdHeader -> the header carrying the request id in both directions
+const RequestIdHeader = "X-Request-Id"
+
+// RequestIdMetadataKey -> the grpc metadata carrying the request id to the services
+const RequestIdMetadataKey = "x-request-id"
+
+// maxRequestIdLength -> longer incoming ids are replaced by a generated one
+const maxRequestIdLength = 64
+
+// RequestId -> tag every request with an id, a sane id set by the client
+// or an upstream proxy is kept so the request can be traced across services
+//
+// the id is returned by the X-Request-Id header of every response
+// and added to the json error bodies as "requestId"
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIdHeader)
+		if !isValidRequestId(id) {
+			id = newRequestId()
+		}
+
+		w.Header().Set(RequestIdHeader, id)
+		rw := &requestIdWriter{ResponseWriter: w, id: id}
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), RequestIdKey, id)))
+		rw.flushError()
+	})
+}
+
+// requestIdWriter -> buffer an error response to put the request id to its body
+type requestIdWriter struct {
+	http.ResponseWriter
+	id     string
+	status int
+	body   *bytes.Buffer
+}
+
+func (w *requestIdWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	if code >= http.StatusBadRequest {
+		w.body = &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *requestIdWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.body != nil {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *requestIdWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushError -> write the buffered error response,
+// a json object body gets the "requestId" field
+func (w *requestIdWriter) flushError() {
+	if w.body == nil {
+		return
+	}
+
+	body := w.body.Bytes()
+	var object map[string]any
+	if err := json.Unmarshal(body, &object); err == nil && object != nil {
+		object["requestId"] = w.id
+		if encoded, err := json.Marshal(object); err == nil {
+			body = append(encoded, '\n')
+			w.Header().Del("Content-Length")
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// GetRequestId -> the id of the request the context belongs to or ""
+func GetRequestId(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIdKey).(string)
+	return id
+}
+
+// UnaryClientRequestId -> a grpc client interceptor forwarding the request id
+// of the call context to the service as outgoing metadata
+func UnaryClientRequestId(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id := GetRequestId(ctx); id != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIdMetadataKey, id)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// newRequestId -> a random 128 bit hex id
+func newRequestId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isValidRequestId -> only short ids of letters, digits, "-", "_" and "." are accepted,
+// anything else could be used to forge log lines
+func isValidRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
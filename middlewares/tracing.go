@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// RequestIdAttribute -> the span attribute holding the request id
+const RequestIdAttribute = attribute.Key("http.request.id")
+
+// Tracing -> a server span of every request, continuing the incoming trace context
+//
+// the span is named by the route pattern matched by the mux, e.g. "GET /api/v1/orders/{orderId}/",
+// so the requests of one route are grouped regardless of the path values
+func Tracing(mux *http.ServeMux, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			span.SetAttributes(RequestIdAttribute.String(GetRequestId(r.Context())))
+			if _, pattern := mux.Handler(r); pattern != "" {
+				_, route := splitPattern(pattern)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			next.ServeHTTP(w, r)
+		}),
+		"http.request",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			_, pattern := mux.Handler(r)
+			if pattern == "" {
+				return r.Method
+			}
+			if method, route := splitPattern(pattern); method == "" {
+				return r.Method + " " + route
+			}
+			return pattern
+		}),
+	)
+}
+
+// ClientTracing -> the grpc stats handler creating a client span of every backend call
+// and passing the trace context in the call metadata
+func ClientTracing() stats.Handler {
+	return otelgrpc.NewClientHandler()
+}
+
+// splitPattern -> the method and the path of a mux pattern, the method may be empty
+func splitPattern(pattern string) (string, string) {
+	if method, route, ok := strings.Cut(pattern, " "); ok {
+		return method, route
+	}
+	return "", pattern
+}
@@ -0,0 +1,35 @@
+package otp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateRecoveryCodes -> one-time codes to sign in without the authenticator app,
+// formatted as "xxxxx-xxxxx"
+func GenerateRecoveryCodes(n int) []string {
+	codes := make([]string, 0, n)
+	for range n {
+		code := strings.ToLower(rand.Text()[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes
+}
+
+// HashRecoveryCode -> a stored form of the recovery code,
+// the code is normalized so a typed one matches regardless of case and dashes
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsRecoveryCode -> check if the code looks like a recovery code rather than a TOTP one
+func IsRecoveryCode(code string) bool {
+	return len(normalizeRecoveryCode(code)) == 10
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
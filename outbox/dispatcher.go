@@ -0,0 +1,380 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/preferences"
+	"github.com/noo8xl/anvil-gateway/realtime"
+	"github.com/noo8xl/anvil-gateway/shutdown"
+	"github.com/noo8xl/anvil-gateway/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelCodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readBatch -> number of the messages a worker takes at once
+const readBatch = 10
+
+// promoteBatch -> number of the due retries moved back to the queue at once
+const promoteBatch = 100
+
+// Publisher -> push a delivered notification to the customer's open streams
+type Publisher interface {
+	Publish(ctx context.Context, customerId uint64, event realtime.Event) error
+}
+
+// Preferences -> the channels the customer gets the notifications of an area by
+type Preferences interface {
+	Allows(ctx context.Context, customerId uint64, area, channel string) (bool, error)
+}
+
+// Dispatcher -> a pool of workers delivering the outbox messages
+// to the notifications service
+//
+// every message is delivered at least once: a message is removed from the queue
+// only after the delivery, a failed one is retried with an exponential backoff
+// and dead-lettered after the last attempt or on an error a retry can't fix
+type Dispatcher struct {
+	cfg         config.OutboxConfig
+	store       *cache.CacheService
+	client      notificationsPb.NotificationsServiceClient
+	logger      *zap.Logger
+	metrics     *dispatcherMetrics
+	consumer    string
+	publisher   Publisher
+	preferences Preferences
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func InitDispatcher(
+	cfg config.OutboxConfig,
+	store *cache.CacheService,
+	client notificationsPb.NotificationsServiceClient,
+	logger *zap.Logger,
+) *Dispatcher {
+	hostname, _ := os.Hostname()
+
+	return &Dispatcher{
+		cfg:      cfg,
+		store:    store,
+		client:   client,
+		logger:   logger,
+		metrics:  initDispatcherMetrics(),
+		consumer: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// UsePublisher -> push every delivered notification to the customer's streams,
+// without it the customers find the new notifications by the list only
+func (d *Dispatcher) UsePublisher(publisher Publisher) {
+	d.publisher = publisher
+}
+
+// UsePreferences -> skip the notifications and the emails the customer has muted,
+// without it every message is delivered
+func (d *Dispatcher) UsePreferences(preferences Preferences) {
+	d.preferences = preferences
+}
+
+// Start -> start the workers and the scheduler of the retries,
+// they run until Stop
+func (d *Dispatcher) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := d.store.CreateOutboxGroup(ctx); err != nil {
+		cancel()
+		return err
+	}
+	d.cancel = cancel
+
+	for range d.cfg.Workers {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.work(ctx)
+		}()
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.schedule(ctx)
+	}()
+	return nil
+}
+
+// Stop -> stop taking new messages and wait for the deliveries in progress until ctx is done,
+// the messages left in the queue are delivered by the next start
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work -> take the new messages and deliver them one by one
+func (d *Dispatcher) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		entries, err := d.store.ReadOutboxMessages(ctx, d.consumer, readBatch, d.cfg.PollInterval)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Error("failed to read the outbox", zap.Error(err))
+				shutdown.Delay(ctx, d.cfg.PollInterval)
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			d.process(entry)
+		}
+	}
+}
+
+// schedule -> move the due retries back to the queue, take over the messages
+// abandoned by other workers and sample the outbox depth once per poll interval
+func (d *Dispatcher) schedule(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := d.store.PromoteOutboxRetries(ctx, time.Now(), promoteBatch); err != nil && ctx.Err() == nil {
+			d.logger.Error("failed to move the outbox retries to the queue", zap.Error(err))
+		}
+
+		entries, err := d.store.ClaimOutboxMessages(ctx, d.consumer, d.cfg.ClaimIdle, readBatch)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Error("failed to claim the abandoned outbox messages", zap.Error(err))
+		}
+		for _, entry := range entries {
+			d.process(entry)
+		}
+
+		depth, err := d.store.GetOutboxDepth(ctx)
+		if err == nil {
+			d.metrics.observeDepth(depth)
+		}
+	}
+}
+
+// process -> deliver a message and acknowledge, retry or dead-letter it,
+// the message is finished even if the dispatcher is being stopped
+func (d *Dispatcher) process(entry cache.OutboxEntry) {
+	ctx := context.Background()
+
+	var message Message
+	if err := json.Unmarshal(entry.Message, &message); err != nil || message.Kind == "" {
+		// the original body of a malformed message is kept as is
+		d.deadLetter(ctx, entry.Id, entry.Message, message, "malformed message")
+		return
+	}
+
+	if !d.allows(ctx, message, channelOf(message)) {
+		d.metrics.deliveries.WithLabelValues(message.Kind, resultMuted).Inc()
+		if err := d.store.AckOutboxMessage(ctx, entry.Id); err != nil {
+			d.logger.Error("failed to acknowledge an outbox message", zap.String("id", message.Id), zap.Error(err))
+		}
+		return
+	}
+
+	message.Attempt++
+	err := d.deliver(ctx, message)
+	switch {
+	case err == nil:
+		d.metrics.deliveries.WithLabelValues(message.Kind, resultDelivered).Inc()
+		if err = d.store.AckOutboxMessage(ctx, entry.Id); err != nil {
+			// the message is delivered again once claimed, so it's counted
+			// and pushed by the delivery that's acknowledged
+			d.logger.Error("failed to acknowledge an outbox message", zap.String("id", message.Id), zap.Error(err))
+			return
+		}
+		d.countUnread(ctx, message)
+		d.publish(ctx, message)
+
+	case !isRetryable(err) || message.Attempt >= d.cfg.MaxAttempts:
+		message.LastError = err.Error()
+		bytes, _ := json.Marshal(message)
+		d.deadLetter(ctx, entry.Id, bytes, message, err.Error())
+
+	default:
+		message.LastError = err.Error()
+		d.retry(ctx, entry.Id, message)
+	}
+}
+
+// deliver -> make a single delivery attempt within the delivery timeout
+func (d *Dispatcher) deliver(ctx context.Context, message Message) error {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(message.Trace))
+	if message.RequestId != "" {
+		ctx = context.WithValue(ctx, middlewares.RequestIdKey, message.RequestId)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "outbox.deliver",
+		trace.WithAttributes(
+			attribute.String("outbox.message.kind", message.Kind),
+			attribute.Int64("outbox.message.attempt", message.Attempt),
+		))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.DeliveryTimeout)
+	defer cancel()
+
+	var err error
+	switch message.Kind {
+	case KindNotification:
+		_, err = d.client.CreateNotification(ctx, &notificationsPb.CreateNotificationRequest{
+			CustomerId: message.CustomerId,
+			Title:      message.Title,
+			Body:       message.Body,
+			Area:       message.Area,
+			CreatedAt:  message.CreatedAt.Format(time.RFC3339),
+		})
+	case KindEmail:
+		_, err = d.client.SendEmail(ctx, &notificationsPb.SendEmailRequest{
+			Email:   message.Email,
+			Subject: message.Title,
+			Body:    message.Body,
+		})
+	default:
+		err = status.Errorf(codes.InvalidArgument, "unknown message kind %q", message.Kind)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelCodes.Error, err.Error())
+	}
+	return err
+}
+
+// countUnread -> count a delivered notification as unread
+func (d *Dispatcher) countUnread(ctx context.Context, message Message) {
+	if message.Kind != KindNotification {
+		return
+	}
+	if err := d.store.CountNewNotification(ctx, message.CustomerId); err != nil {
+		d.logger.Error("failed to count an unread notification", zap.String("id", message.Id), zap.Error(err))
+	}
+}
+
+// publish -> push a delivered notification to the customer's streams,
+// a customer missing it still finds it in the list
+func (d *Dispatcher) publish(ctx context.Context, message Message) {
+	if d.publisher == nil || message.Kind != KindNotification || !d.allows(ctx, message, preferences.ChannelPush) {
+		return
+	}
+
+	err := d.publisher.Publish(ctx, message.CustomerId, realtime.Event{
+		Id:        message.Id,
+		Title:     message.Title,
+		Body:      message.Body,
+		Area:      message.Area,
+		CreatedAt: message.CreatedAt.Format(time.RFC3339),
+	})
+	if err != nil {
+		d.logger.Error("failed to publish a notification", zap.String("id", message.Id), zap.Error(err))
+	}
+}
+
+// allows -> whether the customer gets the message by the channel,
+// a message of no customer is always sent and so is one whose preferences can't be read
+func (d *Dispatcher) allows(ctx context.Context, message Message, channel string) bool {
+	if d.preferences == nil || message.CustomerId == 0 {
+		return true
+	}
+
+	allowed, err := d.preferences.Allows(ctx, message.CustomerId, message.Area, channel)
+	if err != nil {
+		d.logger.Warn("failed to read the notification preferences", zap.Uint64("customerId", message.CustomerId), zap.Error(err))
+		return true
+	}
+	return allowed
+}
+
+// channelOf -> the channel the message is delivered by
+func channelOf(message Message) string {
+	if message.Kind == KindEmail {
+		return preferences.ChannelEmail
+	}
+	return preferences.ChannelInApp
+}
+
+// retry -> schedule the next attempt of the message after the backoff
+func (d *Dispatcher) retry(ctx context.Context, id string, message Message) {
+	d.metrics.deliveries.WithLabelValues(message.Kind, resultRetried).Inc()
+
+	bytes, err := json.Marshal(message)
+	if err == nil {
+		err = d.store.RetryOutboxMessage(ctx, id, bytes, time.Now().Add(d.backoff(message.Attempt)))
+	}
+	if err != nil {
+		d.logger.Error("failed to schedule an outbox retry", zap.String("id", message.Id), zap.Error(err))
+	}
+}
+
+// deadLetter -> give up on the message
+func (d *Dispatcher) deadLetter(ctx context.Context, id string, body []byte, message Message, reason string) {
+	d.metrics.deliveries.WithLabelValues(message.Kind, resultDead).Inc()
+	d.logger.Warn("an outbox message is dead-lettered",
+		zap.String("id", message.Id),
+		zap.String("kind", message.Kind),
+		zap.Int64("attempt", message.Attempt),
+		zap.String("requestId", message.RequestId),
+		zap.String("reason", reason))
+
+	if err := d.store.DeadLetterOutboxMessage(ctx, id, body, reason); err != nil {
+		d.logger.Error("failed to dead-letter an outbox message", zap.String("id", message.Id), zap.Error(err))
+	}
+}
+
+// backoff -> the delay before the next attempt, doubled after every attempt
+// up to the max backoff
+func (d *Dispatcher) backoff(attempt int64) time.Duration {
+	delay := d.cfg.RetryBackoff
+	for i := int64(1); i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
+
+// isRetryable -> whether another attempt may succeed, a rejected request fails the same way again
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// delivery results
+const (
+	resultDelivered = "delivered"
+	resultRetried   = "retried"
+	resultDead      = "dead"
+	resultMuted     = "muted"
+)
+
+// dispatcherMetrics -> the delivery results and the depth of the outbox,
+// a growing queue or any dead letter is the alerting signal
+type dispatcherMetrics struct {
+	deliveries *prometheus.CounterVec
+	depth      *prometheus.GaugeVec
+}
+
+func initDispatcherMetrics() *dispatcherMetrics {
+	deliveries := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_deliveries_total",
+			Help: "Total number of outbox delivery attempts by message kind and result",
+		},
+		[]string{"kind", "result"},
+	))
+	depth := metrics.Register(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "outbox_queue_depth",
+			Help: "Number of outbox messages queued, waiting for a retry and dead-lettered",
+		},
+		[]string{"queue"},
+	))
+
+	return &dispatcherMetrics{deliveries: deliveries, depth: depth}
+}
+
+func (m *dispatcherMetrics) observeDepth(depth cache.OutboxDepth) {
+	m.depth.WithLabelValues("pending").Set(float64(depth.Pending))
+	m.depth.WithLabelValues("retry").Set(float64(depth.Retrying))
+	m.depth.WithLabelValues("dead").Set(float64(depth.DeadLetters))
+}
@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// message kinds
+const (
+	KindNotification = "notification"
+	KindEmail        = "email"
+)
+
+// Message -> a notification side effect waiting for a delivery
+// to the notifications service
+//
+//   - Trace -> the trace context of the request it was written by,
+//     the delivery span is a part of the same trace
+//   - Attempt -> number of the delivery attempts made so far
+type Message struct {
+	Id         string            `json:"id"`
+	Kind       string            `json:"kind"`
+	CustomerId uint64            `json:"customerId,omitempty"`
+	Email      string            `json:"email,omitempty"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Area       string            `json:"area,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	RequestId  string            `json:"requestId,omitempty"`
+	Trace      map[string]string `json:"trace,omitempty"`
+	Attempt    int64             `json:"attempt"`
+	LastError  string            `json:"lastError,omitempty"`
+}
+
+// Notification -> an in-app notification of the customer
+func Notification(customerId uint64, title, body, area string) Message {
+	return Message{
+		Kind:       KindNotification,
+		CustomerId: customerId,
+		Title:      title,
+		Body:       body,
+		Area:       area,
+	}
+}
+
+// Email -> an email to the address
+func Email(email, subject, body string) Message {
+	return Message{
+		Kind:  KindEmail,
+		Email: email,
+		Title: subject,
+		Body:  body,
+	}
+}
+
+// CustomerEmail -> an email to the customer about a notification of the area,
+// it's sent only if the customer gets the emails of the area
+func CustomerEmail(customerId uint64, email, subject, body, area string) Message {
+	message := Email(email, subject, body)
+	message.CustomerId = customerId
+	message.Area = area
+	return message
+}
+
+// Outbox -> write the notification side effects of a request to a durable queue,
+// they are delivered by the Dispatcher once the request is done
+type Outbox struct {
+	store *cache.CacheService
+}
+
+func InitOutbox(store *cache.CacheService) *Outbox {
+	return &Outbox{store: store}
+}
+
+// Enqueue -> queue the messages at once, either all of them are queued or none
+func (o *Outbox) Enqueue(ctx context.Context, messages ...Message) error {
+	now := time.Now().UTC()
+	requestId := middlewares.GetRequestId(ctx)
+
+	encoded := make([][]byte, 0, len(messages))
+	for _, message := range messages {
+		message.Id = rand.Text()
+		message.CreatedAt = now
+		message.RequestId = requestId
+		message.Trace = map[string]string{}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(message.Trace))
+
+		bytes, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, bytes)
+	}
+
+	return o.store.AppendOutboxMessages(ctx, encoded...)
+}
@@ -0,0 +1,358 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	blogPb "github.com/noo8xl/anvil-api/main/blog"
+	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
+	offersPb "github.com/noo8xl/anvil-api/main/offers"
+	ordersPb "github.com/noo8xl/anvil-api/main/orders"
+	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
+)
+
+// resource kinds, used as the cache key prefix
+const (
+	Offer        = "offer"
+	Order        = "order"
+	Review       = "review"
+	Post         = "post"
+	Notification = "notification"
+)
+
+var (
+	ErrForbidden = errors.New("forbidden: you don't have access to this resource")
+	ErrNotFound  = errors.New("resource not found")
+)
+
+// maxLookupPages -> upper bound of the list pages scanned
+// for the resources which have no single item getter
+const maxLookupPages = 20
+
+// maxEntries -> upper bound of the cached resources
+const maxEntries = 10000
+
+// Resource -> the owner and the participants of a resource
+type Resource struct {
+	Owner        uint64
+	Participants []uint64
+}
+
+// IsOwner -> check if the customer owns the resource
+func (r *Resource) IsOwner(customerId uint64) bool {
+	return customerId != 0 && r.Owner == customerId
+}
+
+// IsParticipant -> check if the customer owns or participates in the resource
+func (r *Resource) IsParticipant(customerId uint64) bool {
+	return r.IsOwner(customerId) || (customerId != 0 && slices.Contains(r.Participants, customerId))
+}
+
+// RequireOwner -> ErrForbidden unless the customer owns the resource
+func (r *Resource) RequireOwner(customerId uint64) error {
+	if !r.IsOwner(customerId) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// RequireParticipant -> ErrForbidden unless the customer owns or participates in the resource
+func (r *Resource) RequireParticipant(customerId uint64) error {
+	if !r.IsParticipant(customerId) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Guard -> resolve the owners of the resources by the services
+// and keep them for a short time, so the repeated checks are cheap
+type Guard struct {
+	offers        offersPb.OffersServiceClient
+	orders        ordersPb.OrdersServiceClient
+	reviews       reviewsPb.ReviewsServiceClient
+	blog          blogPb.BlogServiceClient
+	notifications notificationsPb.NotificationsServiceClient
+
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	resource *Resource
+	// reviews -> the reviews of a customer by their ids, see Review
+	reviews map[uint64]*Resource
+	expires time.Time
+}
+
+// InitGuard -> create a guard, a zero ttl disables the cache
+func InitGuard(
+	offers offersPb.OffersServiceClient,
+	orders ordersPb.OrdersServiceClient,
+	reviews reviewsPb.ReviewsServiceClient,
+	blog blogPb.BlogServiceClient,
+	notifications notificationsPb.NotificationsServiceClient,
+	ttl time.Duration,
+) *Guard {
+	return &Guard{
+		offers:        offers,
+		orders:        orders,
+		reviews:       reviews,
+		blog:          blog,
+		notifications: notifications,
+		ttl:           ttl,
+		entries:       make(map[string]entry),
+	}
+}
+
+// Offer -> owned by the poster, the applicants and the approved one participate
+func (g *Guard) Offer(ctx context.Context, offerId uint64) (*Resource, error) {
+	return g.resolve(offerKey(offerId), func() (*Resource, error) {
+		offer, err := g.offers.GetOfferDetails(ctx, &offersPb.GetOfferDetailsRequest{OfferId: offerId})
+		if err != nil {
+			return nil, err
+		}
+
+		participants := slices.Clone(offer.AppliedBy)
+		if offer.ApprovedFor != 0 && !slices.Contains(participants, offer.ApprovedFor) {
+			participants = append(participants, offer.ApprovedFor)
+		}
+		return &Resource{Owner: offer.PostedBy, Participants: participants}, nil
+	})
+}
+
+// Order -> owned by the customer, the applicant participates
+func (g *Guard) Order(ctx context.Context, orderId uint64) (*Resource, error) {
+	return g.resolve(orderKey(orderId), func() (*Resource, error) {
+		order, err := g.orders.GetOrderDetails(ctx, &ordersPb.GetOrderDetailsRequest{OrderId: orderId})
+		if err != nil {
+			return nil, err
+		}
+		if order.OrderBasics == nil {
+			return nil, ErrNotFound
+		}
+
+		resource := &Resource{Owner: order.OrderBasics.CustomerId}
+		if order.OrderBasics.ApplicantId != 0 {
+			resource.Participants = []uint64{order.OrderBasics.ApplicantId}
+		}
+		return resource, nil
+	})
+}
+
+// Review -> owned by the reviewer, the reviewed customer participates
+//
+// the reviews service has no single review getter and lists the reviews
+// only by the reviewed customer, so the list is scanned once and every review
+// of it is kept by a single entry of the customer, the lookups of any review id
+// reuse it until it expires instead of scanning the list again
+func (g *Guard) Review(ctx context.Context, reviewId, customerId uint64) (*Resource, error) {
+	reviews, err := g.reviewsOf(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	resource, ok := reviews[reviewId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return resource, nil
+}
+
+// reviewsOf -> the reviews of the reviewed customer by their ids
+func (g *Guard) reviewsOf(ctx context.Context, customerId uint64) (map[uint64]*Resource, error) {
+	key := reviewsKey(customerId)
+	if e, ok := g.cached(key, time.Now()); ok {
+		return e.reviews, nil
+	}
+
+	reviews := make(map[uint64]*Resource)
+	var skip uint32
+	for range maxLookupPages {
+		list, err := g.reviews.GetReviewsList(ctx, &reviewsPb.GetReviewsListRequest{CustomerId: customerId, Skip: skip})
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+		if len(list.List) == 0 {
+			break
+		}
+
+		for _, item := range list.List {
+			if item.Review != nil {
+				reviews[item.Review.ReviewId] = &Resource{Owner: item.Review.ReviewerId, Participants: []uint64{item.Review.CustomerId}}
+			}
+		}
+		skip += uint32(len(list.List))
+	}
+
+	g.keep(key, entry{reviews: reviews})
+	return reviews, nil
+}
+
+// Post -> owned by the customer whose blog has the post
+func (g *Guard) Post(ctx context.Context, postId, customerId uint64) (*Resource, error) {
+	return g.resolve(postKey(customerId, postId), func() (*Resource, error) {
+		var skip uint32
+		for range maxLookupPages {
+			blog, err := g.blog.GetBlog(ctx, &blogPb.GetBlogRequest{CustomerId: customerId, Skip: skip})
+			if err != nil {
+				return nil, err
+			}
+			if len(blog.Blog) == 0 {
+				break
+			}
+
+			for _, item := range blog.Blog {
+				if item.Id == postId {
+					return &Resource{Owner: customerId}, nil
+				}
+			}
+			skip += uint32(len(blog.Blog))
+		}
+		return nil, ErrNotFound
+	})
+}
+
+// Notification -> owned by the customer it was sent to
+func (g *Guard) Notification(ctx context.Context, notificationId, customerId uint64) (*Resource, error) {
+	return g.resolve(notificationKey(customerId, notificationId), func() (*Resource, error) {
+		var skip uint32
+		for range maxLookupPages {
+			list, err := g.notifications.GetNotificationsList(ctx, &notificationsPb.GetNotificationsListRequest{CustomerId: customerId, Skip: skip})
+			if err != nil {
+				return nil, err
+			}
+			if len(list.List) == 0 {
+				break
+			}
+
+			for _, item := range list.List {
+				if item.Id == notificationId {
+					return &Resource{Owner: item.CustomerId}, nil
+				}
+			}
+			skip += uint32(len(list.List))
+		}
+		return nil, ErrNotFound
+	})
+}
+
+// ForgetOffer -> drop the cached offer after its owner or participants change
+func (g *Guard) ForgetOffer(offerId uint64) {
+	g.forget(offerKey(offerId))
+}
+
+// ForgetOrder -> drop the cached order after its owner or participants change
+func (g *Guard) ForgetOrder(orderId uint64) {
+	g.forget(orderKey(orderId))
+}
+
+// ForgetReview -> drop the cached reviews of the reviewed customer
+func (g *Guard) ForgetReview(reviewId, customerId uint64) {
+	g.forget(reviewsKey(customerId))
+}
+
+// ForgetPost -> drop the cached post of the customer
+func (g *Guard) ForgetPost(postId, customerId uint64) {
+	g.forget(postKey(customerId, postId))
+}
+
+// ForgetNotification -> drop the cached notification of the customer
+func (g *Guard) ForgetNotification(notificationId, customerId uint64) {
+	g.forget(notificationKey(customerId, notificationId))
+}
+
+// ############################################################
+
+// resolve -> get the resource from the cache or by the fetch func,
+// the "not found" errors of the services are reported as ErrNotFound
+func (g *Guard) resolve(key string, fetch func() (*Resource, error)) (*Resource, error) {
+	if e, ok := g.cached(key, time.Now()); ok {
+		return e.resource, nil
+	}
+
+	resource, err := fetch()
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || strings.Contains(err.Error(), "not found") {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	g.keep(key, entry{resource: resource})
+	return resource, nil
+}
+
+// cached -> the entry of the key unless it's expired
+func (g *Guard) cached(key string, now time.Time) (entry, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return entry{}, false
+	}
+	return e, true
+}
+
+// keep -> cache the entry for the ttl
+func (g *Guard) keep(key string, e entry) {
+	if g.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.entries) >= maxEntries {
+		g.prune(now)
+	}
+	e.expires = now.Add(g.ttl)
+	g.entries[key] = e
+}
+
+func (g *Guard) forget(key string) {
+	g.mu.Lock()
+	delete(g.entries, key)
+	g.mu.Unlock()
+}
+
+// prune -> drop the expired entries, or all of them if none is expired
+func (g *Guard) prune(now time.Time) {
+	for key, e := range g.entries {
+		if !now.Before(e.expires) {
+			delete(g.entries, key)
+		}
+	}
+	if len(g.entries) >= maxEntries {
+		clear(g.entries)
+	}
+}
+
+func offerKey(offerId uint64) string {
+	return fmt.Sprintf("%s:%d", Offer, offerId)
+}
+
+func orderKey(orderId uint64) string {
+	return fmt.Sprintf("%s:%d", Order, orderId)
+}
+
+func reviewsKey(customerId uint64) string {
+	return fmt.Sprintf("%s:%d", Review, customerId)
+}
+
+func postKey(customerId, postId uint64) string {
+	return fmt.Sprintf("%s:%d:%d", Post, customerId, postId)
+}
+
+func notificationKey(customerId, notificationId uint64) string {
+	return fmt.Sprintf("%s:%d:%d", Notification, customerId, notificationId)
+}
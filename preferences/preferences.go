@@ -0,0 +1,213 @@
+package preferences
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+)
+
+// the channels a notification is sent by
+const (
+	ChannelInApp = "inApp"
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// AreaSecurity -> the area of the security notifications, e.g. a sign-in code,
+// it can't be muted
+const AreaSecurity = "security"
+
+// Areas -> the areas of the notifications sent by the gateway,
+// the admins may notify the customers of any other one
+var Areas = []string{"orders", "offers"}
+
+// maxAreas -> upper bound of the areas a customer may configure
+const maxAreas = 32
+
+var areaPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// ErrInvalidArea -> the area name is malformed or can't be configured
+var ErrInvalidArea = errors.New("invalid notification area")
+
+// Setting -> the channels of an area, a channel missing in the json is enabled
+//
+//   - MutedUntil -> every channel of the area is off until the time
+type Setting struct {
+	InApp      bool       `json:"inApp"`
+	Email      bool       `json:"email"`
+	Push       bool       `json:"push"`
+	MutedUntil *time.Time `json:"mutedUntil,omitempty"`
+}
+
+// Default -> the setting of an area the customer hasn't configured, every channel is on
+func Default() Setting {
+	return Setting{InApp: true, Email: true, Push: true}
+}
+
+func (s *Setting) UnmarshalJSON(data []byte) error {
+	type setting Setting
+	decoded := setting(Default())
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*s = Setting(decoded)
+	return nil
+}
+
+// Allows -> whether the channel is on at the time
+func (s Setting) Allows(channel string, now time.Time) bool {
+	if s.MutedUntil != nil && now.Before(*s.MutedUntil) {
+		return false
+	}
+
+	switch channel {
+	case ChannelInApp:
+		return s.InApp
+	case ChannelEmail:
+		return s.Email
+	case ChannelPush:
+		return s.Push
+	default:
+		return true
+	}
+}
+
+// Preferences -> the settings of the areas the customer has configured
+type Preferences struct {
+	Areas map[string]Setting `json:"areas"`
+}
+
+// Allows -> whether the customer gets a notification of the area by the channel at the time,
+// the security ones are always sent
+func (p Preferences) Allows(area, channel string, now time.Time) bool {
+	if area == AreaSecurity {
+		return true
+	}
+	setting, ok := p.Areas[area]
+	if !ok {
+		return true
+	}
+	return setting.Allows(channel, now)
+}
+
+// WithDefaults -> the preferences with every area of Areas, the unconfigured ones are on
+func (p Preferences) WithDefaults() Preferences {
+	areas := make(map[string]Setting, len(p.Areas)+len(Areas))
+	for _, area := range Areas {
+		areas[area] = Default()
+	}
+	for area, setting := range p.Areas {
+		areas[area] = setting
+	}
+	return Preferences{Areas: areas}
+}
+
+// Validate -> check the area names, the security area can't be configured
+func (p Preferences) Validate() error {
+	if len(p.Areas) > maxAreas {
+		return fmt.Errorf("%w: up to %d areas may be configured", ErrInvalidArea, maxAreas)
+	}
+	for area := range p.Areas {
+		if err := ValidateArea(area); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateArea -> check the area may be configured
+func ValidateArea(area string) error {
+	if area == AreaSecurity {
+		return fmt.Errorf("%w: the security notifications can't be muted", ErrInvalidArea)
+	}
+	if !areaPattern.MatchString(area) {
+		return fmt.Errorf("%w: %q", ErrInvalidArea, area)
+	}
+	return nil
+}
+
+// Store -> the notification preferences of the customers, the profile service
+// has no such fields so they're kept by the gateway
+type Store struct {
+	cache *cache.CacheService
+}
+
+func InitStore(cache *cache.CacheService) *Store {
+	return &Store{cache: cache}
+}
+
+// Get -> the preferences of the customer, empty ones if they're not set
+func (s *Store) Get(ctx context.Context, customerId uint64) (Preferences, error) {
+	encoded, err := s.cache.GetNotificationPreferences(ctx, customerId)
+	if err != nil || encoded == nil {
+		return Preferences{Areas: map[string]Setting{}}, err
+	}
+
+	var preferences Preferences
+	if err = json.Unmarshal(encoded, &preferences); err != nil {
+		return Preferences{Areas: map[string]Setting{}}, err
+	}
+	if preferences.Areas == nil {
+		preferences.Areas = map[string]Setting{}
+	}
+	return preferences, nil
+}
+
+// Set -> replace the preferences of the customer, the expired mutes are dropped
+func (s *Store) Set(ctx context.Context, customerId uint64, preferences Preferences) (Preferences, error) {
+	if err := preferences.Validate(); err != nil {
+		return Preferences{}, err
+	}
+
+	now := time.Now()
+	areas := make(map[string]Setting, len(preferences.Areas))
+	for area, setting := range preferences.Areas {
+		if setting.MutedUntil != nil && !now.Before(*setting.MutedUntil) {
+			setting.MutedUntil = nil
+		}
+		areas[area] = setting
+	}
+
+	preferences = Preferences{Areas: areas}
+
+	encoded, err := json.Marshal(preferences)
+	if err != nil {
+		return Preferences{}, err
+	}
+	if err = s.cache.SetNotificationPreferences(ctx, customerId, encoded); err != nil {
+		return Preferences{}, err
+	}
+	return preferences, nil
+}
+
+// SetArea -> replace the setting of a single area of the customer
+func (s *Store) SetArea(ctx context.Context, customerId uint64, area string, setting Setting) (Preferences, error) {
+	if err := ValidateArea(area); err != nil {
+		return Preferences{}, err
+	}
+
+	preferences, err := s.Get(ctx, customerId)
+	if err != nil {
+		return Preferences{}, err
+	}
+	preferences.Areas[area] = setting
+	return s.Set(ctx, customerId, preferences)
+}
+
+// Allows -> whether the customer gets a notification of the area by the channel now
+func (s *Store) Allows(ctx context.Context, customerId uint64, area, channel string) (bool, error) {
+	if area == AreaSecurity {
+		return true, nil
+	}
+
+	preferences, err := s.Get(ctx, customerId)
+	if err != nil {
+		return true, err
+	}
+	return preferences.Allows(area, channel, time.Now()), nil
+}
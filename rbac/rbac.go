@@ -0,0 +1,101 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+// permissions checked by the routes and handlers,
+// granted to the roles by config.RBACConfig
+const (
+	AdminAccess         = "admin:access"
+	OrdersUpdateApplied = "orders:update_applied"
+	OrdersDeleteApplied = "orders:delete_applied"
+
+	CustomersRead          = "customers:read"
+	CustomersCreate        = "customers:create"
+	CustomersBlock         = "customers:block"
+	OrdersRead             = "orders:read"
+	OrdersUpdate           = "orders:update"
+	ReviewsModerate        = "reviews:moderate"
+	BlogModerate           = "blog:moderate"
+	NotificationsBroadcast = "notifications:broadcast"
+	AuditRead              = "audit:read"
+)
+
+// Authorizer -> check the customer role permissions,
+// every denied check is recorded to the audit log
+//
+// a permission is granted by its exact name, by "{resource}:*" or by "*"
+type Authorizer struct {
+	roles   map[string]map[string]bool
+	auditor audit.Auditor
+}
+
+func InitAuthorizer(cfg config.RBACConfig, auditor audit.Auditor) *Authorizer {
+	roles := make(map[string]map[string]bool, len(cfg.Roles))
+	for role, permissions := range cfg.Roles {
+		granted := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			granted[permission] = true
+		}
+		roles[role] = granted
+	}
+
+	return &Authorizer{
+		roles:   roles,
+		auditor: auditor,
+	}
+}
+
+// Can -> check if the role is granted the permission
+func (a *Authorizer) Can(role, permission string) bool {
+	granted := a.roles[role]
+	if granted == nil {
+		return false
+	}
+	if granted["*"] || granted[permission] {
+		return true
+	}
+
+	resource, _, ok := strings.Cut(permission, ":")
+	return ok && granted[resource+":*"]
+}
+
+// HasPermissions -> check if the customer is granted every permission,
+// implements middlewares.PermissionChecker
+func (a *Authorizer) HasPermissions(r *http.Request, customer *authPb.CustomerDto, permissions ...string) bool {
+	for _, permission := range permissions {
+		if !a.Can(customer.Role, permission) {
+			a.deny(r.Context(), r, customer, permission)
+			return false
+		}
+	}
+	return true
+}
+
+// Authorize -> check the permission of the request customer in a handler
+func (a *Authorizer) Authorize(r *http.Request, permission string) bool {
+	customer, ok := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto)
+	if !ok || customer == nil {
+		return false
+	}
+	return a.HasPermissions(r, customer, permission)
+}
+
+func (a *Authorizer) deny(ctx context.Context, r *http.Request, customer *authPb.CustomerDto, permission string) {
+	a.auditor.Record(ctx, audit.Event{
+		ActorId:   customer.CustomerId,
+		ActorRole: customer.Role,
+		Action:    permission,
+		Target:    r.Method + " " + r.URL.Path,
+		Outcome:   audit.OutcomeDenied,
+		Ip:        audit.ClientIp(r),
+	})
+}
@@ -0,0 +1,67 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/fanout"
+	"go.uber.org/zap"
+)
+
+// Event -> a new notification pushed to the customer's streams
+type Event struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Area      string `json:"area"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Subscription -> the events of a single customer's stream
+type Subscription = fanout.Subscription[Event]
+
+// Hub -> the notification streams opened to a gateway instance,
+// the events are published to the customer's redis channel
+type Hub struct {
+	*fanout.Hub[Event]
+}
+
+func InitHub(cfg config.StreamConfig, store *cache.CacheService, logger *zap.Logger) *Hub {
+	kind := fanout.Kind{
+		Name:    "realtime",
+		Items:   "events",
+		Publish: store.PublishNotification,
+		Listen:  store.ListenNotifications,
+	}
+	return &Hub{Hub: fanout.InitHub[Event](cfg, kind, logger)}
+}
+
+// Publish -> push the event to every stream of the customer on any instance
+func (h *Hub) Publish(ctx context.Context, customerId uint64, event Event) error {
+	if event.Id == "" {
+		event.Id = rand.Text()
+	}
+	if event.CreatedAt == "" {
+		event.CreatedAt = time.Now().Format(time.RFC3339)
+	}
+	return h.Hub.Publish(ctx, customerId, event)
+}
+
+// Subscribe -> open a stream of the customer's events
+func (h *Hub) Subscribe(customerId uint64) (*Subscription, error) {
+	return h.Hub.Subscribe(customerId, customerId)
+}
+
+// ServeSSE -> push the events as the "notification" server-sent events
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request, s *Subscription) {
+	h.Hub.ServeSSE(w, r, s, "notification", func(event Event) string { return event.Id })
+}
+
+// ServeWebSocket -> push the events over a one-way WebSocket
+func (h *Hub) ServeWebSocket(w http.ResponseWriter, r *http.Request, s *Subscription) {
+	h.Hub.ServeWebSocket(w, r, s, nil)
+}
@@ -0,0 +1,35 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecation -> deprecation details of a version or a single route
+//
+//   - Since -> date the route was deprecated at, sent as the `Deprecation` header
+//   - Sunset -> date the route will be removed at, sent as the `Sunset` header
+//   - Link -> an optional link to the migration guide
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+	Link   string
+}
+
+// writeHeaders -> set the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+func (d *Deprecation) writeHeaders(w http.ResponseWriter) {
+	if d.Since.IsZero() {
+		w.Header().Set("Deprecation", "true")
+	} else {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+	}
+
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	if d.Link != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, d.Link))
+	}
+}
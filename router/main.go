@@ -0,0 +1,339 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+// Router -> a versioned api router on top of the http.ServeMux
+//
+// every version is mounted under /api/{version}/ and can either declare
+// its own handlers or inherit the handlers of a previous version
+type Router struct {
+	mux      *http.ServeMux
+	policies *middlewares.Policies
+	versions []*Version
+	routes   []*Route
+	metrics  *versionMetrics
+	limiter  *middlewares.RateLimiter
+	backends Availability
+	mounted  bool
+}
+
+// Availability -> tells whether a backend service can currently serve calls
+type Availability interface {
+	Available(service string) bool
+}
+
+// unavailableRetryAfter -> the Retry-After seconds of a route answered 503
+// because a backend service it requires is unavailable
+const unavailableRetryAfter = "5"
+
+// Version -> a group of routes served under the same /api/{version} prefix
+type Version struct {
+	name        string
+	prefix      string
+	base        *Version
+	routes      map[string]*Route
+	order       []string
+	deprecation *Deprecation
+}
+
+// Route -> a single route inside a version group
+type Route struct {
+	method      string
+	path        string
+	handler     http.Handler
+	policy      middlewares.Policy
+	deprecation *Deprecation
+	// request and response mappers are used when a version reuses
+	// the handler of the base version with a different payload shape
+	mapRequest  RequestMapper
+	mapResponse ResponseMapper
+	// backend services the route can't be served without
+	requires []string
+}
+
+// InitRouter -> create a new versioned router on top of the given mux,
+// the access policy of every mounted route is declared in the policies table
+func InitRouter(mux *http.ServeMux, policies *middlewares.Policies) *Router {
+	return &Router{
+		mux:      mux,
+		policies: policies,
+		metrics:  initVersionMetrics(),
+	}
+}
+
+// HandleFunc -> register an unversioned handler, e.g. "/health/"
+func (rt *Router) HandleFunc(pattern string, policy middlewares.Policy, handler http.HandlerFunc) {
+	rt.Handle(pattern, policy, handler)
+}
+
+// Handle -> register an unversioned handler by the http.ServeMux pattern
+func (rt *Router) Handle(pattern string, policy middlewares.Policy, handler http.Handler) {
+	method, path := splitPattern(pattern)
+	rt.routes = append(rt.routes, &Route{
+		method:  method,
+		path:    path,
+		handler: handler,
+		policy:  policy,
+	})
+}
+
+// UseRateLimiter -> apply the rate limit group of the route policy
+// to every versioned route, the unversioned ones are not limited
+func (rt *Router) UseRateLimiter(limiter *middlewares.RateLimiter) {
+	rt.limiter = limiter
+}
+
+// UseAvailability -> answer 503 at once to the routes requiring
+// a backend service that is unavailable instead of waiting on its retries
+func (rt *Router) UseAvailability(backends Availability) {
+	rt.backends = backends
+}
+
+// Version -> declare a new api version, e.g. "v1"
+func (rt *Router) Version(name string) *Version {
+	v := &Version{
+		name:   name,
+		prefix: "/api/" + name,
+		routes: make(map[string]*Route),
+	}
+	rt.versions = append(rt.versions, v)
+	return v
+}
+
+// Inherit -> reuse every route of the base version unless
+// it's overridden or removed in the current one
+func (v *Version) Inherit(base *Version) *Version {
+	v.base = base
+	return v
+}
+
+// Name -> get a version name
+func (v *Version) Name() string {
+	return v.name
+}
+
+// HandleFunc -> register a handler by the http.ServeMux pattern
+// relative to the version prefix, e.g. "POST /auth/sign-in/"
+func (v *Version) HandleFunc(pattern string, policy middlewares.Policy, handler http.HandlerFunc) *Route {
+	return v.Handle(pattern, policy, handler)
+}
+
+// Handle -> register (or override an inherited) handler by the pattern
+func (v *Version) Handle(pattern string, policy middlewares.Policy, handler http.Handler) *Route {
+	method, path := splitPattern(pattern)
+	key := method + " " + path
+
+	route := &Route{
+		method:  method,
+		path:    path,
+		handler: handler,
+		policy:  policy,
+	}
+
+	if _, ok := v.routes[key]; !ok {
+		v.order = append(v.order, key)
+	}
+	v.routes[key] = route
+	return route
+}
+
+// Map -> reuse the handler and the access policy of the base version
+// and convert the request and/or response between the version shapes
+func (v *Version) Map(pattern string, req RequestMapper, res ResponseMapper) (*Route, error) {
+	if v.base == nil {
+		return nil, fmt.Errorf("router: version %s has no base version to map %s from", v.name, pattern)
+	}
+
+	method, path := splitPattern(pattern)
+	base := v.base.lookup(method + " " + path)
+	if base == nil {
+		return nil, fmt.Errorf("router: route %s not found in the base version of %s", pattern, v.name)
+	}
+
+	route := v.Handle(pattern, base.policy, base.handler)
+	route.requires = slices.Clone(base.requires)
+	route.mapRequest = req
+	route.mapResponse = res
+	return route, nil
+}
+
+// Remove -> drop an inherited route from the current version
+func (v *Version) Remove(pattern string) {
+	method, path := splitPattern(pattern)
+	key := method + " " + path
+	if _, ok := v.routes[key]; !ok {
+		v.order = append(v.order, key)
+	}
+	v.routes[key] = nil
+}
+
+// Deprecate -> mark the whole version as deprecated
+func (v *Version) Deprecate(d Deprecation) *Version {
+	v.deprecation = &d
+	return v
+}
+
+// Deprecate -> mark a single route as deprecated
+func (r *Route) Deprecate(d Deprecation) *Route {
+	r.deprecation = &d
+	return r
+}
+
+// Requires -> declare the backend services the route can't be served without
+func (r *Route) Requires(services ...string) *Route {
+	r.requires = append(r.requires, services...)
+	return r
+}
+
+// Mount -> register every route of every version on the mux
+//
+// fails if any of the routes has no declared access policy,
+// so a forgotten policy is caught at startup instead of in production
+func (rt *Router) Mount() error {
+	if rt.mounted {
+		return errors.New("router: routes are already mounted")
+	}
+
+	for _, route := range rt.routes {
+		rt.policies.Declare(route.pattern(""), route.policy)
+	}
+	for _, v := range rt.versions {
+		for _, key := range v.keys() {
+			if route := v.lookup(key); route != nil {
+				rt.policies.Declare(route.pattern(v.prefix), route.policy)
+			}
+		}
+	}
+
+	if err := rt.policies.Validate(); err != nil {
+		return fmt.Errorf("router: %w", err)
+	}
+
+	for _, route := range rt.routes {
+		rt.mux.Handle(route.pattern(""), route.handler)
+	}
+	for _, v := range rt.versions {
+		for _, key := range v.keys() {
+			route := v.lookup(key)
+			if route == nil {
+				continue
+			}
+			rt.mux.Handle(route.pattern(v.prefix), rt.wrap(v, route, v.routes[key] == route))
+		}
+	}
+
+	rt.mounted = true
+	return nil
+}
+
+// wrap -> apply the version mappers, rate limits, deprecation headers, metrics
+// and the availability of the required backends
+//
+// a route level deprecation is applied only in the version it was declared in,
+// so inheriting a deprecated route doesn't deprecate it in the next version
+func (rt *Router) wrap(v *Version, route *Route, own bool) http.Handler {
+	var handler http.Handler = route.handler
+
+	if route.mapRequest != nil || route.mapResponse != nil {
+		handler = mapHandler(handler, route.mapRequest, route.mapResponse)
+	}
+	if rt.limiter != nil {
+		handler = rt.limiter.Limit(route.policy.RateLimitGroup(), handler)
+	}
+
+	var deprecation *Deprecation
+	if own {
+		deprecation = route.deprecation
+	}
+	if deprecation == nil {
+		deprecation = v.deprecation
+	}
+
+	endpoint := route.pattern("")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deprecation != nil {
+			deprecation.writeHeaders(w)
+		}
+		rt.metrics.observe(v.name, endpoint, deprecation != nil)
+		if service, ok := rt.unavailable(route); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", unavailableRetryAfter)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": service + " service is unavailable"})
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// unavailable -> the first backend required by the route that is unavailable
+func (rt *Router) unavailable(route *Route) (string, bool) {
+	if rt.backends == nil {
+		return "", false
+	}
+	for _, service := range route.requires {
+		if !rt.backends.Available(service) {
+			return service, true
+		}
+	}
+	return "", false
+}
+
+// keys -> get the route keys of the version including the inherited ones
+func (v *Version) keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+
+	if v.base != nil {
+		for _, key := range v.base.keys() {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range v.order {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// lookup -> find a route by the key in the version or its base
+func (v *Version) lookup(key string) *Route {
+	if route, ok := v.routes[key]; ok {
+		return route
+	}
+	if v.base != nil {
+		return v.base.lookup(key)
+	}
+	return nil
+}
+
+// pattern -> get the http.ServeMux pattern of the route under the prefix
+func (r *Route) pattern(prefix string) string {
+	if r.method == "" {
+		return prefix + r.path
+	}
+	return r.method + " " + prefix + r.path
+}
+
+// splitPattern -> split "METHOD /path/" into the method and the path
+func splitPattern(pattern string) (string, string) {
+	method, path, found := strings.Cut(strings.TrimSpace(pattern), " ")
+	if !found {
+		return "", method
+	}
+	return method, strings.TrimSpace(path)
+}
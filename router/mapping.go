@@ -0,0 +1,141 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// RequestMapper -> convert an incoming request of the current version
+// to the shape expected by the handler of the base version
+type RequestMapper func(r *http.Request) (*http.Request, error)
+
+// ResponseMapper -> convert a response body produced by the handler
+// of the base version to the shape of the current version
+type ResponseMapper func(status int, body []byte) ([]byte, error)
+
+// MapJSONBody -> a RequestMapper helper to rewrite a JSON request body
+func MapJSONBody(fn func(body map[string]any) (map[string]any, error)) RequestMapper {
+	return func(r *http.Request) (*http.Request, error) {
+		var body map[string]any
+		if r.Body != nil && r.ContentLength != 0 {
+			defer r.Body.Close()
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+		}
+
+		body, err := fn(body)
+		if err != nil {
+			return nil, err
+		}
+
+		// an empty body stays empty instead of becoming a null
+		var payload []byte
+		if body != nil {
+			if payload, err = json.Marshal(body); err != nil {
+				return nil, err
+			}
+		}
+
+		mapped := r.Clone(r.Context())
+		mapped.Body = http.NoBody
+		if len(payload) > 0 {
+			mapped.Body = io.NopCloser(bytes.NewReader(payload))
+		}
+		mapped.ContentLength = int64(len(payload))
+		mapped.Header.Set("Content-Length", strconv.Itoa(len(payload)))
+		return mapped, nil
+	}
+}
+
+// MapJSONResponse -> a ResponseMapper helper to rewrite a successful JSON response
+func MapJSONResponse(fn func(body map[string]any) (map[string]any, error)) ResponseMapper {
+	return func(status int, body []byte) ([]byte, error) {
+		if status >= http.StatusBadRequest || len(bytes.TrimSpace(body)) == 0 {
+			return body, nil
+		}
+
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+
+		payload, err := fn(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(payload)
+	}
+}
+
+// mapHandler -> wrap the base handler with the version mappers
+func mapHandler(next http.Handler, req RequestMapper, res ResponseMapper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if req != nil {
+			mapped, err := req(r)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			r = mapped
+		}
+
+		if res == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &bufferedWriter{header: make(http.Header)}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		body, err := res(status, rec.body.Bytes())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		for key, values := range rec.header {
+			if key == "Content-Length" {
+				continue
+			}
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+// bufferedWriter -> keeps the whole response in memory to map it afterwards
+type bufferedWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (b *bufferedWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedWriter) WriteHeader(code int) {
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
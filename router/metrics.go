@@ -0,0 +1,29 @@
+package router
+
+import (
+	"strconv"
+
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// versionMetrics -> per version usage to track when an old version can be retired
+type versionMetrics struct {
+	requests *prometheus.CounterVec
+}
+
+func initVersionMetrics() *versionMetrics {
+	requests := metrics.Register(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_version_requests_total",
+			Help: "Total number of API requests by version and route",
+		},
+		[]string{"version", "route", "deprecated"},
+	))
+
+	return &versionMetrics{requests: requests}
+}
+
+func (m *versionMetrics) observe(version, route string, deprecated bool) {
+	m.requests.WithLabelValues(version, route, strconv.FormatBool(deprecated)).Inc()
+}
@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/audit"
+)
+
+// @description -> Query the audit trail, the newest events first
+//
+// @route -> /api/v1/admin/audit/events/?actorId={actorId}&action={action}&target={target}&outcome={outcome}&from={RFC3339}&to={RFC3339}&limit={limit}
+//
+// every filter is optional, an action ending with "." matches by prefix (e.g. "orders."),
+// the limit is 500 at most
+//
+// the "redis" sink keeps every event unless AUDIT_STREAM_MAX_LEN is set, then the
+// events older than the last AUDIT_STREAM_MAX_LEN ones are deleted and aren't found
+//
+// @method -> GET
+//
+// @response 200
+//
+//	{
+//		events: []audit.Event
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+//
+// @response 501 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) GetAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+
+	filter, err := readAuditFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	querier, ok := h.auditor.(audit.Querier)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": audit.ErrNotQueryable.Error()})
+		return
+	}
+
+	events, err := querier.Query(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, audit.ErrNotQueryable) {
+			w.WriteHeader(http.StatusNotImplemented)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"events": events})
+}
+
+// readAuditFilter -> parse the audit query filters
+func readAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action:  query.Get("action"),
+		Target:  query.Get("target"),
+		Outcome: query.Get("outcome"),
+	}
+
+	var err error
+	if v := query.Get("actorId"); v != "" {
+		if filter.ActorId, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return filter, errors.New("invalid actorId")
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("from should be an RFC3339 time")
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errors.New("to should be an RFC3339 time")
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 {
+			return filter, errors.New("limit should be a positive number")
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	blogPb "github.com/noo8xl/anvil-api/main/blog"
+	"github.com/noo8xl/anvil-gateway/audit"
+)
+
+// @description -> Get the blog of any customer for moderation
+//
+// @route -> /api/v1/admin/blog/get-blog/{customerId}/{skip}/
+//
+// @method -> GET
+//
+// @response 200 {object} blogPb.Blog
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) GetBlogHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId, err := strconv.ParseUint(r.PathValue("customerId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	blog, err := h.blogClient.GetBlog(r.Context(), &blogPb.GetBlogRequest{
+		CustomerId: customerId,
+		Skip:       uint32(skip),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(blog)
+}
+
+// @description -> Edit a blog post of any customer, e.g. to redact an abusive text
+// (the blog service has no post removal, so a post is moderated by editing it)
+//
+// @route -> /api/v1/admin/blog/update/
+//
+// @method -> PUT
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		CustomerId: uint64
+//		PostId: uint64
+//		Title: string
+//		Description: string
+//	}
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto *blogPb.PostRequest
+	if err = json.Unmarshal(body, &dto); err != nil || dto == nil || dto.PostId == 0 || dto.CustomerId == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "post id and customer id are required"})
+		return
+	}
+
+	_, err = h.blogClient.UpdatePost(r.Context(), dto)
+	h.record(r, "admin.blog.update", audit.Target("post", dto.PostId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.cacheService.ClearBlog(r.Context(), dto.CustomerId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
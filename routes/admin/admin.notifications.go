@@ -1,9 +1,247 @@
 package routes
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"time"
+
+	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
+	profilePb "github.com/noo8xl/anvil-api/main/profile"
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/preferences"
+	"github.com/noo8xl/anvil-gateway/realtime"
 )
 
+// maxBroadcastRecipients -> upper bound of the customers notified by a single broadcast
+const maxBroadcastRecipients = 1000
+
+// @description -> Send a notification to a customer
+//
+// @route -> /api/v1/admin/notifications/create-notification/
+//
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		CustomerId: uint64
+//		Title: string
+//		Body: string
+//		Area: string
+//	}
+//
+// @response 201
+//
+// @response 200 -> the customer has muted the area, nothing is sent
+//
+//	{
+//		muted: true
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
 func (h *AdminHandler) CreateNotificationHandler(w http.ResponseWriter, r *http.Request) {
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto *notificationsPb.CreateNotificationRequest
+	if err = json.Unmarshal(body, &dto); err != nil || dto == nil || dto.CustomerId == 0 || dto.Title == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "customer id and title are required"})
+		return
+	}
+	dto.CreatedAt = time.Now().Format(time.RFC3339)
+
+	if !h.allows(r, dto.CustomerId, dto.Area, preferences.ChannelInApp) {
+		h.record(r, "admin.notifications.create", audit.Target("customer", dto.CustomerId), nil)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]bool{"muted": true})
+		return
+	}
+
+	_, err = h.notificationsClient.CreateNotification(r.Context(), dto)
+	h.record(r, "admin.notifications.create", audit.Target("customer", dto.CustomerId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	h.created(r, dto.CustomerId, dto.Title, dto.Body, dto.Area, dto.CreatedAt)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// @description -> Send the same notification to a list of customers,
+// optionally duplicated by an email
+//
+// @route -> /api/v1/admin/notifications/broadcast/
+//
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		customerIds: []uint64 // up to 1000
+//		title: string
+//		body: string
+//		area: string
+//		email: bool
+//	}
+//
+// @response 200
+//
+//	{
+//		sent: int
+//		muted: int // the customers muting both the area and its emails
+//		failed: []uint64
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) BroadcastNotificationHandler(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto struct {
+		CustomerIds []uint64 `json:"customerIds"`
+		Title       string   `json:"title"`
+		Body        string   `json:"body"`
+		Area        string   `json:"area"`
+		Email       bool     `json:"email"`
+	}
+	if err = json.Unmarshal(body, &dto); err != nil || len(dto.CustomerIds) == 0 || dto.Title == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "customer ids and title are required"})
+		return
+	}
+
+	slices.Sort(dto.CustomerIds)
+	dto.CustomerIds = slices.Compact(dto.CustomerIds)
+	if len(dto.CustomerIds) > maxBroadcastRecipients {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("a broadcast is limited to %d customers", maxBroadcastRecipients)})
+		return
+	}
+
+	createdAt := time.Now().Format(time.RFC3339)
+	failed := []uint64{}
+	muted := 0
+	for _, customerId := range dto.CustomerIds {
+		inApp := h.allows(r, customerId, dto.Area, preferences.ChannelInApp)
+		email := dto.Email && h.allows(r, customerId, dto.Area, preferences.ChannelEmail)
+		if !inApp && !email {
+			muted++
+			continue
+		}
+
+		err = nil
+		if inApp {
+			_, err = h.notificationsClient.CreateNotification(r.Context(), &notificationsPb.CreateNotificationRequest{
+				CustomerId: customerId,
+				Title:      dto.Title,
+				Body:       dto.Body,
+				Area:       dto.Area,
+				CreatedAt:  createdAt,
+			})
+		}
+		if err == nil && inApp {
+			h.created(r, customerId, dto.Title, dto.Body, dto.Area, createdAt)
+		}
+		if err == nil && email {
+			err = h.sendEmail(r.Context(), customerId, dto.Title, dto.Body)
+		}
+		if err != nil {
+			failed = append(failed, customerId)
+		}
+	}
+
+	sent := len(dto.CustomerIds) - len(failed) - muted
+	var broadcastErr error
+	if len(failed) > 0 {
+		broadcastErr = fmt.Errorf("failed to notify %d customers", len(failed))
+	}
+	h.record(r, "admin.notifications.broadcast", fmt.Sprintf("customers:%d", len(dto.CustomerIds)), broadcastErr)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"sent":   sent,
+		"muted":  muted,
+		"failed": failed,
+	})
+}
+
+// sendEmail -> email the customer by the address from the profile
+func (h *AdminHandler) sendEmail(ctx context.Context, customerId uint64, subject, body string) error {
+	profile, err := h.profileClient.GetCustomerProfile(ctx, &profilePb.GetCustomerProfileRequest{CustomerId: customerId})
+	if err != nil {
+		return err
+	}
+	if profile.Base == nil || profile.Base.Email == "" {
+		return fmt.Errorf("customer %d has no email", customerId)
+	}
+
+	_, err = h.notificationsClient.SendEmail(ctx, &notificationsPb.SendEmailRequest{
+		Email:   profile.Base.Email,
+		Subject: subject,
+		Body:    body,
+	})
+	return err
+}
+
+// created -> count a created notification as unread and push it to the customer's open streams,
+// it doesn't fail the request, the notification is in the list anyway
+func (h *AdminHandler) created(r *http.Request, customerId uint64, title, body, area, createdAt string) {
+	if err := h.cacheService.CountNewNotification(r.Context(), customerId); err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to count an unread notification: %w", err))
+	}
+	if !h.allows(r, customerId, area, preferences.ChannelPush) {
+		return
+	}
+
+	err := h.hub.Publish(r.Context(), customerId, realtime.Event{
+		Title:     title,
+		Body:      body,
+		Area:      area,
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to publish the notification: %w", err))
+	}
+}
+
+// allows -> whether the customer gets the notifications of the area by the channel,
+// the preferences that can't be read don't stop a notification
+func (h *AdminHandler) allows(r *http.Request, customerId uint64, area, channel string) bool {
+	allowed, err := h.preferences.Allows(r.Context(), customerId, area, channel)
+	if err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to read the notification preferences: %w", err))
+	}
+	return allowed
 }
@@ -1 +1,184 @@
 package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	ordersPb "github.com/noo8xl/anvil-api/main/orders"
+	"github.com/noo8xl/anvil-gateway/audit"
+)
+
+// @description -> Get the orders list of any customer or applicant
+//
+// @route -> /api/v1/admin/orders/get-orders-list/{skip}/?customerId={customerId}&applicantId={applicantId}&status={status}
+//
+// @method -> GET
+//
+// @response 200 {object} ordersPb.OrdersList
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) GetOrdersListHandler(w http.ResponseWriter, r *http.Request) {
+
+	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	filter := &ordersPb.GetOrdersListByFilterRequest{
+		Skip:   uint32(skip),
+		Status: strings.ToUpper(r.URL.Query().Get("status")),
+	}
+	if v := r.URL.Query().Get("customerId"); v != "" {
+		if filter.CustomerId, err = strconv.ParseUint(v, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if v := r.URL.Query().Get("applicantId"); v != "" {
+		if filter.ApplicantId, err = strconv.ParseUint(v, 10, 64); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	orders, err := h.ordersClient.GetOrdersListByFilter(r.Context(), filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.record(r, "admin.orders.list", audit.Target("customer", filter.CustomerId), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// @description -> Get the details of any order
+//
+// @route -> /api/v1/admin/orders/get-order-details/{orderId}/
+//
+// @method -> GET
+//
+// @response 200 {object} ordersPb.Order
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 404 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) GetOrderDetailsHandler(w http.ResponseWriter, r *http.Request) {
+
+	orderId, err := strconv.ParseUint(r.PathValue("orderId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	order, err := h.ordersClient.GetOrderDetails(r.Context(), &ordersPb.GetOrderDetailsRequest{OrderId: orderId})
+	if err != nil {
+		if strings.Contains(err.Error(), "order not found") {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "order not found"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.record(r, "admin.orders.read", audit.Target("order", orderId), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// @description -> Update any order regardless of its owner and status
+//
+// @route -> /api/v1/admin/orders/update/
+//
+// @method -> PUT
+//
+// @body -> body should follow the same structure as /api/v1/orders/update/
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto *ordersPb.OrderRequest
+	if err = json.Unmarshal(body, &dto); err != nil || dto == nil || dto.OrderBasics == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "order basics are required"})
+		return
+	}
+
+	if dto.PaymentDetails != nil && dto.OrderDetails != nil {
+		var totalSum float64
+		for _, round := range dto.PaymentDetails.PaymentRounds {
+			totalSum += round.Amount
+		}
+		if totalSum != dto.OrderDetails.Price {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "total sum of payment rounds is not equal to the order price"})
+			return
+		}
+	}
+
+	_, err = h.ordersClient.UpdateOrder(r.Context(), dto)
+	h.record(r, "admin.orders.update", audit.Target("order", dto.OrderBasics.OrderId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// the customer and the applicant may be changed, so the user routes resolve the owners again
+	h.owners.ForgetOrder(dto.OrderBasics.OrderId)
+
+	err = h.cacheService.ClearOrderDetails(r.Context(), dto.OrderBasics.OrderId)
+	if err == nil {
+		err = h.cacheService.ClearFilteredOrdersList(r.Context(), dto.OrderBasics.CustomerId)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
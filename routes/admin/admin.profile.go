@@ -1,9 +1,242 @@
 package routes
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	profilePb "github.com/noo8xl/anvil-api/main/profile"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/middlewares"
 )
 
+// @description -> Find a customer by email or by id
+//
+// @route -> /api/v1/admin/customers/search/?email={email} or ?customerId={customerId}
+//
+// @method -> GET
+//
+// @response 200
+//
+//	{
+//		customer: profilePb.CustomerResponse
+//		blocked: bool
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 404 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) SearchCustomersHandler(w http.ResponseWriter, r *http.Request) {
+
+	var customerId uint64
+	var err error
+
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	switch {
+	case email != "":
+		var customer *authPb.CustomerDto
+		customer, err = h.authClient.GetCustomer(r.Context(), &authPb.GetCustomerByEmailRequest{Email: email})
+		if err == nil {
+			customerId = customer.CustomerId
+		}
+	case r.URL.Query().Get("customerId") != "":
+		customerId, err = strconv.ParseUint(r.URL.Query().Get("customerId"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email or customerId is required"})
+		return
+	}
+
+	var profile *profilePb.CustomerResponse
+	if err == nil {
+		profile, err = h.profileClient.GetCustomerProfile(r.Context(), &profilePb.GetCustomerProfileRequest{CustomerId: customerId})
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "customer not found"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	blocked, err := h.cacheService.IsCustomerBlocked(r.Context(), customerId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.record(r, "admin.customers.read", audit.Target("customer", customerId), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"customer": profile,
+		"blocked":  blocked,
+	})
+}
+
+// @description -> Create a customer on behalf of the support team
+//
+// @route -> /api/v1/admin/customers/create/
+//
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		Email: string
+//		Name: string
+//		Password: string
+//	}
+//
+// @response 201 {object} profilePb.CreateCustomerResponse
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
 func (h *AdminHandler) CreateCustomerHandler(w http.ResponseWriter, r *http.Request) {
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto *profilePb.CreateCustomerRequest
+	if err = json.Unmarshal(body, &dto); err != nil || dto == nil || dto.Email == "" || dto.Password == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email and password are required"})
+		return
+	}
+
+	response, err := h.profileClient.CreateCustomer(r.Context(), dto)
+	if err != nil {
+		h.record(r, "admin.customers.create", "customer:"+dto.Email, err)
+		if strings.Contains(err.Error(), "customer already exists") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "customer already exists"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.record(r, "admin.customers.create", audit.Target("customer", response.CustomerId), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @description -> Block a customer, every session of the customer is revoked
+// and the customer can't sign in until unblocked
+//
+// @route -> /api/v1/admin/customers/block/{customerId}/
+//
+// @method -> POST
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) BlockCustomerHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId, err := h.readCustomerId(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	err = h.cacheService.BlockCustomer(r.Context(), customerId)
+	if err == nil {
+		err = h.sessions.RevokeAll(r.Context(), customerId)
+	}
+	h.record(r, "admin.customers.block", audit.Target("customer", customerId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @description -> Unblock a customer
+//
+// @route -> /api/v1/admin/customers/unblock/{customerId}/
+//
+// @method -> POST
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) UnblockCustomerHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId, err := h.readCustomerId(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	err = h.cacheService.UnblockCustomer(r.Context(), customerId)
+	h.record(r, "admin.customers.unblock", audit.Target("customer", customerId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readCustomerId -> get the {customerId} path value, an admin can't block themselves
+func (h *AdminHandler) readCustomerId(r *http.Request) (uint64, error) {
+	customerId, err := strconv.ParseUint(r.PathValue("customerId"), 10, 64)
+	if err != nil || customerId == 0 {
+		return 0, errors.New("invalid customer id")
+	}
+
+	if actor, ok := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto); ok && actor != nil && actor.CustomerId == customerId {
+		return 0, errors.New("you can't change your own account status")
+	}
+	return customerId, nil
 }
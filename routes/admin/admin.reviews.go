@@ -1 +1,168 @@
 package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
+	"github.com/noo8xl/anvil-gateway/audit"
+)
+
+// @description -> Get the reviews of any customer for moderation
+//
+// @route -> /api/v1/admin/reviews/get-reviews-list/{customerId}/{skip}/
+//
+// @method -> GET
+//
+// @response 200 {object} reviewsPb.GetReviewsListResponse
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) GetReviewsListHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId, err := strconv.ParseUint(r.PathValue("customerId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	reviews, err := h.reviewsClient.GetReviewsList(r.Context(), &reviewsPb.GetReviewsListRequest{
+		CustomerId: customerId,
+		Skip:       uint32(skip),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// @description -> Edit a review, e.g. to redact an abusive text
+//
+// @route -> /api/v1/admin/reviews/update/
+//
+// @method -> PUT
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		ReviewId: uint64
+//		ReviewerId: uint64
+//		CustomerId: uint64
+//		Title: string
+//		Body: string
+//	}
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) UpdateReviewHandler(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto *reviewsPb.ReviewRequest
+	if err = json.Unmarshal(body, &dto); err != nil || dto == nil || dto.ReviewId == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "review id is required"})
+		return
+	}
+
+	_, err = h.reviewsClient.UpdateReview(r.Context(), dto)
+	h.record(r, "admin.reviews.update", audit.Target("review", dto.ReviewId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.cacheService.ClearReviewDetails(r.Context(), dto.ReviewId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @description -> Delete a review of the customer
+//
+// @route -> /api/v1/admin/reviews/delete/{customerId}/{reviewId}/
+//
+// @method -> DELETE
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 404 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *AdminHandler) DeleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId, err := strconv.ParseUint(r.PathValue("customerId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	reviewId, err := strconv.ParseUint(r.PathValue("reviewId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	_, err = h.reviewsClient.DeleteReview(r.Context(), &reviewsPb.DeleteReviewRequest{
+		ReviewId:   reviewId,
+		CustomerId: customerId,
+	})
+	h.record(r, "admin.reviews.delete", audit.Target("review", reviewId), err)
+	if err != nil {
+		if strings.Contains(err.Error(), "review not found") {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "review not found"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.cacheService.ClearReviewDetails(r.Context(), reviewId)
+	h.cacheService.ClearReviewCommentsList(r.Context(), reviewId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
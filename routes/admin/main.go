@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"net/http"
+
 	authPb "github.com/noo8xl/anvil-api/main/auth"
 	blogPb "github.com/noo8xl/anvil-api/main/blog"
 	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
@@ -11,6 +13,13 @@ import (
 
 	// promotionsPb "github.com/noo8xl/anvil-api/main/promotions"
 	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
+
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/ownership"
+	"github.com/noo8xl/anvil-gateway/preferences"
+	"github.com/noo8xl/anvil-gateway/realtime"
+	"github.com/noo8xl/anvil-gateway/tokens"
 )
 
 type AdminHandler struct {
@@ -23,6 +32,12 @@ type AdminHandler struct {
 	offersClient        offersPb.OffersServiceClient
 	notificationsClient notificationsPb.NotificationsServiceClient
 	// promotionsClient    promotionsPb.PromotionsServiceClient
+	cacheService *cache.CacheService
+	sessions     *tokens.Sessions
+	auditor      audit.Auditor
+	hub          *realtime.Hub
+	preferences  *preferences.Store
+	owners       *ownership.Guard
 }
 
 func InitAdminHandler(
@@ -35,6 +50,11 @@ func InitAdminHandler(
 	offersClient offersPb.OffersServiceClient,
 	notificationsClient notificationsPb.NotificationsServiceClient,
 	// promotionsClient promotionsPb.PromotionsServiceClient,
+	sessions *tokens.Sessions,
+	auditor audit.Auditor,
+	hub *realtime.Hub,
+	notificationPreferences *preferences.Store,
+	owners *ownership.Guard,
 ) *AdminHandler {
 	return &AdminHandler{
 		authClient:          authClient,
@@ -46,5 +66,19 @@ func InitAdminHandler(
 		offersClient:        offersClient,
 		notificationsClient: notificationsClient,
 		// promotionsClient:    promotionsClient,
+		cacheService: cache.InitCacheService(),
+		sessions:     sessions,
+		auditor:      auditor,
+		hub:          hub,
+		preferences:  notificationPreferences,
+		owners:       owners,
 	}
 }
+
+// ############################################################
+
+// record -> put an admin action to the audit trail,
+// the target is a "{kind}:{id}" reference of the affected resource
+func (h *AdminHandler) record(r *http.Request, action, target string, err error) {
+	h.auditor.Record(r.Context(), audit.NewEvent(r, action, target, err))
+}
@@ -1,11 +1,37 @@
 package routes
 
-import "net/http"
+import (
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/rbac"
+	"github.com/noo8xl/anvil-gateway/router"
+)
 
-func (h *AdminHandler) RegisterAdminRoutes(mux *http.ServeMux) {
-	// mux.HandleFunc("GET /admin/orders/get-orders-list/{skip}/", h.GetOrdersListHandler)
+// adminWith -> an access policy of an admin route, every route
+// requires the admin access and the permission of its action
+func adminWith(permissions ...string) middlewares.Policy {
+	return middlewares.RequirePermissions(append([]string{rbac.AdminAccess}, permissions...)...)
+}
+
+func (h *AdminHandler) RegisterAdminRoutes(api *router.Version) {
+
+	api.HandleFunc("GET /admin/customers/search/", adminWith(rbac.CustomersRead), h.SearchCustomersHandler).Requires("auth", "profile")
+	api.HandleFunc("POST /admin/customers/create/", adminWith(rbac.CustomersCreate), h.CreateCustomerHandler).Requires("profile")
+	api.HandleFunc("POST /admin/customers/block/{customerId}/", adminWith(rbac.CustomersBlock), h.BlockCustomerHandler)
+	api.HandleFunc("POST /admin/customers/unblock/{customerId}/", adminWith(rbac.CustomersBlock), h.UnblockCustomerHandler)
+
+	api.HandleFunc("GET /admin/orders/get-orders-list/{skip}/", adminWith(rbac.OrdersRead), h.GetOrdersListHandler).Requires("orders")
+	api.HandleFunc("GET /admin/orders/get-order-details/{orderId}/", adminWith(rbac.OrdersRead), h.GetOrderDetailsHandler).Requires("orders")
+	api.HandleFunc("PUT /admin/orders/update/", adminWith(rbac.OrdersUpdate), h.UpdateOrderHandler).Requires("orders")
+
+	api.HandleFunc("GET /admin/reviews/get-reviews-list/{customerId}/{skip}/", adminWith(rbac.ReviewsModerate), h.GetReviewsListHandler).Requires("reviews")
+	api.HandleFunc("PUT /admin/reviews/update/", adminWith(rbac.ReviewsModerate), h.UpdateReviewHandler).Requires("reviews")
+	api.HandleFunc("DELETE /admin/reviews/delete/{customerId}/{reviewId}/", adminWith(rbac.ReviewsModerate), h.DeleteReviewHandler).Requires("reviews")
+	api.HandleFunc("GET /admin/blog/get-blog/{customerId}/{skip}/", adminWith(rbac.BlogModerate), h.GetBlogHandler).Requires("blog")
+	api.HandleFunc("PUT /admin/blog/update/", adminWith(rbac.BlogModerate), h.UpdatePostHandler).Requires("blog")
+
+	api.HandleFunc("POST /admin/notifications/create-notification/", adminWith(rbac.NotificationsBroadcast), h.CreateNotificationHandler).Requires("notifications")
+	api.HandleFunc("POST /admin/notifications/broadcast/", adminWith(rbac.NotificationsBroadcast), h.BroadcastNotificationHandler).Requires("notifications", "profile")
 
-	// mux.HandleFunc("POST /admin/notifications/create-notification/", h.CreateNotificationHandler)
+	api.HandleFunc("GET /admin/audit/events/", adminWith(rbac.AuditRead), h.GetAuditEventsHandler)
 
-	// mux.HandleFunc("POST /profile/create/", h.CreateCustomer) // TODO: admin permission only
 }
@@ -3,17 +3,27 @@ package routes
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 
 	authPb "github.com/noo8xl/anvil-api/main/auth"
-	notificationPb "github.com/noo8xl/anvil-api/main/notifications"
 	profilePb "github.com/noo8xl/anvil-api/main/profile"
+	"github.com/noo8xl/anvil-common/exceptions"
 	helpers "github.com/noo8xl/anvil-common/helpers"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/bruteforce"
+	"github.com/noo8xl/anvil-gateway/otp"
+	"github.com/noo8xl/anvil-gateway/templates"
+	"github.com/noo8xl/anvil-gateway/tokens"
 )
 
+// minPasswordLength -> the shortest password accepted on reset
+const minPasswordLength = 8
+
 // @description -> Sign up a new customer
 //
 // @route -> /api/v1/auth/sign-up
@@ -58,7 +68,7 @@ func (h *Handler) HandleAuthSignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.profileClient.CreateCustomer(context.Background(), dto)
+	_, err = h.profileClient.CreateCustomer(r.Context(), dto)
 	if err != nil {
 		if err.Error() == "rpc error: code = Unknown desc = customer already exists" {
 			w.WriteHeader(http.StatusBadRequest)
@@ -79,12 +89,21 @@ func (h *Handler) HandleAuthSignUp(w http.ResponseWriter, r *http.Request) {
 //
 // @method -> POST
 //
-// @response 200 {object} authPb.SignInResponse
+// @response 200 {object} authPb.SignInResponse with the session tokens
 //
 //	{
 //		Email: string
 //		Password: string
-//		TwoStepCode: string
+//		TwoStepCode: string // emailed, authenticator app or recovery code
+//	}
+//
+// the response also contains the session tokens:
+//
+//	{
+//		sessionId: string
+//		accessToken: string
+//		refreshToken: string
+//		expiresIn: int64
 //	}
 //
 // @response 400 {object} ErrorResponse {error: err text}
@@ -93,6 +112,8 @@ func (h *Handler) HandleAuthSignUp(w http.ResponseWriter, r *http.Request) {
 //
 // @response 403 {object} ErrorResponse {error: err text}
 //
+// @response 429 {object} ErrorResponse {error: err text} with a Retry-After header
+//
 // @response 500 {object} ErrorResponse {error: err text}
 func (h *Handler) HandleAuthSignIn(w http.ResponseWriter, r *http.Request) {
 
@@ -112,39 +133,65 @@ func (h *Handler) HandleAuthSignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	customer, err := h.authClient.GetCustomer(context.Background(), &authPb.GetCustomerByEmailRequest{Email: dto.Email})
+	attemptKeys := []string{bruteforce.EmailKey(dto.Email), bruteforce.IpKey(audit.ClientIp(r))}
+	if err = h.guard.Check(r.Context(), bruteforce.SignIn, attemptKeys...); err != nil {
+		writeAttemptsError(w, err)
+		return
+	}
+
+	customer, err := h.authClient.GetCustomer(r.Context(), &authPb.GetCustomerByEmailRequest{Email: dto.Email})
 	if err != nil {
+		h.guard.Fail(r.Context(), bruteforce.SignIn, attemptKeys...)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	if customer.IsTwoFa.Value && dto.TwoStepCode != "" {
-		c, err := h.cacheService.Get2FACode(dto.Email)
+	method := otp.MethodEmail
+	if customer.IsTwoFa.Value {
+		method, err = h.cacheService.GetTwoStepMethod(r.Context(), customer.CustomerId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	if customer.IsTwoFa.Value && method == otp.MethodTOTP {
+		if dto.TwoStepCode == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "two-step auth is enabled. enter the code from the authenticator app or a recovery code"})
+			return
+		}
+	}
+
+	if customer.IsTwoFa.Value && method == otp.MethodEmail && dto.TwoStepCode != "" {
+		c, err := h.cacheService.Get2FACode(r.Context(), dto.Email)
 		if err != nil {
+			h.guard.Fail(r.Context(), bruteforce.SignIn, attemptKeys...)
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
 
-		if c != dto.TwoStepCode {
+		if !sameCode(c, dto.TwoStepCode) {
+			h.guard.Fail(r.Context(), bruteforce.SignIn, attemptKeys...)
+			h.guard.FailCode(r.Context(), dto.Email)
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "invalid code"})
 			return
 		}
 	}
 
-	if customer.IsTwoFa.Value && dto.TwoStepCode == "" {
+	if customer.IsTwoFa.Value && method == otp.MethodEmail && dto.TwoStepCode == "" {
 		code := helpers.GenerateRandomPassword(6)
-		h.cacheService.Set2FACode(dto.Email, code)
+		h.cacheService.Set2FACode(r.Context(), dto.Email, code)
 
-		notificationDto := &notificationPb.SendEmailRequest{
-			Email:   dto.Email,
-			Subject: "Two-Step Verification",
-			Body:    code,
+		locale := h.locale(r.Context(), customer.CustomerId, r.Header.Get("Accept-Language"))
+		notificationDto, err := h.renderEmail(dto.Email, templates.TwoStepCode, locale, templates.Data{"Code": code})
+		if err == nil {
+			_, err = h.notificationsClient.SendEmail(r.Context(), notificationDto)
 		}
-
-		_, err = h.notificationsClient.SendEmail(context.Background(), notificationDto)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -155,26 +202,32 @@ func (h *Handler) HandleAuthSignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authClient.SignIn(context.Background(), dto)
+	response, err := h.authClient.SignIn(r.Context(), dto)
 	if err != nil {
-		if strings.Split(err.Error(), "desc = ")[1] == "invalid code" {
+		desc := err.Error()
+		if _, after, ok := strings.Cut(desc, "desc = "); ok {
+			desc = after
+		}
+		if desc == "invalid code" || desc == "invalid password" || desc == "customer not found" {
+			h.guard.Fail(r.Context(), bruteforce.SignIn, attemptKeys...)
+		}
+
+		if desc == "invalid code" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "invalid code"})
 			return
 		}
 
-		if strings.Split(err.Error(), "desc = ")[1] == "two-step auth is enabled" {
+		if desc == "two-step auth is enabled" {
 
 			code := helpers.GenerateRandomPassword(6)
-			h.cacheService.Set2FACode(dto.Email, code)
+			h.cacheService.Set2FACode(r.Context(), dto.Email, code)
 
-			notificationDto := &notificationPb.SendEmailRequest{
-				Email:   dto.Email,
-				Subject: "Two-Step Verification",
-				Body:    code,
+			locale := h.locale(r.Context(), customer.CustomerId, r.Header.Get("Accept-Language"))
+			notificationDto, err := h.renderEmail(dto.Email, templates.TwoStepCode, locale, templates.Data{"Code": code})
+			if err == nil {
+				_, err = h.notificationsClient.SendEmail(r.Context(), notificationDto)
 			}
-
-			_, err = h.notificationsClient.SendEmail(context.Background(), notificationDto)
 			if err != nil {
 				log.Println("auth sign in err -> ", err)
 				w.WriteHeader(http.StatusInternalServerError)
@@ -186,13 +239,13 @@ func (h *Handler) HandleAuthSignIn(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if strings.Split(err.Error(), "desc = ")[1] == "invalid password" {
+		if desc == "invalid password" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "invalid password"})
 			return
 		}
 
-		if strings.Split(err.Error(), "desc = ")[1] == "customer not found" {
+		if desc == "customer not found" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "customer not found"})
 			return
@@ -203,96 +256,214 @@ func (h *Handler) HandleAuthSignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	// the authenticator code is checked once the password is accepted, so a wrong password
+	// neither uses up a recovery code nor tells whether the code is valid
+	if customer.IsTwoFa.Value && method == otp.MethodTOTP {
+		if err = h.verifyTOTPCode(r.Context(), customer.CustomerId, dto.TwoStepCode); err != nil {
+			h.guard.Fail(r.Context(), bruteforce.SignIn, attemptKeys...)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	// only the email counter is reset, so an own account can't be used to clear the ip one
+	h.guard.Reset(r.Context(), bruteforce.SignIn, bruteforce.EmailKey(dto.Email))
+	if customer.IsTwoFa.Value && method == otp.MethodEmail {
+		h.cacheService.Clear2FACode(r.Context(), dto.Email)
+	}
+
+	// checked after the credentials, so the blocked accounts can't be enumerated
+	blocked, err := h.cacheService.IsCustomerBlocked(r.Context(), customer.CustomerId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if blocked {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": tokens.ErrCustomerBlocked.Error()})
+		return
+	}
+
+	pair, err := h.sessions.Create(r.Context(), customer, tokens.SessionMeta{Ip: audit.ClientIp(r), UserAgent: r.UserAgent()})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		*authPb.SignInResponse
+		*tokens.TokenPair
+	}{response, pair})
 
 }
 
-// @description -> Forgot password
+// @description -> Request a password reset link, the response is the same
+// whether the account exists or not, so it can't be used to enumerate accounts
 //
-// @route -> /api/v1/auth/forgot-password/{email}
+// @route -> /api/v1/auth/forgot-password/
 //
 // @method -> POST
 //
+// @body -> body should follow the following structure:
+//
+//	{
+//		email: string
+//	}
+//
 // @response 202
 //
 // @response 400 {object} ErrorResponse {error: err text}
+func (h *Handler) HandleAuthForgotPwd(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto struct {
+		Email string `json:"email"`
+	}
+	if err = json.Unmarshal(body, &dto); err != nil || dto.Email == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email is required"})
+		return
+	}
+
+	// the lookup and the email are sent in the background,
+	// so the response time doesn't depend on the account existence,
+	// the request cancellation is dropped as they outlive the request,
+	// the shutdown waits for them to finish
+	acceptLanguage := r.Header.Get("Accept-Language")
+	h.jobs.Go(r.Context(), func(ctx context.Context) {
+		h.sendPasswordResetLink(ctx, dto.Email, acceptLanguage)
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the account exists, a reset link was sent to the email"})
+}
+
+// @description -> Set a new password by the token from the reset link,
+// every active session of the customer is revoked
 //
-// @response 401 {object} ErrorResponse {error: err text}
+// @route -> /api/v1/auth/reset-password/
 //
-// @response 403 {object} ErrorResponse {error: err text}
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		token: string
+//		newPassword: string
+//	}
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
 //
 // @response 500 {object} ErrorResponse {error: err text}
-func (h *Handler) HandleAuthForgotPwd(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleAuthResetPwd(w http.ResponseWriter, r *http.Request) {
 
-	if r.PathValue("email") == "" {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "email is required"})
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err = json.Unmarshal(body, &dto); err != nil || dto.Token == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "token is required"})
+		return
+	}
+
+	if len(dto.NewPassword) < minPasswordLength {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("password must be at least %d characters long", minPasswordLength)})
 		return
 	}
 
-	response, err := h.authClient.GetCustomerPassword(context.Background(), &authPb.GetCustomerByEmailRequest{Email: r.PathValue("email")})
+	reset, err := h.resets.Consume(r.Context(), dto.Token)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	if response.Password == "" {
+	current, err := h.authClient.GetCustomerPassword(r.Context(), &authPb.GetCustomerByEmailRequest{Email: reset.Email})
+	if err != nil || current.CustomerId != reset.CustomerId {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "- customer not found"})
+		json.NewEncoder(w).Encode(map[string]string{"error": tokens.ErrInvalidResetToken.Error()})
 		return
 	}
 
-	pwd := helpers.GenerateRandomPassword(12)
-	hash, err := helpers.EncryptKey(pwd)
+	hash, err := helpers.EncryptKey(dto.NewPassword)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	// log pwd and hash while debugging
-	// log.Println("generated pwd -> ", pwd)
-	// log.Println("encrypted pwd -> ", hash)
-
 	changePasswordDto := &profilePb.ChangePasswordRequest{
-		CustomerId:  response.CustomerId,
-		OldPassword: response.Password,
+		CustomerId:  current.CustomerId,
+		OldPassword: current.Password,
 		NewPassword: hash,
 	}
 
-	notificationDto := &notificationPb.SendEmailRequest{
-		Email:   response.Email,
-		Subject: "Reset Password",
-		Body:    pwd,
+	_, err = h.profileClient.ChangePassword(r.Context(), changePasswordDto)
+	// the request is anonymous, the actor is the owner of the reset token
+	event := audit.NewEvent(r, "auth.password.reset", audit.Target("customer", current.CustomerId), err)
+	event.ActorId = current.CustomerId
+	h.auditor.Record(r.Context(), event)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
 	}
 
-	if _, err = h.profileClient.ChangePassword(context.Background(), changePasswordDto); err != nil {
-		if err.Error() == "got a wrong old password" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "got a wrong old password"})
-			return
-		}
+	if err = h.sessions.RevokeAll(r.Context(), current.CustomerId); err != nil {
+		exceptions.HandleAnException(err)
+	}
 
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "-- customer not found"})
-			return
-		}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+// sendPasswordResetLink -> issue a reset token and email the link if the account exists
+func (h *Handler) sendPasswordResetLink(ctx context.Context, email, acceptLanguage string) {
+	customer, err := h.authClient.GetCustomer(ctx, &authPb.GetCustomerByEmailRequest{Email: email})
+	if err != nil || customer == nil || customer.CustomerId == 0 {
 		return
 	}
 
-	_, err = h.notificationsClient.SendEmail(context.Background(), notificationDto)
+	token, err := h.resets.Issue(ctx, customer.CustomerId, customer.Email)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		exceptions.HandleAnException(err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	link := token
+	if h.resetUrl != "" {
+		link = h.resetUrl + "?token=" + url.QueryEscape(token)
+	}
+
+	locale := h.locale(ctx, customer.CustomerId, acceptLanguage)
+	notificationDto, err := h.renderEmail(customer.Email, templates.PasswordReset, locale, templates.Data{"Link": link})
+	if err == nil {
+		_, err = h.notificationsClient.SendEmail(ctx, notificationDto)
+	}
+	if err != nil {
+		exceptions.HandleAnException(err)
+	}
 }
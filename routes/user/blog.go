@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -63,7 +62,7 @@ func (h *Handler) CreateBlogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = h.blogClient.CreatePost(context.Background(), dto); err != nil {
+	if _, err = h.blogClient.CreatePost(r.Context(), dto); err != nil {
 		if strings.Split(err.Error(), "desc = ")[1] == "customer not found" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "customer not found"})
@@ -127,7 +126,16 @@ func (h *Handler) UpdateBlogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := h.blogClient.UpdatePost(context.Background(), dto); err != nil {
+	owner, err := h.owners.Post(r.Context(), dto.PostId, customerId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	if _, err := h.blogClient.UpdatePost(r.Context(), dto); err != nil {
 		if strings.Split(err.Error(), "desc = ")[1] == "customer not found" {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "customer not found"})
@@ -196,7 +204,7 @@ func (h *Handler) GetBlogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blog, err := h.cacheService.GetBlog(customerId)
+	blog, err := h.cacheService.GetBlog(r.Context(), customerId)
 	if err != nil {
 		w.WriteHeader(500)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -218,14 +226,14 @@ func (h *Handler) GetBlogHandler(w http.ResponseWriter, r *http.Request) {
 			Skip:       uint32(skip),
 		}
 
-		response, err := h.blogClient.GetBlog(context.Background(), dto)
+		response, err := h.blogClient.GetBlog(r.Context(), dto)
 		if err != nil {
 			w.WriteHeader(500)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
 
-		h.cacheService.SetBlog(customerId, response)
+		h.cacheService.SetBlog(r.Context(), customerId, response)
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
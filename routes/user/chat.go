@@ -0,0 +1,444 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/noo8xl/anvil-gateway/chat"
+	"github.com/noo8xl/anvil-gateway/fanout"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/ownership"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// createChatDto -> the subject of a new chat,
+// the owner of an order or an offer picks one of its participants by ParticipantId,
+// a participant talks to the owner
+type createChatDto struct {
+	Subject       string `json:"subject"`
+	SubjectId     uint64 `json:"subjectId"`
+	ParticipantId uint64 `json:"participantId"`
+}
+
+// sendMessageDto -> a new chat message
+type sendMessageDto struct {
+	Body string `json:"body"`
+}
+
+// @description -> Open a chat about an order or an offer, the chat of the same
+// participants is returned if they have it already
+//
+// @route -> /api/v1/chat/create-chat/
+//
+// @method -> POST
+//
+// @body:
+//
+//	{
+//		subject: string ("order" | "offer")
+//		subjectId: uint64
+//		participantId: uint64 (the owner picks a participant, the only one by default)
+//	}
+//
+// @response 201:
+//
+//	{
+//		id: uint64
+//		subject: string
+//		subjectId: uint64
+//		participants: []uint64
+//		createdAt: string
+//		lastMessageAt: string
+//	}
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) CreateChatHandler(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto createChatDto
+	if err := json.Unmarshal(body, &dto); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err := chat.ValidateSubject(dto.Subject); err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	resource, err := h.subjectOf(r.Context(), dto.Subject, dto.SubjectId)
+	if err == nil {
+		err = resource.RequireParticipant(customerId)
+	}
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	other := resource.Owner
+	if resource.IsOwner(customerId) {
+		other = dto.ParticipantId
+		if other == 0 && len(resource.Participants) == 1 {
+			other = resource.Participants[0]
+		}
+		if other == customerId || !slices.Contains(resource.Participants, other) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("participantId has to take part in the %s", dto.Subject)})
+			return
+		}
+	}
+
+	created, err := h.chatClient.CreateChat(r.Context(), dto.Subject, dto.SubjectId, []uint64{customerId, other})
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @description -> Get the chats list of the customer, the recently active first
+//
+// @route -> /api/v1/chat/get-chats-list/{customerId}/{skip}/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 200:
+//
+//	{
+//		list: [{
+//			id: uint64
+//			subject: string
+//			subjectId: uint64
+//			participants: []uint64
+//			createdAt: string
+//			lastMessageAt: string
+//		}]
+//	}
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) GetChatsListHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.ParseUint(r.PathValue("customerId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	if err := validateCustomer(customerId, id); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	list, err := h.chatClient.GetChatsList(r.Context(), customerId, uint32(skip))
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"list": list})
+}
+
+// @description -> Get the messages list of a chat, the newest first
+//
+// @route -> /api/v1/chat/get-chat-messages-list/{chatId}/{skip}/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 200:
+//
+//	{
+//		list: [{
+//			id: uint64
+//			chatId: uint64
+//			senderId: uint64
+//			body: string
+//			createdAt: string
+//		}]
+//	}
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 404 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) GetChatMessagesListHandler(w http.ResponseWriter, r *http.Request) {
+
+	chatId, err := strconv.ParseUint(r.PathValue("chatId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	if _, err = h.chatOf(r.Context(), chatId, customerId); err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	list, err := h.chatClient.GetMessagesList(r.Context(), chatId, uint32(skip))
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"list": list})
+}
+
+// @description -> Send a message to a chat, it's pushed to the open chat streams
+//
+// @route -> /api/v1/chat/send-message/{chatId}/
+//
+// @method -> POST
+//
+// @body:
+//
+//	{
+//		body: string
+//	}
+//
+// @response 201:
+//
+//	{
+//		id: uint64
+//		chatId: uint64
+//		senderId: uint64
+//		body: string
+//		createdAt: string
+//	}
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 404 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) SendChatMessageHandler(w http.ResponseWriter, r *http.Request) {
+
+	chatId, err := strconv.ParseUint(r.PathValue("chatId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto sendMessageDto
+	if err := json.Unmarshal(body, &dto); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	message, err := h.sendMessage(r.Context(), chatId, customerId, dto.Body)
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// @description -> Stream the new messages of a chat over a WebSocket and send
+// the ones of the customer by it, the access token may be passed by the "token"
+// query parameter
+//
+// @route -> /api/v1/chat/stream/{chatId}/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 101 -> json text messages, the client sends {body: string}:
+//
+//	{
+//		id: uint64
+//		chatId: uint64
+//		senderId: uint64
+//		body: string
+//		createdAt: string
+//	}
+//
+// or {error: string} if a message of the client can't be sent
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 404 {object} ErrorResponse
+//
+// @response 429 {object} ErrorResponse
+//
+// @response 503 {object} ErrorResponse
+func (h *Handler) StreamChatHandler(w http.ResponseWriter, r *http.Request) {
+
+	chatId, err := strconv.ParseUint(r.PathValue("chatId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	if _, err = h.chatOf(r.Context(), chatId, customerId); err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	subscription, err := h.chats.Subscribe(chatId, customerId)
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+	defer subscription.Close()
+
+	h.chats.ServeWebSocket(w, r, subscription, func(ctx context.Context, body string) error {
+		_, err := h.sendMessage(ctx, chatId, customerId, body)
+		return err
+	})
+}
+
+// subjectOf -> the order or the offer a chat is opened about
+func (h *Handler) subjectOf(ctx context.Context, subject string, subjectId uint64) (*ownership.Resource, error) {
+	switch subject {
+	case chat.SubjectOrder:
+		return h.owners.Order(ctx, subjectId)
+	case chat.SubjectOffer:
+		return h.owners.Offer(ctx, subjectId)
+	default:
+		return nil, chat.ValidateSubject(subject)
+	}
+}
+
+// chatOf -> the chat of the customer, who has to be one of its participants
+// and still take part in the order or the offer it's opened about
+func (h *Handler) chatOf(ctx context.Context, chatId, customerId uint64) (*chat.Chat, error) {
+	found, err := h.chatClient.GetChat(ctx, chatId)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(found.Participants, customerId) {
+		return nil, ownership.ErrForbidden
+	}
+
+	resource, err := h.subjectOf(ctx, found.Subject, found.SubjectId)
+	if err == nil {
+		err = resource.RequireParticipant(customerId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// sendMessage -> send the customer's message to the chat and push it to the chat streams,
+// the message is kept even if it can't be pushed, the streams get it with the next list
+func (h *Handler) sendMessage(ctx context.Context, chatId, customerId uint64, body string) (*chat.Message, error) {
+	if _, err := h.chatOf(ctx, chatId, customerId); err != nil {
+		return nil, err
+	}
+
+	message, err := h.chatClient.SendMessage(ctx, chatId, customerId, body)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.chats.Publish(ctx, message); err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to publish the chat message: %w", err))
+	}
+	return message, nil
+}
+
+// writeChatError -> respond to a chat request that failed
+func writeChatError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, chat.ErrInvalidSubject), errors.Is(err, chat.ErrInvalidMessage):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, chat.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, fanout.ErrTooManyStreams):
+		w.WriteHeader(http.StatusTooManyRequests)
+	case errors.Is(err, fanout.ErrClosed):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case status.Code(err) == codes.Unavailable:
+		// the orders or the offers service of the chat subject is down
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		writeOwnershipError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -1,7 +1,13 @@
 package routes
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
 	authPb "github.com/noo8xl/anvil-api/main/auth"
 	blogPb "github.com/noo8xl/anvil-api/main/blog"
@@ -14,7 +20,20 @@ import (
 	// promotionsPb "github.com/noo8xl/anvil-api/main/promotions"
 	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
 
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/bruteforce"
 	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/chat"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/outbox"
+	"github.com/noo8xl/anvil-gateway/ownership"
+	"github.com/noo8xl/anvil-gateway/preferences"
+	"github.com/noo8xl/anvil-gateway/rbac"
+	"github.com/noo8xl/anvil-gateway/realtime"
+	"github.com/noo8xl/anvil-gateway/shutdown"
+	"github.com/noo8xl/anvil-gateway/templates"
+	"github.com/noo8xl/anvil-gateway/tokens"
 )
 
 type Handler struct {
@@ -28,8 +47,25 @@ type Handler struct {
 	notificationsClient notificationsPb.NotificationsServiceClient
 	// promotionsClient    promotionsPb.PromotionsServiceClient
 	cacheService *cache.CacheService
+	sessions     *tokens.Sessions
+	resets       *tokens.PasswordResets
+	resetUrl     string
+	guard        *bruteforce.Guard
+	rbac         *rbac.Authorizer
+	owners       *ownership.Guard
+	auditor      audit.Auditor
+	jobs         *shutdown.Jobs
+	outbox       *outbox.Outbox
+	templates    *templates.Catalog
+	hub          *realtime.Hub
+	preferences  *preferences.Store
+	chatClient   chat.Client
+	chats        *chat.Hub
 }
 
+// ownersCacheTTL -> how long the resolved resource owners are reused
+const ownersCacheTTL = 30 * time.Second
+
 func InitHandler(
 	authClient authPb.AuthServiceClient,
 	profileClient profilePb.ProfileServiceClient,
@@ -40,6 +76,19 @@ func InitHandler(
 	offersClient offersPb.OffersServiceClient,
 	notificationsClient notificationsPb.NotificationsServiceClient,
 	// promotionsClient promotionsPb.PromotionsServiceClient,
+	sessions *tokens.Sessions,
+	resets *tokens.PasswordResets,
+	resetUrl string,
+	guard *bruteforce.Guard,
+	authorizer *rbac.Authorizer,
+	auditor audit.Auditor,
+	jobs *shutdown.Jobs,
+	notifications *outbox.Outbox,
+	catalog *templates.Catalog,
+	hub *realtime.Hub,
+	notificationPreferences *preferences.Store,
+	chatClient chat.Client,
+	chats *chat.Hub,
 ) *Handler {
 	cs := cache.InitCacheService()
 	return &Handler{
@@ -53,9 +102,29 @@ func InitHandler(
 		notificationsClient: notificationsClient,
 		// promotionsClient:    promotionsClient,
 		cacheService: cs,
+		sessions:     sessions,
+		resets:       resets,
+		resetUrl:     resetUrl,
+		guard:        guard,
+		rbac:         authorizer,
+		owners:       ownership.InitGuard(offersClient, ordersClient, reviewsClient, blogClient, notificationsClient, ownersCacheTTL),
+		auditor:      auditor,
+		jobs:         jobs,
+		outbox:       notifications,
+		templates:    catalog,
+		hub:          hub,
+		preferences:  notificationPreferences,
+		chatClient:   chatClient,
+		chats:        chats,
 	}
 }
 
+// Owners -> the resource owners guard, shared with the admin routes
+// so the resources they change are resolved again
+func (h *Handler) Owners() *ownership.Guard {
+	return h.owners
+}
+
 // ############################################################
 // ############################################################
 // ############################################################
@@ -67,3 +136,102 @@ func validateCustomer(headerId uint64, payloadId uint64) error {
 	}
 	return nil
 }
+
+// record -> put a security-sensitive action of the customer to the audit trail
+func (h *Handler) record(r *http.Request, action, target string, err error) {
+	h.auditor.Record(r.Context(), audit.NewEvent(r, action, target, err))
+}
+
+// sameCode -> compare the codes in constant time
+func sameCode(expected, given string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(given)) == 1
+}
+
+// writeAttemptsError -> reject a blocked attempt with 429 or fail with 500
+func writeAttemptsError(w http.ResponseWriter, err error) {
+	var blocked *bruteforce.BlockedError
+	if errors.As(err, &blocked) {
+		w.Header().Set("Retry-After", bruteforce.RetryAfterSeconds(blocked))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(map[string]string{"error": blocked.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// writeOwnershipError -> reject a foreign resource with 403, a missing one with 404 or fail with 500
+func writeOwnershipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ownership.ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, ownership.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// notify -> queue the notification side effects of the request, they are delivered
+// after the response, a failed queueing doesn't fail the request as its primary write is done
+func (h *Handler) notify(r *http.Request, messages ...outbox.Message) {
+	if err := h.outbox.Enqueue(r.Context(), messages...); err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to queue the notifications: %w", err))
+	}
+}
+
+// notifyCustomer -> queue an in-app notification of the customer rendered in their locale
+func (h *Handler) notifyCustomer(r *http.Request, customerId uint64, area, name string, data templates.Data) {
+	rendered, err := h.templates.Render(name, h.recipientLocale(r, customerId), data)
+	if err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to render the %s notification: %w", name, err))
+		return
+	}
+	h.notify(r, outbox.Notification(customerId, rendered.Title, rendered.Text, area))
+}
+
+// notifyEmail -> queue an email to the customer about a notification of the area
+// rendered in their locale
+func (h *Handler) notifyEmail(r *http.Request, customerId uint64, email, area, name string, data templates.Data) {
+	rendered, err := h.templates.Render(name, h.recipientLocale(r, customerId), data)
+	if err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to render the %s email: %w", name, err))
+		return
+	}
+	h.notify(r, outbox.CustomerEmail(customerId, email, rendered.Title, rendered.EmailBody(), area))
+}
+
+// renderEmail -> an email of the template rendered in the locale, sent right away by the caller
+func (h *Handler) renderEmail(email, name, locale string, data templates.Data) (*notificationsPb.SendEmailRequest, error) {
+	rendered, err := h.templates.Render(name, locale, data)
+	if err != nil {
+		return nil, err
+	}
+	return &notificationsPb.SendEmailRequest{
+		Email:   email,
+		Subject: rendered.Title,
+		Body:    rendered.EmailBody(),
+	}, nil
+}
+
+// recipientLocale -> the locale of the customer receiving a notification of the request,
+// the Accept-Language of the request counts only if they're the one making it
+func (h *Handler) recipientLocale(r *http.Request, customerId uint64) string {
+	acceptLanguage := ""
+	if requester, ok := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto); ok && requester.CustomerId == customerId {
+		acceptLanguage = r.Header.Get("Accept-Language")
+	}
+	return h.locale(r.Context(), customerId, acceptLanguage)
+}
+
+// locale -> the locale chosen by the customer in the profile,
+// or the best match of the Accept-Language value if there's none
+func (h *Handler) locale(ctx context.Context, customerId uint64, acceptLanguage string) string {
+	if customerId != 0 {
+		if locale, err := h.cacheService.GetCustomerLocale(ctx, customerId); err == nil && locale != "" {
+			return locale
+		}
+	}
+	return h.templates.Match(acceptLanguage)
+}
@@ -3,13 +3,19 @@ package routes
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
 	authPb "github.com/noo8xl/anvil-api/main/auth"
 	notificationPb "github.com/noo8xl/anvil-api/main/notifications"
+	"github.com/noo8xl/anvil-common/exceptions"
+	"github.com/noo8xl/anvil-gateway/fanout"
 	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/preferences"
 )
 
 // @description -> Get notifications list by customer id
@@ -23,14 +29,15 @@ import (
 // @response 200:
 //
 //		{
-//		    List: {
-//		    Id: uint64
-//		    CustomerId: uint64
-//		    Area: string
-//	    	Title: string
-//	    	Body: string
-//	    	CreatedAt: string
-//	    }
+//		    list: [{
+//		    id: uint64
+//		    customerId: uint64
+//		    area: string
+//	    	title: string
+//	    	body: string
+//	    	createdAt: string
+//	    	read: bool
+//	    }]
 //	   }
 //
 // @response 400 {object} ErrorResponse
@@ -43,31 +50,51 @@ import (
 func (h *Handler) GetNotificationsListHandler(w http.ResponseWriter, r *http.Request) {
 
 	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
-	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 64)
+	skip, err := strconv.ParseUint(r.PathValue("skip"), 10, 32)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	response, err := h.notificationsClient.GetNotificationsList(context.Background(), &notificationPb.GetNotificationsListRequest{
-		CustomerId: customerId,
-		Skip:       uint32(skip),
-	})
-	if err != nil {
-		if strings.Contains(err.Error(), "customer not found") {
-			w.WriteHeader(http.StatusNotFound)
+	response, err := h.cacheService.GetNotificationsList(r.Context(), customerId, uint32(skip))
+	if err != nil || response == nil {
+		response, err = h.notificationsClient.GetNotificationsList(r.Context(), &notificationPb.GetNotificationsListRequest{
+			CustomerId: customerId,
+			Skip:       uint32(skip),
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "customer not found") {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
+		h.cacheService.SetNotificationsList(r.Context(), customerId, uint32(skip), response)
+	}
 
+	ids := make([]uint64, 0, len(response.List))
+	for _, notification := range response.List {
+		ids = append(ids, notification.Id)
+	}
+	read, err := h.cacheService.GetReadNotifications(r.Context(), customerId, ids...)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
+	list := make([]notificationItem, 0, len(response.List))
+	for _, notification := range response.List {
+		list = append(list, notificationItem{Notification: notification, Read: read[notification.Id]})
+	}
+
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(map[string]any{"list": list})
 }
 
 // @description -> Delete a notification by notificationId
@@ -96,7 +123,17 @@ func (h *Handler) DeleteNotificationHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	_, err = h.notificationsClient.DeleteNotification(context.Background(), &notificationPb.DeleteNotificationRequest{
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	owner, err := h.owners.Notification(r.Context(), notificationId, customerId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	_, err = h.notificationsClient.DeleteNotification(r.Context(), &notificationPb.DeleteNotificationRequest{
 		NotificationId: notificationId,
 	})
 	if err != nil {
@@ -104,6 +141,10 @@ func (h *Handler) DeleteNotificationHandler(w http.ResponseWriter, r *http.Reque
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	h.owners.ForgetNotification(notificationId, customerId)
+	if err = h.cacheService.ForgetNotification(r.Context(), customerId, notificationId); err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to drop the read state of a notification: %w", err))
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -131,7 +172,7 @@ func (h *Handler) ClearNotificationsHandler(w http.ResponseWriter, r *http.Reque
 
 	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
 
-	_, err := h.notificationsClient.ClearNotifications(context.Background(), &notificationPb.ClearNotificationsRequest{
+	_, err := h.notificationsClient.ClearNotifications(r.Context(), &notificationPb.ClearNotificationsRequest{
 		CustomerId: customerId,
 	})
 	if err != nil {
@@ -145,6 +186,450 @@ func (h *Handler) ClearNotificationsHandler(w http.ResponseWriter, r *http.Reque
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	if err = h.cacheService.ResetNotifications(r.Context(), customerId); err != nil {
+		exceptions.HandleAnException(fmt.Errorf("failed to reset the read state of the notifications: %w", err))
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// @description -> Mark a notification of the customer read
+//
+// @route -> /api/v1/notifications/mark-as-read/{notificationId}/
+//
+// @method -> PATCH
+//
+// @body -> an empty one
+//
+// @response 200:
+//
+//	{
+//		unread: int64
+//	}
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 404 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+
+	notificationId, err := strconv.ParseUint(r.PathValue("notificationId"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	owner, err := h.owners.Notification(r.Context(), notificationId, customerId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	unread, err := h.cacheService.MarkNotificationsRead(r.Context(), customerId, notificationId)
+	if err == nil && unread < 0 {
+		unread, err = h.unreadNotifications(r.Context(), customerId)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"unread": unread})
+}
+
+// @description -> Mark every notification of the customer read
+//
+// @route -> /api/v1/notifications/mark-all-as-read/
+//
+// @method -> PATCH
+//
+// @body -> an empty one
+//
+// @response 200:
+//
+//	{
+//		unread: int64 // 0
+//	}
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 403 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+
+	ids, err := h.listNotificationIds(r.Context(), customerId)
+	if err == nil {
+		err = h.cacheService.MarkAllNotificationsRead(r.Context(), customerId, ids...)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"unread": 0})
+}
+
+// @description -> Get the number of the unread notifications of the customer,
+// e.g. for a badge, it's served from the cache
+//
+// @route -> /api/v1/notifications/get-unread-count/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 200:
+//
+//	{
+//		unread: int64
+//	}
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) GetUnreadNotificationsCountHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+
+	unread, err := h.unreadNotifications(r.Context(), customerId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"unread": unread})
+}
+
+// @description -> Stream the new notifications of the customer as server-sent events,
+// the access token may be passed by the "token" query parameter
+//
+// @route -> /api/v1/notifications/stream/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 200 -> text/event-stream of the "notification" events:
+//
+//	id: string
+//	event: notification
+//	data: {
+//		id: string
+//		title: string
+//		body: string
+//		area: string
+//		createdAt: string
+//	}
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 429 {object} ErrorResponse
+//
+// @response 503 {object} ErrorResponse
+func (h *Handler) StreamNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	subscription, err := h.hub.Subscribe(customerId)
+	if err != nil {
+		writeStreamError(w, err)
+		return
+	}
+	defer subscription.Close()
+
+	h.hub.ServeSSE(w, r, subscription)
+}
+
+// @description -> Stream the new notifications of the customer over a WebSocket,
+// the access token may be passed by the "token" query parameter
+//
+// @route -> /api/v1/notifications/ws/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 101 -> json text messages:
+//
+//	{
+//		id: string
+//		title: string
+//		body: string
+//		area: string
+//		createdAt: string
+//	}
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 429 {object} ErrorResponse
+//
+// @response 503 {object} ErrorResponse
+func (h *Handler) StreamNotificationsWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	subscription, err := h.hub.Subscribe(customerId)
+	if err != nil {
+		writeStreamError(w, err)
+		return
+	}
+	defer subscription.Close()
+
+	h.hub.ServeWebSocket(w, r, subscription)
+}
+
+// writeStreamError -> respond to a stream that can't be opened
+func writeStreamError(w http.ResponseWriter, err error) {
+	code := http.StatusServiceUnavailable
+	if errors.Is(err, fanout.ErrTooManyStreams) {
+		code = http.StatusTooManyRequests
+	}
+
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// @description -> Get the notification preferences of the customer,
+// the areas of the gateway notifications are listed even if not configured
+//
+// @route -> /api/v1/notifications/preferences/
+//
+// @method -> GET
+//
+// @body -> an empty one
+//
+// @response 200:
+//
+//	{
+//		areas: {
+//			[area: string]: {
+//				inApp: bool
+//				email: bool
+//				push: bool
+//				mutedUntil?: string // RFC 3339, every channel of the area is off until it
+//			}
+//		}
+//	}
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	settings, err := h.preferences.Get(r.Context(), customerId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings.WithDefaults())
+}
+
+// @description -> Replace the notification preferences of the customer,
+// a missing area or channel is on, the security notifications can't be muted
+//
+// @route -> /api/v1/notifications/preferences/
+//
+// @method -> PUT
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		areas: {
+//			[area: string]: {
+//				inApp: bool
+//				email: bool
+//				push: bool
+//				mutedUntil?: string // RFC 3339
+//			}
+//		}
+//	}
+//
+// @response 200 -> the saved preferences, as the GET response
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) SetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto preferences.Preferences
+	if err = json.Unmarshal(body, &dto); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.preferences.Set(r.Context(), customerId, dto)
+	if err != nil {
+		writePreferencesError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings.WithDefaults())
+}
+
+// @description -> Set the channels of a single notification area, e.g. mute the emails
+// of the orders or every channel of them for a while
+//
+// @route -> /api/v1/notifications/preferences/{area}/
+//
+// @method -> PUT
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		inApp: bool // a missing channel is on
+//		email: bool
+//		push: bool
+//		mutedUntil?: string // RFC 3339
+//	}
+//
+// @response 200 -> the saved preferences, as the GET response
+//
+// @response 400 {object} ErrorResponse
+//
+// @response 401 {object} ErrorResponse
+//
+// @response 500 {object} ErrorResponse
+func (h *Handler) SetAreaPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto preferences.Setting
+	if err = json.Unmarshal(body, &dto); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.preferences.SetArea(r.Context(), customerId, r.PathValue("area"), dto)
+	if err != nil {
+		writePreferencesError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings.WithDefaults())
+}
+
+// writePreferencesError -> respond to the preferences that can't be saved
+func writePreferencesError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if errors.Is(err, preferences.ErrInvalidArea) {
+		code = http.StatusBadRequest
+	}
+
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// maxListPages -> upper bound of the list pages read to find every notification of the customer
+const maxListPages = 20
+
+// notificationItem -> a notification of the list with its read state
+type notificationItem struct {
+	*notificationPb.Notification
+	Read bool `json:"read"`
+}
+
+// unreadNotifications -> the unread counter of the customer,
+// an unknown one is recounted from the list and the read state
+// of the notifications no longer listed is dropped
+func (h *Handler) unreadNotifications(ctx context.Context, customerId uint64) (int64, error) {
+	unread, known, err := h.cacheService.GetUnreadNotifications(ctx, customerId)
+	if err != nil || known {
+		return unread, err
+	}
+
+	ids, err := h.listNotificationIds(ctx, customerId)
+	if err != nil {
+		return 0, err
+	}
+	if err = h.cacheService.PruneReadNotifications(ctx, customerId, ids...); err != nil {
+		return 0, err
+	}
+	read, err := h.cacheService.GetReadNotifications(ctx, customerId, ids...)
+	if err != nil {
+		return 0, err
+	}
+
+	unread = 0
+	for _, id := range ids {
+		if !read[id] {
+			unread++
+		}
+	}
+	return h.cacheService.SetUnreadNotifications(ctx, customerId, unread)
+}
+
+// listNotificationIds -> the ids of every notification of the customer
+func (h *Handler) listNotificationIds(ctx context.Context, customerId uint64) ([]uint64, error) {
+	ids := []uint64{}
+
+	var skip uint32
+	for range maxListPages {
+		list, err := h.notificationsClient.GetNotificationsList(ctx, &notificationPb.GetNotificationsListRequest{
+			CustomerId: customerId,
+			Skip:       skip,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(list.List) == 0 {
+			break
+		}
+
+		for _, notification := range list.List {
+			ids = append(ids, notification.Id)
+		}
+		skip += uint32(len(list.List))
+	}
+	return ids, nil
+}
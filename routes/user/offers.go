@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -9,10 +8,10 @@ import (
 	"strings"
 
 	authPb "github.com/noo8xl/anvil-api/main/auth"
-	notificationPb "github.com/noo8xl/anvil-api/main/notifications"
 	offersPb "github.com/noo8xl/anvil-api/main/offers"
 	profilePb "github.com/noo8xl/anvil-api/main/profile"
 	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/templates"
 )
 
 // @description -> Create a new offer
@@ -69,7 +68,7 @@ func (h *Handler) CreateOfferHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.offersClient.CreateOffer(context.Background(), dto)
+	_, err = h.offersClient.CreateOffer(r.Context(), dto)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -108,6 +107,8 @@ func (h *Handler) CreateOfferHandler(w http.ResponseWriter, r *http.Request) {
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) UpdateOfferHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -133,7 +134,16 @@ func (h *Handler) UpdateOfferHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	offer, err := h.cacheService.GetOfferDetails(dto.OfferId)
+	owner, err := h.owners.Offer(r.Context(), dto.OfferId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	offer, err := h.cacheService.GetOfferDetails(r.Context(), dto.OfferId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -141,15 +151,16 @@ func (h *Handler) UpdateOfferHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if offer != nil {
-		h.cacheService.ClearOfferDetails(dto.OfferId)
+		h.cacheService.ClearOfferDetails(r.Context(), dto.OfferId)
 	}
 
-	_, err = h.offersClient.UpdateOffer(context.Background(), dto)
+	_, err = h.offersClient.UpdateOffer(r.Context(), dto)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	h.owners.ForgetOffer(dto.OfferId)
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -203,7 +214,7 @@ func (h *Handler) GetOfferDetailsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	offer, err := h.cacheService.GetOfferDetails(offerId)
+	offer, err := h.cacheService.GetOfferDetails(r.Context(), offerId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -221,14 +232,14 @@ func (h *Handler) GetOfferDetailsHandler(w http.ResponseWriter, r *http.Request)
 		OfferId: offerId,
 	}
 
-	offer, err = h.offersClient.GetOfferDetails(context.Background(), payload)
+	offer, err = h.offersClient.GetOfferDetails(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	h.cacheService.SetOfferDetails(offerId, offer)
+	h.cacheService.SetOfferDetails(r.Context(), offerId, offer)
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
@@ -298,7 +309,7 @@ func (h *Handler) GetOffersListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filter.CustomerId = customerId
-	offers, err := h.offersClient.GetOffersList(context.Background(), filter)
+	offers, err := h.offersClient.GetOffersList(r.Context(), filter)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -367,7 +378,7 @@ func (h *Handler) GetMyOffersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	offers, err := h.offersClient.GetMyOffers(context.Background(), filter)
+	offers, err := h.offersClient.GetMyOffers(r.Context(), filter)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -395,6 +406,8 @@ func (h *Handler) GetMyOffersHandler(w http.ResponseWriter, r *http.Request) {
 //
 // @response 403 {object} ErrorResponse
 //
+// @response 404 {object} ErrorResponse
+//
 // @response 500 {object} ErrorResponse
 func (h *Handler) DeleteOfferHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -405,19 +418,30 @@ func (h *Handler) DeleteOfferHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	owner, err := h.owners.Offer(r.Context(), offerId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
 	payload := &offersPb.DeleteOfferRequest{
 		OfferId: offerId,
 	}
 
-	_, err = h.offersClient.DeleteOffer(context.Background(), payload)
+	_, err = h.offersClient.DeleteOffer(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	h.cacheService.ClearOfferDetails(offerId)
-	h.cacheService.ClearApplicantsList(offerId)
+	h.cacheService.ClearOfferDetails(r.Context(), offerId)
+	h.cacheService.ClearApplicantsList(r.Context(), offerId)
+	h.owners.ForgetOffer(offerId)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -466,7 +490,14 @@ func (h *Handler) ApplyToTheOfferHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = h.offersClient.ApplyToTheOffer(context.Background(), dto)
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	if err := validateCustomer(customerId, dto.CustomerId); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	_, err = h.offersClient.ApplyToTheOffer(r.Context(), dto)
 	if err != nil {
 		if strings.Contains(err.Error(), "offer not found") {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Offer not found"})
@@ -482,27 +513,17 @@ func (h *Handler) ApplyToTheOfferHandler(w http.ResponseWriter, r *http.Request)
 		OfferId: dto.OfferId,
 	}
 
-	offer, err := h.offersClient.GetOfferDetails(context.Background(), payload)
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+	h.owners.ForgetOffer(dto.OfferId)
 
-	notificationPayload := &notificationPb.CreateNotificationRequest{
-		CustomerId: offer.PostedBy,
-		Title:      "New Application",
-		Body:       "You have a new application to the offer. To view it, please go to the offers section.",
-		Area:       "offers",
-	}
-
-	_, err = h.notificationsClient.CreateNotification(context.Background(), notificationPayload)
+	offer, err := h.offersClient.GetOfferDetails(r.Context(), payload)
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	h.notifyCustomer(r, offer.PostedBy, "offers", templates.OfferApplied, nil)
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -559,7 +580,7 @@ func (h *Handler) GetApplicantsListHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	applicantsList, err := h.cacheService.GetApplicantsList(offerId)
+	applicantsList, err := h.cacheService.GetApplicantsList(r.Context(), offerId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -578,7 +599,7 @@ func (h *Handler) GetApplicantsListHandler(w http.ResponseWriter, r *http.Reques
 		Skip:    uint32(skip),
 	}
 
-	applicantsList, err = h.offersClient.GetApplicantsList(context.Background(), payload)
+	applicantsList, err = h.offersClient.GetApplicantsList(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -591,7 +612,7 @@ func (h *Handler) GetApplicantsListHandler(w http.ResponseWriter, r *http.Reques
 			CustomerId: applicant.CustomerId,
 		}
 
-		applicantCard, err := h.profileClient.GetPublicProfile(context.Background(), profilePayload)
+		applicantCard, err := h.profileClient.GetPublicProfile(r.Context(), profilePayload)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -600,7 +621,7 @@ func (h *Handler) GetApplicantsListHandler(w http.ResponseWriter, r *http.Reques
 		applicantsList.Applicant[i].Customer = applicantCard
 	}
 
-	h.cacheService.SetApplicantsList(offerId, applicantsList)
+	h.cacheService.SetApplicantsList(r.Context(), offerId, applicantsList)
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
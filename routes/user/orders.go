@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,14 +8,23 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	authPb "github.com/noo8xl/anvil-api/main/auth"
-	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
 	offersPb "github.com/noo8xl/anvil-api/main/offers"
 	ordersPb "github.com/noo8xl/anvil-api/main/orders"
 	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
+	"github.com/noo8xl/anvil-gateway/audit"
 	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/ownership"
+	"github.com/noo8xl/anvil-gateway/rbac"
+	"github.com/noo8xl/anvil-gateway/templates"
+)
+
+// order sides checked by authorizeOrder
+const (
+	orderCustomer = iota
+	orderApplicant
+	orderAnySide
 )
 
 // @description -> Create a new order
@@ -83,6 +91,8 @@ import (
 //
 // @response 403 {object} ErrorResponse {error: err text}
 //
+// @response 404 {object} ErrorResponse {error: err text}
+//
 // @response 500 {object} ErrorResponse {error: err text}
 func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -116,8 +126,24 @@ func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	if err := validateCustomer(customerId, payload.OrderBasics.CustomerId); err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	owner, err := h.owners.Offer(r.Context(), payload.OrderBasics.OfferId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
 	// get title and body from offers by offerId
-	offer, err := h.offersClient.GetOfferDetails(context.Background(), &offersPb.GetOfferDetailsRequest{OfferId: payload.OrderBasics.OfferId})
+	offer, err := h.offersClient.GetOfferDetails(r.Context(), &offersPb.GetOfferDetailsRequest{OfferId: payload.OrderBasics.OfferId})
 	if err != nil {
 		if strings.Contains(err.Error(), "offer not found") {
 			w.WriteHeader(http.StatusNotFound)
@@ -132,25 +158,23 @@ func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	payload.OrderDetails.Title = offer.OfferDetails.Title
 	payload.OrderDetails.Body = offer.OfferDetails.Description
 
-	notificationBody := fmt.Sprintf("You have a new invitation to order: %s. To see details visit your profile.", payload.OrderDetails.Title)
 	type Result struct {
 		service string
 		err     error
 	}
 	var wg sync.WaitGroup
 	creationResults := make(chan Result, 2)
-	notificationResults := make(chan Result, 2)
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		_, err = h.ordersClient.CreateOrder(context.Background(), payload)
+		_, err := h.ordersClient.CreateOrder(r.Context(), payload)
 		creationResults <- Result{service: "orders", err: err}
 	}()
 
 	go func() {
 		defer wg.Done()
-		_, err = h.offersClient.ChangeOfferStatus(context.Background(), &offersPb.ChangeOfferStatusRequest{
+		_, err := h.offersClient.ChangeOfferStatus(r.Context(), &offersPb.ChangeOfferStatusRequest{
 			OfferId: payload.OrderBasics.OfferId,
 			Status:  "PENDING",
 		})
@@ -170,39 +194,9 @@ func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-			CustomerId: payload.OrderBasics.ApplicantId,
-			Title:      "New Order Invitation",
-			Body:       notificationBody,
-			Area:       "orders",
-			CreatedAt:  time.Now().Format(time.RFC3339),
-		})
-		notificationResults <- Result{service: "notifications", err: err}
-	}()
-
-	go func() {
-		defer wg.Done()
-		_, err = h.notificationsClient.SendEmail(context.Background(), &notificationsPb.SendEmailRequest{
-			Email:   customerEmail,
-			Subject: "New Order Invitation",
-			Body:    notificationBody,
-		})
-		notificationResults <- Result{service: "notifications", err: err}
-	}()
-
-	wg.Wait()
-	close(notificationResults)
-
-	for result := range notificationResults {
-		if result.err != nil {
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": fmt.Sprintf("failed to %s: %s", result.service, result.err.Error()),
-			})
-		}
-	}
+	invitation := templates.Data{"OrderTitle": payload.OrderDetails.Title}
+	h.notifyCustomer(r, payload.OrderBasics.ApplicantId, "orders", templates.OrderInvited, invitation)
+	h.notifyEmail(r, customerId, customerEmail, "orders", templates.OrderInvited, invitation)
 
 	w.WriteHeader(http.StatusCreated)
 }
@@ -271,11 +265,11 @@ func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
 //
 // @response 403 {object} ErrorResponse {error: err text}
 //
+// @response 404 {object} ErrorResponse {error: err text}
+//
 // @response 500 {object} ErrorResponse {error: err text}
 func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 
-	role := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).Role
-
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(400)
@@ -308,7 +302,7 @@ func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s, err := h.ordersClient.GetOrderStatus(context.Background(), &ordersPb.GetOrderStatusRequest{OrderId: dto.OrderBasics.OrderId})
+	s, err := h.ordersClient.GetOrderStatus(r.Context(), &ordersPb.GetOrderStatusRequest{OrderId: dto.OrderBasics.OrderId})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -319,14 +313,15 @@ func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 
 	if s.Status == "APPLIED" {
 
-		if role != "ADMIN" && role != "SUPERVISOR" {
+		if !h.rbac.Authorize(r, rbac.OrdersUpdateApplied) {
 			w.WriteHeader(http.StatusForbidden)
 			json.NewEncoder(w).Encode(map[string]string{
 				"error": "forbidden. You are not allowed to update this order",
 			})
 			return
 		} else {
-			_, err = h.ordersClient.UpdateOrder(context.Background(), dto)
+			_, err = h.ordersClient.UpdateOrder(r.Context(), dto)
+			h.record(r, "orders.update", audit.Target("order", dto.OrderBasics.OrderId), err)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				json.NewEncoder(w).Encode(map[string]string{
@@ -335,7 +330,7 @@ func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			err = h.cacheService.ClearOrderDetails(dto.OrderBasics.OrderId)
+			err = h.cacheService.ClearOrderDetails(r.Context(), dto.OrderBasics.OrderId)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				json.NewEncoder(w).Encode(map[string]string{
@@ -349,41 +344,34 @@ func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.authorizeOrder(w, r, dto.OrderBasics, orderCustomer) {
+		return
+	}
+
 	type Result struct {
 		service string
 		err     error
 	}
 
-	notificationBody := fmt.Sprintf("Order %d has been updated. To see details visit your profile.", dto.OrderBasics.OrderId)
 	var wg sync.WaitGroup
-	results := make(chan Result, 3)
-	wg.Add(3)
+	results := make(chan Result, 2)
+	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		_, err = h.ordersClient.UpdateOrder(context.Background(), dto)
+		_, err := h.ordersClient.UpdateOrder(r.Context(), dto)
+		h.record(r, "orders.update", audit.Target("order", dto.OrderBasics.OrderId), err)
 		results <- Result{service: "orders", err: err}
 	}()
 
 	go func() {
 		defer wg.Done()
-		_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-			CustomerId: dto.OrderBasics.ApplicantId,
-			Title:      "Order Updated!",
-			Body:       notificationBody,
-			Area:       "orders",
-			CreatedAt:  time.Now().Format(time.RFC3339),
-		})
-		results <- Result{service: "notifications", err: err}
-	}()
-
-	go func() {
-		defer wg.Done()
-		err = h.cacheService.ClearOrderDetails(dto.OrderBasics.OrderId)
+		err := h.cacheService.ClearOrderDetails(r.Context(), dto.OrderBasics.OrderId)
 		results <- Result{service: "cache", err: err}
 	}()
 
 	wg.Wait()
+	close(results)
 
 	for result := range results {
 		if result.err != nil {
@@ -395,6 +383,8 @@ func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.notifyCustomer(r, dto.OrderBasics.ApplicantId, "orders", templates.OrderUpdated, templates.Data{"OrderId": dto.OrderBasics.OrderId})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -475,7 +465,7 @@ func (h *Handler) GetOrderDetailsHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Try to get from cache first
-	order, err := h.cacheService.GetOrderDetails(orderId)
+	order, err := h.cacheService.GetOrderDetails(r.Context(), orderId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -492,7 +482,7 @@ func (h *Handler) GetOrderDetailsHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Get order details if not in cache
-	order, err = h.ordersClient.GetOrderDetails(context.Background(), &ordersPb.GetOrderDetailsRequest{
+	order, err = h.ordersClient.GetOrderDetails(r.Context(), &ordersPb.GetOrderDetailsRequest{
 		OrderId: orderId,
 	})
 	if err != nil {
@@ -522,15 +512,17 @@ func (h *Handler) GetOrderDetailsHandler(w http.ResponseWriter, r *http.Request)
 
 	go func() {
 		defer wg.Done()
-		customerReviewsStats, err = h.reviewsClient.GetCustomerStats(context.Background(),
+		stats, err := h.reviewsClient.GetCustomerStats(r.Context(),
 			&reviewsPb.GetCustomerStatsRequest{CustomerId: order.OrderBasics.CustomerId})
+		customerReviewsStats = stats
 		results <- Result{service: "reviews", err: err}
 	}()
 
 	go func() {
 		defer wg.Done()
-		applicantReviewsStats, err = h.reviewsClient.GetCustomerStats(context.Background(),
+		stats, err := h.reviewsClient.GetCustomerStats(r.Context(),
 			&reviewsPb.GetCustomerStatsRequest{CustomerId: order.OrderBasics.ApplicantId})
+		applicantReviewsStats = stats
 		results <- Result{service: "reviews", err: err}
 	}()
 
@@ -550,7 +542,7 @@ func (h *Handler) GetOrderDetailsHandler(w http.ResponseWriter, r *http.Request)
 		order.Applicant.Rank = applicantReviewsStats.Rank
 	}
 
-	if err := h.cacheService.SetOrderDetails(orderId, order); err != nil {
+	if err := h.cacheService.SetOrderDetails(r.Context(), orderId, order); err != nil {
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": err.Error(),
 		})
@@ -611,7 +603,7 @@ func (h *Handler) GetOrdersRequestsListByApplicantIdHandler(w http.ResponseWrite
 		Skip:        uint32(skip),
 	}
 
-	orderList, err := h.ordersClient.GetOrdersRequestsListByApplicantId(context.Background(), payload)
+	orderList, err := h.ordersClient.GetOrdersRequestsListByApplicantId(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -675,7 +667,7 @@ func (h *Handler) GetOrdersListByFilterHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	orderList, err := h.cacheService.GetFilteredOrdersList(customerId)
+	orderList, err := h.cacheService.GetFilteredOrdersList(r.Context(), customerId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -701,7 +693,7 @@ func (h *Handler) GetOrdersListByFilterHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	orderList, err = h.ordersClient.GetOrdersListByFilter(context.Background(), payload)
+	orderList, err = h.ordersClient.GetOrdersListByFilter(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -710,7 +702,7 @@ func (h *Handler) GetOrdersListByFilterHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = h.cacheService.SetFilteredOrdersList(customerId, orderList)
+	err = h.cacheService.SetFilteredOrdersList(r.Context(), customerId, orderList)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -740,6 +732,8 @@ func (h *Handler) GetOrdersListByFilterHandler(w http.ResponseWriter, r *http.Re
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -753,14 +747,13 @@ func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
-	role := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).Role
 
 	payload := &ordersPb.DeleteOrderRequest{
 		OrderId:    orderId,
 		CustomerId: customerId,
 	}
 
-	s, err := h.ordersClient.GetOrderStatus(context.Background(), &ordersPb.GetOrderStatusRequest{OrderId: orderId})
+	s, err := h.ordersClient.GetOrderStatus(r.Context(), &ordersPb.GetOrderStatusRequest{OrderId: orderId})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -771,14 +764,15 @@ func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 
 	if s.Status == "APPLIED" {
 
-		if role != "ADMIN" && role != "SUPERVISOR" {
+		if !h.rbac.Authorize(r, rbac.OrdersDeleteApplied) {
 			w.WriteHeader(http.StatusForbidden)
 			json.NewEncoder(w).Encode(map[string]string{
 				"error": "forbidden: you are not allowed to delete this order because it is applied",
 			})
 			return
 		} else {
-			_, err = h.ordersClient.DeleteOrder(context.Background(), payload)
+			_, err = h.ordersClient.DeleteOrder(r.Context(), payload)
+			h.record(r, "orders.delete", audit.Target("order", orderId), err)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				json.NewEncoder(w).Encode(map[string]string{
@@ -791,7 +785,17 @@ func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.ordersClient.DeleteOrder(context.Background(), payload)
+	owner, err := h.owners.Order(r.Context(), orderId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	_, err = h.ordersClient.DeleteOrder(r.Context(), payload)
+	h.record(r, "orders.delete", audit.Target("order", orderId), err)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -799,8 +803,9 @@ func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	h.owners.ForgetOrder(orderId)
 
-	err = h.cacheService.ClearOrderDetails(orderId)
+	err = h.cacheService.ClearOrderDetails(r.Context(), orderId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -839,6 +844,8 @@ func (h *Handler) DeleteOrderHandler(w http.ResponseWriter, r *http.Request) {
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) ApplyToTheOrderHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -861,23 +868,12 @@ func (h *Handler) ApplyToTheOrderHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = h.ordersClient.ApplyToTheOrder(context.Background(), payload)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+	if !h.authorizeOrder(w, r, payload.OrderBasics, orderApplicant) {
 		return
 	}
 
-	notificationBody := "Congratulations! You have been accepted to the order. To see details visit your profile."
-	_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-		CustomerId: payload.OrderBasics.CustomerId,
-		Title:      "New Application",
-		Body:       notificationBody,
-		Area:       "orders",
-		CreatedAt:  time.Now().Format(time.RFC3339),
-	})
+	_, err = h.ordersClient.ApplyToTheOrder(r.Context(), payload)
+	h.record(r, "orders.apply", audit.Target("order", payload.OrderBasics.OrderId), err)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -885,6 +881,9 @@ func (h *Handler) ApplyToTheOrderHandler(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
+	h.owners.ForgetOrder(payload.OrderBasics.OrderId)
+
+	h.notifyCustomer(r, payload.OrderBasics.CustomerId, "orders", templates.OrderApplied, nil)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -915,6 +914,8 @@ func (h *Handler) ApplyToTheOrderHandler(w http.ResponseWriter, r *http.Request)
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) RejectAnOrderHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -937,24 +938,12 @@ func (h *Handler) RejectAnOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.ordersClient.RejectAnOrder(context.Background(), payload)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+	if !h.authorizeOrder(w, r, payload.OrderBasics, orderAnySide) {
 		return
 	}
 
-	notificationBody := "Your order has been rejected. To see details visit your profile."
-
-	_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-		CustomerId: payload.OrderBasics.ApplicantId,
-		Title:      "Order Rejected",
-		Body:       notificationBody,
-		Area:       "orders",
-		CreatedAt:  time.Now().Format(time.RFC3339),
-	})
+	_, err = h.ordersClient.RejectAnOrder(r.Context(), payload)
+	h.record(r, "orders.reject", audit.Target("order", payload.OrderBasics.OrderId), err)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -963,6 +952,8 @@ func (h *Handler) RejectAnOrderHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.notifyCustomer(r, payload.OrderBasics.ApplicantId, "orders", templates.OrderRejected, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -998,6 +989,8 @@ func (h *Handler) RejectAnOrderHandler(w http.ResponseWriter, r *http.Request) {
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) CreateComplianceRequestHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -1020,24 +1013,12 @@ func (h *Handler) CreateComplianceRequestHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	_, err = h.ordersClient.CreateComplianceRequest(context.Background(), payload)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+	if !h.authorizeOrder(w, r, payload.OrderBasics, orderApplicant) {
 		return
 	}
 
-	notificationBody := "You have a new compliance request. To see details visit your profile."
-
-	_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-		CustomerId: payload.OrderBasics.CustomerId,
-		Title:      "New Compliance Request",
-		Body:       notificationBody,
-		Area:       "orders",
-		CreatedAt:  time.Now().Format(time.RFC3339),
-	})
+	_, err = h.ordersClient.CreateComplianceRequest(r.Context(), payload)
+	h.record(r, "orders.compliance.create", audit.Target("order", payload.OrderBasics.OrderId), err)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1046,6 +1027,8 @@ func (h *Handler) CreateComplianceRequestHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.notifyCustomer(r, payload.OrderBasics.CustomerId, "orders", templates.ComplianceRequested, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -1077,6 +1060,8 @@ func (h *Handler) CreateComplianceRequestHandler(w http.ResponseWriter, r *http.
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -1101,7 +1086,11 @@ func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	order, err = h.cacheService.GetOrderDetails(payload.OrderBasics.OrderId)
+	if !h.authorizeOrder(w, r, payload.OrderBasics, orderCustomer) {
+		return
+	}
+
+	order, err = h.cacheService.GetOrderDetails(r.Context(), payload.OrderBasics.OrderId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1111,7 +1100,7 @@ func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if order == nil {
-		order, err = h.ordersClient.GetOrderDetails(context.Background(), &ordersPb.GetOrderDetailsRequest{OrderId: payload.OrderBasics.OrderId})
+		order, err = h.ordersClient.GetOrderDetails(r.Context(), &ordersPb.GetOrderDetailsRequest{OrderId: payload.OrderBasics.OrderId})
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -1162,7 +1151,8 @@ func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err = h.ordersClient.ApproveCompliance(context.Background(), payload)
+	_, err = h.ordersClient.ApproveCompliance(r.Context(), payload)
+	h.record(r, "orders.compliance.approve", audit.Target("order", payload.OrderBasics.OrderId), err)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1171,7 +1161,7 @@ func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = h.cacheService.ClearOrderDetails(payload.OrderBasics.OrderId)
+	err = h.cacheService.ClearOrderDetails(r.Context(), payload.OrderBasics.OrderId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1180,25 +1170,9 @@ func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	notificationBody := "Your compliance request has been approved. To see details visit your profile."
-	if payload.Round == order.PaymentDetails.RoundAmount {
-		notificationBody = "Your compliance request has been approved and order has been completed! To see details visit your profile."
-	}
-
-	_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-		CustomerId: order.OrderBasics.ApplicantId,
-		Title:      "Compliance Request Approved",
-		Body:       notificationBody,
-		Area:       "orders",
-		CreatedAt:  time.Now().Format(time.RFC3339),
+	h.notifyCustomer(r, order.OrderBasics.ApplicantId, "orders", templates.ComplianceApproved, templates.Data{
+		"Completed": payload.Round == order.PaymentDetails.RoundAmount,
 	})
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -1233,6 +1207,8 @@ func (h *Handler) ComplianceApproveHandler(w http.ResponseWriter, r *http.Reques
 //
 // @response 403 {error: err text}
 //
+// @response 404 {error: err text}
+//
 // @response 500 {error: err text}
 func (h *Handler) RejectComplianceHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -1255,24 +1231,12 @@ func (h *Handler) RejectComplianceHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = h.ordersClient.RejectCompliance(context.Background(), payload)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+	if !h.authorizeOrder(w, r, payload.OrderBasics, orderCustomer) {
 		return
 	}
 
-	notificationBody := "Your compliance request has been rejected. To see details visit your profile."
-
-	_, err = h.notificationsClient.CreateNotification(context.Background(), &notificationsPb.CreateNotificationRequest{
-		CustomerId: payload.OrderBasics.ApplicantId,
-		Title:      "Compliance Request Rejected",
-		Body:       notificationBody,
-		Area:       "orders",
-		CreatedAt:  time.Now().Format(time.RFC3339),
-	})
+	_, err = h.ordersClient.RejectCompliance(r.Context(), payload)
+	h.record(r, "orders.compliance.reject", audit.Target("order", payload.OrderBasics.OrderId), err)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1281,7 +1245,9 @@ func (h *Handler) RejectComplianceHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = h.cacheService.ClearComplianceRequestsList(payload.OrderBasics.CustomerId)
+	h.notifyCustomer(r, payload.OrderBasics.ApplicantId, "orders", templates.ComplianceRejected, nil)
+
+	err = h.cacheService.ClearComplianceRequestsList(r.Context(), payload.OrderBasics.CustomerId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1347,7 +1313,7 @@ func (h *Handler) GetComplianceRequestsListHandler(w http.ResponseWriter, r *htt
 
 	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
 
-	list, err := h.cacheService.GetComplianceRequestsList(customerId)
+	list, err := h.cacheService.GetComplianceRequestsList(r.Context(), customerId)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1377,7 +1343,7 @@ func (h *Handler) GetComplianceRequestsListHandler(w http.ResponseWriter, r *htt
 		Skip:       uint32(skip),
 	}
 
-	complianceRequestsList, err := h.ordersClient.GetComplianceRequestsList(context.Background(), payload)
+	complianceRequestsList, err := h.ordersClient.GetComplianceRequestsList(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1386,7 +1352,7 @@ func (h *Handler) GetComplianceRequestsListHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	if err := h.cacheService.SetComplianceRequestsList(customerId, complianceRequestsList); err != nil {
+	if err := h.cacheService.SetComplianceRequestsList(r.Context(), customerId, complianceRequestsList); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": err.Error(),
@@ -1398,3 +1364,35 @@ func (h *Handler) GetComplianceRequestsListHandler(w http.ResponseWriter, r *htt
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(complianceRequestsList)
 }
+
+// authorizeOrder -> check that the caller takes the given side of the order
+// and the basics of the payload name the same customers as the order itself,
+// so the notifications can't be sent on behalf of a foreign order
+func (h *Handler) authorizeOrder(w http.ResponseWriter, r *http.Request, basics *ordersPb.OrderBasics, side int) bool {
+	if basics == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "order basics are required"})
+		return false
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+
+	order, err := h.owners.Order(r.Context(), basics.OrderId)
+	if err == nil {
+		switch {
+		case !order.IsOwner(basics.CustomerId) || !order.IsParticipant(basics.ApplicantId) || basics.ApplicantId == basics.CustomerId:
+			err = ownership.ErrForbidden
+		case side == orderCustomer:
+			err = order.RequireOwner(customerId)
+		case side == orderApplicant && customerId != basics.ApplicantId:
+			err = ownership.ErrForbidden
+		case side == orderAnySide:
+			err = order.RequireParticipant(customerId)
+		}
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return false
+	}
+	return true
+}
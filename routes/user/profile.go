@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -39,7 +38,7 @@ func (h *Handler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 		Name:     r.PathValue("name"),
 		Password: r.PathValue("password"),
 	}
-	_, err := h.profileClient.CreateCustomer(context.Background(), dto)
+	_, err := h.profileClient.CreateCustomer(r.Context(), dto)
 	if err != nil {
 		if err.Error() == "customer already exists" {
 			w.WriteHeader(400)
@@ -112,7 +111,7 @@ func (h *Handler) FillProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.profileClient.FillProfile(context.Background(), dto)
+	_, err = h.profileClient.FillProfile(r.Context(), dto)
 	if err != nil {
 		if strings.Contains(err.Error(), "customer not found") {
 			w.WriteHeader(404)
@@ -186,7 +185,7 @@ func (h *Handler) UpdateCustomerProfileHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	customer, err := h.profileClient.UpdateCustomerProfile(context.Background(), dto)
+	customer, err := h.profileClient.UpdateCustomerProfile(r.Context(), dto)
 	if err != nil {
 		if strings.Contains(err.Error(), "customer not found") {
 			w.WriteHeader(404)
@@ -198,9 +197,9 @@ func (h *Handler) UpdateCustomerProfileHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	h.cacheService.ClearPublicProfile(dto.Base.CustomerId)
-	h.cacheService.ClearCustomerProfile(dto.Base.CustomerId)
-	h.cacheService.SetCustomerProfile(dto.Base.CustomerId, customer)
+	h.cacheService.ClearPublicProfile(r.Context(), dto.Base.CustomerId)
+	h.cacheService.ClearCustomerProfile(r.Context(), dto.Base.CustomerId)
+	h.cacheService.SetCustomerProfile(r.Context(), dto.Base.CustomerId, customer)
 
 	w.WriteHeader(200)
 }
@@ -292,7 +291,7 @@ func (h *Handler) GetCustomerProfileHandler(w http.ResponseWriter, r *http.Reque
 	var wg sync.WaitGroup
 	wg.Add(3)
 
-	response, err := h.cacheService.GetCustomerProfile(customerId)
+	response, err := h.cacheService.GetCustomerProfile(r.Context(), customerId)
 	if err != nil {
 		w.WriteHeader(500)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -311,7 +310,7 @@ func (h *Handler) GetCustomerProfileHandler(w http.ResponseWriter, r *http.Reque
 
 	go func() {
 		defer wg.Done()
-		orderStats, err := h.ordersClient.GetCustomerStats(context.Background(), &ordersPb.GetCustomerStatsRequest{
+		orderStats, err := h.ordersClient.GetCustomerStats(r.Context(), &ordersPb.GetCustomerStatsRequest{
 			CustomerId: customerId,
 		})
 		results <- Result{orderStats: orderStats, err: err}
@@ -319,7 +318,7 @@ func (h *Handler) GetCustomerProfileHandler(w http.ResponseWriter, r *http.Reque
 
 	go func() {
 		defer wg.Done()
-		reviewsStats, err := h.reviewsClient.GetCustomerStats(context.Background(), &reviewsPb.GetCustomerStatsRequest{
+		reviewsStats, err := h.reviewsClient.GetCustomerStats(r.Context(), &reviewsPb.GetCustomerStatsRequest{
 			CustomerId: customerId,
 		})
 		results <- Result{reviewsStats: reviewsStats, err: err}
@@ -327,7 +326,7 @@ func (h *Handler) GetCustomerProfileHandler(w http.ResponseWriter, r *http.Reque
 
 	go func() {
 		defer wg.Done()
-		customer, err := h.profileClient.GetCustomerProfile(context.Background(), &profilePb.GetCustomerProfileRequest{
+		customer, err := h.profileClient.GetCustomerProfile(r.Context(), &profilePb.GetCustomerProfileRequest{
 			CustomerId: customerId,
 		})
 		results <- Result{customer: customer, err: err}
@@ -335,7 +334,7 @@ func (h *Handler) GetCustomerProfileHandler(w http.ResponseWriter, r *http.Reque
 
 	// go func() {
 	// 	defer wg.Done()
-	// 	kyc, err := h.profileClient.GetCustomerKycProfile(context.Background(), &profilePb.GetCustomerKycRequest{
+	// 	kyc, err := h.profileClient.GetCustomerKycProfile(r.Context(), &profilePb.GetCustomerKycRequest{
 	// 		CustomerId: customerId,
 	// 	})
 	// 	results <- Result{customerKyc: kyc, err: err}
@@ -374,7 +373,7 @@ func (h *Handler) GetCustomerProfileHandler(w http.ResponseWriter, r *http.Reque
 		Reviews: reviewsStats,
 	}
 
-	if err := h.cacheService.SetCustomerProfile(customerId, response); err != nil {
+	if err := h.cacheService.SetCustomerProfile(r.Context(), customerId, response); err != nil {
 		w.WriteHeader(500)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -441,7 +440,7 @@ func (h *Handler) GetPublicProfileHandler(w http.ResponseWriter, r *http.Request
 	var wg sync.WaitGroup
 	wg.Add(3)
 
-	response, err := h.cacheService.GetPublicProfile(customerId)
+	response, err := h.cacheService.GetPublicProfile(r.Context(), customerId)
 	if err != nil {
 		if err.Error() != "redis: nil" {
 			w.WriteHeader(500)
@@ -462,7 +461,7 @@ func (h *Handler) GetPublicProfileHandler(w http.ResponseWriter, r *http.Request
 
 	go func() {
 		defer wg.Done()
-		customer, err := h.profileClient.GetPublicProfile(context.Background(), &profilePb.GetPublicProfileRequest{
+		customer, err := h.profileClient.GetPublicProfile(r.Context(), &profilePb.GetPublicProfileRequest{
 			CustomerId: customerId,
 		})
 		results <- Result{customer: customer, err: err}
@@ -470,7 +469,7 @@ func (h *Handler) GetPublicProfileHandler(w http.ResponseWriter, r *http.Request
 
 	go func() {
 		defer wg.Done()
-		stats, err := h.ordersClient.GetCustomerStats(context.Background(), &ordersPb.GetCustomerStatsRequest{
+		stats, err := h.ordersClient.GetCustomerStats(r.Context(), &ordersPb.GetCustomerStatsRequest{
 			CustomerId: customerId,
 		})
 		results <- Result{stats: stats, err: err}
@@ -478,7 +477,7 @@ func (h *Handler) GetPublicProfileHandler(w http.ResponseWriter, r *http.Request
 
 	go func() {
 		defer wg.Done()
-		reviews, err := h.reviewsClient.GetCustomerStats(context.Background(), &reviewsPb.GetCustomerStatsRequest{
+		reviews, err := h.reviewsClient.GetCustomerStats(r.Context(), &reviewsPb.GetCustomerStatsRequest{
 			CustomerId: customerId,
 		})
 		results <- Result{reviews: reviews, err: err}
@@ -510,7 +509,7 @@ func (h *Handler) GetPublicProfileHandler(w http.ResponseWriter, r *http.Request
 		Reviews:  reviews,
 	}
 
-	h.cacheService.SetPublicProfile(customerId, response)
+	h.cacheService.SetPublicProfile(r.Context(), customerId, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -652,7 +651,7 @@ func (h *Handler) ReportCustomerHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = h.profileClient.ReportCustomer(context.Background(), dto)
+	_, err = h.profileClient.ReportCustomer(r.Context(), dto)
 	if err != nil {
 		if strings.Split(err.Error(), "desc = ")[1] == "customer or reporter not found" {
 			w.WriteHeader(404)
@@ -666,3 +665,68 @@ func (h *Handler) ReportCustomerHandler(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(200)
 }
+
+// @description -> Choose the language of the notifications and emails,
+// without one they are sent in the language of the customer's browser
+//
+// @route -> /api/v1/profile/locale/
+//
+// @method -> PUT
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		locale: string // e.g. "en", "ru-RU"
+//	}
+//
+// @response 200
+//
+//	{
+//		locale: string // the supported locale it's resolved to
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) SetLocaleHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto struct {
+		Locale string `json:"locale"`
+	}
+	if err = json.Unmarshal(body, &dto); err != nil || strings.TrimSpace(dto.Locale) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "locale is required"})
+		return
+	}
+
+	locale, ok := h.templates.Resolve(strings.TrimSpace(dto.Locale))
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "unsupported locale, the supported ones are " + strings.Join(h.templates.Locales(), ", "),
+		})
+		return
+	}
+
+	if err = h.cacheService.SetCustomerLocale(r.Context(), customerId, locale); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"locale": locale})
+}
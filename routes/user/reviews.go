@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -72,7 +71,7 @@ func (h *Handler) CreateReviewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validateRelation, err := h.ordersClient.ValidateCustomersRelation(context.Background(), &ordersPb.ValidateCustomersRelationRequest{
+	validateRelation, err := h.ordersClient.ValidateCustomersRelation(r.Context(), &ordersPb.ValidateCustomersRelationRequest{
 		CustomerId:  customerId,
 		ApplicantId: payload.ReviewerId,
 	})
@@ -101,7 +100,7 @@ func (h *Handler) CreateReviewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = h.reviewsClient.CreateReview(context.Background(), payload); err != nil {
+	if _, err = h.reviewsClient.CreateReview(r.Context(), payload); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": err.Error(),
@@ -168,7 +167,18 @@ func (h *Handler) UpdateReviewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = h.reviewsClient.UpdateReview(context.Background(), payload); err != nil {
+	// the reviews are listed only by the reviewed customer, the guard scans
+	// the list of a customer once per its ttl whatever review is asked for
+	owner, err := h.owners.Review(r.Context(), payload.ReviewId, payload.CustomerId)
+	if err == nil {
+		err = owner.RequireOwner(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	if _, err = h.reviewsClient.UpdateReview(r.Context(), payload); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": err.Error(),
@@ -234,7 +244,7 @@ func (h *Handler) GetReviewsListHandler(w http.ResponseWriter, r *http.Request)
 		Skip:       uint32(skip),
 	}
 
-	reviews, err := h.reviewsClient.GetReviewsList(context.Background(), payload)
+	reviews, err := h.reviewsClient.GetReviewsList(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -280,7 +290,17 @@ func (h *Handler) DeleteReviewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.reviewsClient.DeleteReview(context.Background(), &reviewsPb.DeleteReviewRequest{
+	// the review is deleted by the reviewed customer, so it's looked up in their own reviews
+	owner, err := h.owners.Review(r.Context(), reviewId, customerId)
+	if err == nil {
+		err = owner.RequireParticipant(customerId)
+	}
+	if err != nil {
+		writeOwnershipError(w, err)
+		return
+	}
+
+	_, err = h.reviewsClient.DeleteReview(r.Context(), &reviewsPb.DeleteReviewRequest{
 		ReviewId:   reviewId,
 		CustomerId: customerId,
 	})
@@ -290,6 +310,7 @@ func (h *Handler) DeleteReviewHandler(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(map[string]string{
 				"error": "Review not found",
 			})
+			return
 		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -297,6 +318,7 @@ func (h *Handler) DeleteReviewHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	h.owners.ForgetReview(reviewId, customerId)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -357,7 +379,7 @@ func (h *Handler) AddReviewCommentHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if _, err = h.reviewsClient.AddReviewComment(context.Background(), payload); err != nil {
+	if _, err = h.reviewsClient.AddReviewComment(r.Context(), payload); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": err.Error(),
@@ -423,7 +445,7 @@ func (h *Handler) GetReviewCommentsListHandler(w http.ResponseWriter, r *http.Re
 		Skip:     uint32(skip),
 	}
 
-	comments, err := h.reviewsClient.GetReviewCommentsList(context.Background(), payload)
+	comments, err := h.reviewsClient.GetReviewCommentsList(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -432,7 +454,7 @@ func (h *Handler) GetReviewCommentsListHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := h.cacheService.SetReviewCommentsList(reviewId, comments); err != nil {
+	if err := h.cacheService.SetReviewCommentsList(r.Context(), reviewId, comments); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": err.Error(),
@@ -496,7 +518,7 @@ func (h *Handler) SetReviewReactionHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err = h.reviewsClient.SetReviewReaction(context.Background(), payload)
+	_, err = h.reviewsClient.SetReviewReaction(r.Context(), payload)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -1,135 +1,168 @@
 package routes
 
 import (
-	"net/http"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/router"
 )
 
-func (h *Handler) RegisterAuthRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterAuthRoutes(api *router.Version) {
 
-	mux.HandleFunc("POST /api/v1/auth/sign-up/", h.HandleAuthSignUp)
-	mux.HandleFunc("POST /api/v1/auth/sign-in/", h.HandleAuthSignIn)
-	mux.HandleFunc("PATCH /api/v1/auth/forgot-password/{email}/", h.HandleAuthForgotPwd)
+	api.HandleFunc("POST /auth/sign-up/", middlewares.Public().WithRateLimit(middlewares.RateGroupAuth), h.HandleAuthSignUp).Requires("profile")
+	api.HandleFunc("POST /auth/sign-in/", middlewares.Public().WithRateLimit(middlewares.RateGroupAuth), h.HandleAuthSignIn).Requires("auth")
+	api.HandleFunc("POST /auth/forgot-password/", middlewares.Public().WithRateLimit(middlewares.RateGroupEmail), h.HandleAuthForgotPwd)
+	api.HandleFunc("POST /auth/reset-password/", middlewares.Public().WithRateLimit(middlewares.RateGroupAuth), h.HandleAuthResetPwd).Requires("auth", "profile")
+	api.HandleFunc("POST /auth/refresh/", middlewares.Public().WithRateLimit(middlewares.RateGroupAuth), h.HandleAuthRefresh)
+	api.HandleFunc("POST /auth/logout/", middlewares.Authenticated(), h.HandleAuthLogout)
 
 }
 
-func (h *Handler) RegisterBlogRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterBlogRoutes(api *router.Version) {
 
-	mux.HandleFunc("POST /api/v1/blog/create/", h.CreateBlogHandler)
-	mux.HandleFunc("POST /api/v1/blog/update/", h.UpdateBlogHandler)
-	mux.HandleFunc("GET /api/v1/blog/get/{skip}/", h.GetBlogHandler)
-	mux.HandleFunc("DELETE /api/v1/blog/delete/{postId}/", h.DeleteBlogHandler)
-	mux.HandleFunc("PATCH /api/v1/blog/set-reaction/", h.SetReactionHandler)
+	api.HandleFunc("POST /blog/create/", middlewares.Authenticated(), h.CreateBlogHandler).Requires("blog")
+	api.HandleFunc("POST /blog/update/", middlewares.Authenticated(), h.UpdateBlogHandler).Requires("blog")
+	api.HandleFunc("GET /blog/get/{skip}/", middlewares.Authenticated(), h.GetBlogHandler).Requires("blog")
+	api.HandleFunc("DELETE /blog/delete/{postId}/", middlewares.Authenticated(), h.DeleteBlogHandler)
+	api.HandleFunc("PATCH /blog/set-reaction/", middlewares.Authenticated(), h.SetReactionHandler)
 
 }
 
-func (h *Handler) RegisterProfileRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterProfileRoutes(api *router.Version) {
 
 	// profile -> base interactions
-	mux.HandleFunc("GET /api/v1/profile/get/", h.GetCustomerProfileHandler)
-	mux.HandleFunc("POST /api/v1/profile/update/", h.UpdateCustomerProfileHandler)
-	mux.HandleFunc("POST /api/v1/profile/fill/", h.FillProfileHandler)
-	mux.HandleFunc("GET /api/v1/profile/get-public-profile/", h.GetPublicProfileHandler)
-	mux.HandleFunc("POST /api/v1/profile/report/", h.ReportCustomerHandler)
+	api.HandleFunc("GET /profile/get/", middlewares.Authenticated(), h.GetCustomerProfileHandler).Requires("profile", "orders", "reviews")
+	api.HandleFunc("POST /profile/update/", middlewares.Authenticated(), h.UpdateCustomerProfileHandler).Requires("profile")
+	api.HandleFunc("POST /profile/fill/", middlewares.Authenticated(), h.FillProfileHandler).Requires("profile")
+	api.HandleFunc("GET /profile/get-public-profile/", middlewares.Authenticated(), h.GetPublicProfileHandler).Requires("profile", "orders", "reviews")
+	api.HandleFunc("POST /profile/report/", middlewares.Authenticated(), h.ReportCustomerHandler).Requires("profile")
+	api.HandleFunc("PUT /profile/locale/", middlewares.Authenticated(), h.SetLocaleHandler)
 
 	// profile -> kyc area  TODO: not available in the MVP version
-	mux.HandleFunc("POST /api/v1/profile/kyc/create/", h.CreateCustomeKycHandler)
-	mux.HandleFunc("POST /api/v1/profile/kyc/update/", h.UpdateCustomeKycHandler)
-	mux.HandleFunc("GET /api/v1/profile/kyc/get/", h.GetCustomerKycHandler)
+	api.HandleFunc("POST /profile/kyc/create/", middlewares.Authenticated(), h.CreateCustomeKycHandler)
+	api.HandleFunc("POST /profile/kyc/update/", middlewares.Authenticated(), h.UpdateCustomeKycHandler)
+	api.HandleFunc("GET /profile/kyc/get/", middlewares.Authenticated(), h.GetCustomerKycHandler)
 
 	// profile -> security area
-	mux.HandleFunc("PATCH /api/v1/profile/security/change-two-step-status/", h.ChangeTwoStepStatusHandler)
-	mux.HandleFunc("PATCH /api/v1/profile/security/update/change-password/", h.ChangePasswordHandler)
-	mux.HandleFunc("PATCH /api/v1/profile/security/update/change-email/", h.ChangeCustomerEmailHandler)
+	api.HandleFunc("PATCH /profile/security/change-two-step-status/", middlewares.Authenticated().WithRateLimit(middlewares.RateGroupEmail), h.ChangeTwoStepStatusHandler).Requires("profile")
+	api.HandleFunc("PATCH /profile/security/update/change-password/", middlewares.Authenticated(), h.ChangePasswordHandler).Requires("profile")
+	api.HandleFunc("PATCH /profile/security/update/change-email/", middlewares.Authenticated().WithRateLimit(middlewares.RateGroupEmail), h.ChangeCustomerEmailHandler).Requires("profile")
+	api.HandleFunc("GET /profile/security/sessions/", middlewares.Authenticated(), h.GetSessionsListHandler)
+	api.HandleFunc("DELETE /profile/security/sessions/{sessionId}/", middlewares.Authenticated(), h.RevokeSessionHandler)
+	api.HandleFunc("POST /profile/security/totp/enroll/", middlewares.Authenticated(), h.EnrollTOTPHandler)
+	api.HandleFunc("POST /profile/security/totp/confirm/", middlewares.Authenticated(), h.ConfirmTOTPHandler).Requires("profile")
+	api.HandleFunc("POST /profile/security/totp/recovery-codes/", middlewares.Authenticated(), h.RegenerateRecoveryCodesHandler)
 }
 
-func (h *Handler) RegisterOffersRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterOffersRoutes(api *router.Version) {
 
 	// offers -> base interactions
-	mux.HandleFunc("POST /api/v1/offers/create/", h.CreateOfferHandler)
-	mux.HandleFunc("POST /api/v1/offers/update/", h.UpdateOfferHandler)
-	mux.HandleFunc("POST /api/v1/offers/get-offers-list/", h.GetOffersListHandler)
-	mux.HandleFunc("POST /api/v1/offers/get-my-offers/", h.GetMyOffersHandler)
-	mux.HandleFunc("DELETE /api/v1/offers/delete/{offerId}/", h.DeleteOfferHandler)
-	mux.HandleFunc("GET /api/v1/offers/get-offer-details/{offerId}/", h.GetOfferDetailsHandler)
+	api.HandleFunc("POST /offers/create/", middlewares.Authenticated(), h.CreateOfferHandler).Requires("offers")
+	api.HandleFunc("POST /offers/update/", middlewares.Authenticated(), h.UpdateOfferHandler).Requires("offers")
+	api.HandleFunc("POST /offers/get-offers-list/", middlewares.Authenticated(), h.GetOffersListHandler).Requires("offers")
+	api.HandleFunc("POST /offers/get-my-offers/", middlewares.Authenticated(), h.GetMyOffersHandler).Requires("offers")
+	api.HandleFunc("DELETE /offers/delete/{offerId}/", middlewares.Authenticated(), h.DeleteOfferHandler).Requires("offers")
+	api.HandleFunc("GET /offers/get-offer-details/{offerId}/", middlewares.Authenticated(), h.GetOfferDetailsHandler).Requires("offers")
 
 	// offers -> applicants interactions
-	mux.HandleFunc("POST /api/v1/offers/apply/", h.ApplyToTheOfferHandler)
-	mux.HandleFunc("GET /api/v1/offers/get-applicants-list/{offerId}/{skip}/", h.GetApplicantsListHandler)
+	api.HandleFunc("POST /offers/apply/", middlewares.Authenticated(), h.ApplyToTheOfferHandler).Requires("offers")
+	api.HandleFunc("GET /offers/get-applicants-list/{offerId}/{skip}/", middlewares.Authenticated(), h.GetApplicantsListHandler).Requires("offers", "profile")
 }
 
-func (h *Handler) RegisterOrdersRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterOrdersRoutes(api *router.Version) {
 
 	// orders -> base interactions
-	mux.HandleFunc("POST /api/v1/orders/create/", h.CreateOrderHandler)
-	mux.HandleFunc("PUT /api/v1/orders/update/", h.UpdateOrderHandler)
+	api.HandleFunc("POST /orders/create/", middlewares.Authenticated(), h.CreateOrderHandler).Requires("orders", "offers")
+	api.HandleFunc("PUT /orders/update/", middlewares.Authenticated(), h.UpdateOrderHandler).Requires("orders")
 
-	mux.HandleFunc("POST /api/v1/orders/get-orders-list-by-filter/", h.GetOrdersListByFilterHandler)
-	mux.HandleFunc("GET /api/v1/orders/get-order-details/{orderId}/", h.GetOrderDetailsHandler)
-	mux.HandleFunc("DELETE /api/v1/orders/delete/{orderId}/", h.DeleteOrderHandler)
+	api.HandleFunc("POST /orders/get-orders-list-by-filter/", middlewares.Authenticated(), h.GetOrdersListByFilterHandler).Requires("orders")
+	api.HandleFunc("GET /orders/get-order-details/{orderId}/", middlewares.Authenticated(), h.GetOrderDetailsHandler).Requires("orders", "reviews")
+	api.HandleFunc("DELETE /orders/delete/{orderId}/", middlewares.Authenticated(), h.DeleteOrderHandler).Requires("orders")
 
 	// orders -> requests list (as an applicant)
-	mux.HandleFunc("GET /api/v1/orders/get-orders-requests-list/{skip}/", h.GetOrdersRequestsListByApplicantIdHandler)
+	api.HandleFunc("GET /orders/get-orders-requests-list/{skip}/", middlewares.Authenticated(), h.GetOrdersRequestsListByApplicantIdHandler).Requires("orders")
 
 	// orders -> status interactions
-	mux.HandleFunc("POST /api/v1/orders/apply/", h.ApplyToTheOrderHandler)
-	mux.HandleFunc("POST /api/v1/orders/reject/", h.RejectAnOrderHandler)
+	api.HandleFunc("POST /orders/apply/", middlewares.Authenticated(), h.ApplyToTheOrderHandler).Requires("orders")
+	api.HandleFunc("POST /orders/reject/", middlewares.Authenticated(), h.RejectAnOrderHandler).Requires("orders")
 
 	// orders -> compliance interactions
-	mux.HandleFunc("POST /api/v1/orders/compliance/create/", h.CreateComplianceRequestHandler)
-	mux.HandleFunc("POST /api/v1/orders/compliance/approve/", h.ComplianceApproveHandler)
-	mux.HandleFunc("POST /api/v1/orders/compliance/reject/", h.RejectComplianceHandler)
-	mux.HandleFunc("GET /api/v1/orders/compliance/get-list/{skip}/", h.GetComplianceRequestsListHandler)
+	api.HandleFunc("POST /orders/compliance/create/", middlewares.Authenticated(), h.CreateComplianceRequestHandler).Requires("orders")
+	api.HandleFunc("POST /orders/compliance/approve/", middlewares.Authenticated(), h.ComplianceApproveHandler).Requires("orders")
+	api.HandleFunc("POST /orders/compliance/reject/", middlewares.Authenticated(), h.RejectComplianceHandler).Requires("orders")
+	api.HandleFunc("GET /orders/compliance/get-list/{skip}/", middlewares.Authenticated(), h.GetComplianceRequestsListHandler).Requires("orders")
 
 }
 
-func (h *Handler) RegisterReviewsRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterReviewsRoutes(api *router.Version) {
 
 	// reviews -> base interactions
-	mux.HandleFunc("POST /api/v1/reviews/create/", h.CreateReviewHandler)
-	mux.HandleFunc("PUT /api/v1/reviews/update/", h.UpdateReviewHandler)
-	mux.HandleFunc("GET /api/v1/reviews/get-reviews-list/{skip}/", h.GetReviewsListHandler)
-	mux.HandleFunc("DELETE /api/v1/reviews/delete/{reviewId}/", h.DeleteReviewHandler)
+	api.HandleFunc("POST /reviews/create/", middlewares.Authenticated(), h.CreateReviewHandler).Requires("reviews", "orders")
+	api.HandleFunc("PUT /reviews/update/", middlewares.Authenticated(), h.UpdateReviewHandler).Requires("reviews")
+	api.HandleFunc("GET /reviews/get-reviews-list/{skip}/", middlewares.Authenticated(), h.GetReviewsListHandler).Requires("reviews")
+	api.HandleFunc("DELETE /reviews/delete/{reviewId}/", middlewares.Authenticated(), h.DeleteReviewHandler).Requires("reviews")
 
 	// reviews -> comments interactions
-	mux.HandleFunc("POST /api/v1/reviews/add-review-comment/", h.AddReviewCommentHandler)
-	mux.HandleFunc("GET /api/v1/reviews/get-review-comments-list/{reviewId}/{skip}/", h.GetReviewCommentsListHandler)
-	mux.HandleFunc("POST /api/v1/reviews/set-review-reaction/", h.SetReviewReactionHandler)
+	api.HandleFunc("POST /reviews/add-review-comment/", middlewares.Authenticated(), h.AddReviewCommentHandler).Requires("reviews")
+	api.HandleFunc("GET /reviews/get-review-comments-list/{reviewId}/{skip}/", middlewares.Authenticated(), h.GetReviewCommentsListHandler).Requires("reviews")
+	api.HandleFunc("POST /reviews/set-review-reaction/", middlewares.Authenticated(), h.SetReviewReactionHandler).Requires("reviews")
 
 }
 
-func (h *Handler) RegisterNotificationsRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterNotificationsRoutes(api *router.Version) {
 
-	mux.HandleFunc("GET /api/v1/notifications/get-notifications-list/{skip}/", h.GetNotificationsListHandler)
-	mux.HandleFunc("DELETE /api/v1/notifications/delete-notification/{notificationId}/", h.DeleteNotificationHandler)
-	mux.HandleFunc("DELETE /api/v1/notifications/clear-notifications/", h.ClearNotificationsHandler)
+	api.HandleFunc("GET /notifications/get-notifications-list/{skip}/", middlewares.Authenticated(), h.GetNotificationsListHandler).Requires("notifications")
+	api.HandleFunc("DELETE /notifications/delete-notification/{notificationId}/", middlewares.Authenticated(), h.DeleteNotificationHandler).Requires("notifications")
+	api.HandleFunc("DELETE /notifications/clear-notifications/", middlewares.Authenticated(), h.ClearNotificationsHandler).Requires("notifications")
+	api.HandleFunc("PATCH /notifications/mark-as-read/{notificationId}/", middlewares.Authenticated(), h.MarkNotificationReadHandler).Requires("notifications")
+	api.HandleFunc("PATCH /notifications/mark-all-as-read/", middlewares.Authenticated(), h.MarkAllNotificationsReadHandler).Requires("notifications")
+	api.HandleFunc("GET /notifications/get-unread-count/", middlewares.Authenticated(), h.GetUnreadNotificationsCountHandler).Requires("notifications")
+	api.HandleFunc("GET /notifications/preferences/", middlewares.Authenticated(), h.GetNotificationPreferencesHandler)
+	api.HandleFunc("PUT /notifications/preferences/", middlewares.Authenticated(), h.SetNotificationPreferencesHandler)
+	api.HandleFunc("PUT /notifications/preferences/{area}/", middlewares.Authenticated(), h.SetAreaPreferencesHandler)
+
+	// the browsers can't set the Authorization header on an EventSource or a WebSocket
+	api.HandleFunc("GET /notifications/stream/", middlewares.Authenticated().WithQueryToken(), h.StreamNotificationsHandler)
+	if h.hub.AllowsWebSocket() {
+		api.HandleFunc("GET /notifications/ws/", middlewares.Authenticated().WithQueryToken(), h.StreamNotificationsWebSocketHandler)
+	}
 
 }
 
-func (h *Handler) RegisterChatRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterChatRoutes(api *router.Version) {
+
+	// the chats have no backend unless the in-memory one of a development run is used
+	if h.chatClient == nil {
+		return
+	}
+
+	// the service of a chat depends on its subject, so its unavailability is answered
+	// by the handlers instead of the routes requiring both the orders and the offers
+	api.HandleFunc("POST /chat/create-chat/", middlewares.Authenticated(), h.CreateChatHandler)
+	api.HandleFunc("GET /chat/get-chats-list/{customerId}/{skip}/", middlewares.Authenticated(), h.GetChatsListHandler)
+	api.HandleFunc("GET /chat/get-chat-messages-list/{chatId}/{skip}/", middlewares.Authenticated(), h.GetChatMessagesListHandler)
+	api.HandleFunc("POST /chat/send-message/{chatId}/", middlewares.Authenticated(), h.SendChatMessageHandler)
 
-	// mux.Handle("POST /api/v1/chat/create-chat/", middlewares.AuthMiddleware(http.HandlerFunc(h.CreateChatHandler)))
-	// mux.Handle("GET /api/v1/chat/get-chats-list/{customerId}/{skip}/", middlewares.AuthMiddleware(http.HandlerFunc(h.GetChatsListHandler)))
-	// mux.Handle("GET /api/v1/chat/get-chat-messages-list/{chatId}/{skip}/", middlewares.AuthMiddleware(http.HandlerFunc(h.GetChatMessagesListHandler)))
+	// the browsers can't set the Authorization header on a WebSocket
+	api.HandleFunc("GET /chat/stream/{chatId}/", middlewares.Authenticated().WithQueryToken(), h.StreamChatHandler)
 
 }
 
-func (h *Handler) RegisterPromotionsRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterPromotionsRoutes(api *router.Version) {
 
-	// mux.HandleFunc("POST /api/v1/promotions/create/", h.CreatePromotionHandler)
-	// mux.HandleFunc("PUT /api/v1/promotions/update/", h.UpdatePromotionHandler)
-	// mux.HandleFunc("GET /api/v1/promotions/get-promotions-list/{skip}/", h.GetPromotionsListHandler)
+	// api.HandleFunc("POST /promotions/create/", middlewares.Authenticated(), h.CreatePromotionHandler)
+	// api.HandleFunc("PUT /promotions/update/", middlewares.Authenticated(), h.UpdatePromotionHandler)
+	// api.HandleFunc("GET /promotions/get-promotions-list/{skip}/", middlewares.Authenticated(), h.GetPromotionsListHandler)
 
 }
 
-func (h *Handler) RegisterPaymentsRoutes(mux *http.ServeMux) {
+func (h *Handler) RegisterPaymentsRoutes(api *router.Version) {
 
 	// payments -> base interactions
 	// NOTE: not available for the MVP version
 
-	// mux.HandleFunc("POST /api/v1/payments/buy-internal-coins/", h.BuyInternalCoinsHandler)
-	// mux.HandleFunc("POST /api/v1/payments/create/", h.CreatePaymentHandler)
-	// mux.HandleFunc("PUT /api/v1/payments/update/", h.UpdatePaymentHandler)
-	// mux.HandleFunc("GET /api/v1/payments/get-payments-list/{customerId}/{skip}/", h.GetPaymentsListHandler)
-	// mux.HandleFunc("DELETE /api/v1/payments/delete/{paymentId}/", h.DeletePaymentHandler)
+	// api.HandleFunc("POST /payments/buy-internal-coins/", middlewares.Authenticated(), h.BuyInternalCoinsHandler)
+	// api.HandleFunc("POST /payments/create/", middlewares.Authenticated(), h.CreatePaymentHandler)
+	// api.HandleFunc("PUT /payments/update/", middlewares.Authenticated(), h.UpdatePaymentHandler)
+	// api.HandleFunc("GET /payments/get-payments-list/{customerId}/{skip}/", middlewares.Authenticated(), h.GetPaymentsListHandler)
+	// api.HandleFunc("DELETE /payments/delete/{paymentId}/", middlewares.Authenticated(), h.DeletePaymentHandler)
 
 }
@@ -9,11 +9,14 @@ import (
 	"strings"
 
 	authPb "github.com/noo8xl/anvil-api/main/auth"
-	notificationPb "github.com/noo8xl/anvil-api/main/notifications"
 	profilePb "github.com/noo8xl/anvil-api/main/profile"
 	"github.com/noo8xl/anvil-common/exceptions"
 	helpers "github.com/noo8xl/anvil-common/helpers"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/bruteforce"
 	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/otp"
+	"github.com/noo8xl/anvil-gateway/templates"
 )
 
 // @description -> Change customer email
@@ -78,7 +81,8 @@ func (h *Handler) ChangeCustomerEmailHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	_, err = h.profileClient.ChangeCustomerEmail(context.Background(), dto)
+	_, err = h.profileClient.ChangeCustomerEmail(r.Context(), dto)
+	h.record(r, "profile.email.change", audit.Target("customer", customerId), err)
 	if err != nil {
 		if strings.Contains(err.Error(), "error: email is not available to be in use") {
 			w.WriteHeader(400)
@@ -142,7 +146,8 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = h.profileClient.ChangePassword(context.Background(), dto)
+	_, err = h.profileClient.ChangePassword(r.Context(), dto)
+	h.record(r, "profile.password.change", audit.Target("customer", customerId), err)
 	if err != nil {
 		w.WriteHeader(500)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -165,8 +170,13 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 //			IsEnabled: bool
 //			Email: string
 //			Code: string
+//			Method: string // "email" (default) or "totp"
 //	  }
 //
+// the authenticator app is enabled by /profile/security/totp/enroll/ and
+// /profile/security/totp/confirm/; enabling "email" switches back from it and,
+// as disabling it, requires a code of the authenticator app or a recovery code
+//
 // @response 200
 //
 // @response 400 {object} {error: err text}
@@ -175,6 +185,8 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 //
 // @response 403 {object} {error: err text}
 //
+// @response 429 {object} {error: err text} with a Retry-After header
+//
 // @response 500 {object} {error: err text}
 func (h *Handler) ChangeTwoStepStatusHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -189,13 +201,17 @@ func (h *Handler) ChangeTwoStepStatusHandler(w http.ResponseWriter, r *http.Requ
 	}
 	defer r.Body.Close()
 
-	var dto *profilePb.ChangeTwoStepStatusRequest
-	err = json.Unmarshal(body, &dto)
-	if err != nil {
+	var request struct {
+		*profilePb.ChangeTwoStepStatusRequest
+		Method string `json:"method"`
+	}
+	err = json.Unmarshal(body, &request)
+	if err != nil || request.ChangeTwoStepStatusRequest == nil {
 		w.WriteHeader(500)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
 		return
 	}
+	dto := request.ChangeTwoStepStatusRequest
 
 	if err := validateCustomer(customerId, dto.CustomerId); err != nil {
 		w.WriteHeader(http.StatusForbidden)
@@ -210,43 +226,125 @@ func (h *Handler) ChangeTwoStepStatusHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	attemptKey := bruteforce.EmailKey(email)
+	if err = h.guard.Check(r.Context(), bruteforce.TwoStep, attemptKey); err != nil {
+		writeAttemptsError(w, err)
+		return
+	}
+
+	action := "profile.two_step.enable"
 	if !dto.IsEnabled {
-		err = h.disableTwoStepHandler(dto)
-		if err != nil {
-			w.WriteHeader(500)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-			return
-		}
+		action = "profile.two_step.disable"
+		err = h.disableTwoStepHandler(r.Context(), dto, r.Header.Get("Accept-Language"))
 	} else {
-		err = h.enableTwoStepHandler(dto)
-		if err != nil {
-			w.WriteHeader(500)
+		var switched bool
+		switched, err = h.enableTwoStepHandler(r.Context(), dto, request.Method)
+		if switched {
+			action = "profile.two_step.switch"
+		}
+	}
+	// disabling by the emailed code is done in two requests, the first one only sends the code
+	if dto.IsEnabled || dto.Code != "" {
+		h.record(r, action, audit.Target("customer", customerId), err)
+	}
+	if err != nil {
+		if errors.Is(err, errInvalidTwoStepCode) {
+			h.guard.Fail(r.Context(), bruteforce.TwoStep, attemptKey)
+		}
+		if errors.Is(err, errInvalidTwoStepCode) || errors.Is(err, errTwoStepMethod) {
+			w.WriteHeader(400)
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
+		w.WriteHeader(500)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
 	}
 
 	w.WriteHeader(200)
 }
 
-func (h *Handler) enableTwoStepHandler(dto *profilePb.ChangeTwoStepStatusRequest) error {
+// errTwoStepMethod -> the requested two-step method can't be used
+var errTwoStepMethod = errors.New("unknown two-step method. the authenticator app is enabled at /profile/security/totp/enroll/")
+
+// enableTwoStepHandler -> enable the emailed codes, switched reports a switch from
+// the authenticator app, it drops its secret and the recovery codes, so it takes one of them
+func (h *Handler) enableTwoStepHandler(ctx context.Context, dto *profilePb.ChangeTwoStepStatusRequest, method string) (switched bool, err error) {
+
+	switch method {
+	case otp.MethodTOTP:
+		// the authenticator app is enabled by its enrollment confirmation
+		return false, errTwoStepMethod
+	case "", otp.MethodEmail:
+	default:
+		return false, errTwoStepMethod
+	}
+
+	current, err := h.cacheService.GetTwoStepMethod(ctx, dto.CustomerId)
+	if err != nil {
+		return false, err
+	}
+
+	if current == otp.MethodTOTP {
+		// switching from the authenticator app to the emailed codes
+		if dto.Code == "" {
+			return true, errInvalidTwoStepCode
+		}
+		if err = h.verifyTOTPCode(ctx, dto.CustomerId, dto.Code); err != nil {
+			return true, err
+		}
+		if err = h.cacheService.ClearTOTP(ctx, dto.CustomerId); err != nil {
+			return true, err
+		}
+		return true, h.cacheService.SetTwoStepMethod(ctx, dto.CustomerId, otp.MethodEmail)
+	}
 
-	if _, err := h.profileClient.ChangeTwoStepStatus(context.Background(), dto); err != nil {
+	if _, err := h.profileClient.ChangeTwoStepStatus(ctx, dto); err != nil {
 		if strings.Contains(err.Error(), "customer not found") {
-			return errors.New("customer not found")
+			return false, errors.New("customer not found")
 		}
 		if strings.Contains(err.Error(), "customer already has two step enabled") {
-			return errors.New("customer already has two step enabled")
+			return false, errors.New("customer already has two step enabled")
 		}
-		return err
+		return false, err
 	}
-	return nil
+	return false, nil
 }
 
-func (h *Handler) disableTwoStepHandler(dto *profilePb.ChangeTwoStepStatusRequest) error {
+// disableTwoStepHandler -> disable the two-step verification by a code, the emailed code
+// is sent first in the locale of the customer, acceptLanguage is the one of their request
+func (h *Handler) disableTwoStepHandler(ctx context.Context, dto *profilePb.ChangeTwoStepStatusRequest, acceptLanguage string) error {
+
+	method, err := h.cacheService.GetTwoStepMethod(ctx, dto.CustomerId)
+	if err != nil {
+		return err
+	}
+
+	if method == otp.MethodTOTP {
+		if dto.Code == "" {
+			return errInvalidTwoStepCode
+		}
+		if err = h.verifyTOTPCode(ctx, dto.CustomerId, dto.Code); err != nil {
+			return err
+		}
+
+		if _, err = h.profileClient.ChangeTwoStepStatus(ctx, dto); err != nil {
+			if strings.Contains(err.Error(), "customer not found") {
+				return errors.New("customer not found")
+			}
+			return err
+		}
+
+		if err = h.cacheService.ClearTOTP(ctx, dto.CustomerId); err != nil {
+			return err
+		}
+		h.cacheService.ClearPublicProfile(ctx, dto.CustomerId)
+		h.cacheService.ClearCustomerProfile(ctx, dto.CustomerId)
+		return nil
+	}
 
 	if dto.Code != "" {
-		c, err := h.cacheService.Get2FACode(dto.Email)
+		c, err := h.cacheService.Get2FACode(ctx, dto.Email)
 		if err != nil {
 			if err.Error() == "code not found" {
 				return errors.New("code not found")
@@ -254,11 +352,13 @@ func (h *Handler) disableTwoStepHandler(dto *profilePb.ChangeTwoStepStatusReques
 			return err
 		}
 
-		if c != dto.Code {
-			return errors.New("invalid code")
+		if !sameCode(c, dto.Code) {
+			h.guard.FailCode(ctx, dto.Email)
+			return errInvalidTwoStepCode
 		}
+		h.cacheService.Clear2FACode(ctx, dto.Email)
 
-		if _, err := h.profileClient.ChangeTwoStepStatus(context.Background(), dto); err != nil {
+		if _, err := h.profileClient.ChangeTwoStepStatus(ctx, dto); err != nil {
 			if strings.Contains(err.Error(), "customer not found") {
 				return errors.New("customer not found")
 			}
@@ -271,20 +371,20 @@ func (h *Handler) disableTwoStepHandler(dto *profilePb.ChangeTwoStepStatusReques
 	} else {
 
 		code := helpers.GenerateRandomPassword(12)
-		h.cacheService.Set2FACode(dto.Email, code)
+		h.cacheService.Set2FACode(ctx, dto.Email, code)
 
-		_, err := h.notificationsClient.SendEmail(context.Background(), &notificationPb.SendEmailRequest{
-			Email:   dto.Email,
-			Subject: "Two-Step Verification",
-			Body:    code,
-		})
+		locale := h.locale(ctx, dto.CustomerId, acceptLanguage)
+		notificationDto, err := h.renderEmail(dto.Email, templates.TwoStepCode, locale, templates.Data{"Code": code})
+		if err == nil {
+			_, err = h.notificationsClient.SendEmail(ctx, notificationDto)
+		}
 		if err != nil {
 			return err
 		}
 	}
 
-	h.cacheService.ClearPublicProfile(dto.CustomerId)
-	h.cacheService.ClearCustomerProfile(dto.CustomerId)
+	h.cacheService.ClearPublicProfile(ctx, dto.CustomerId)
+	h.cacheService.ClearCustomerProfile(ctx, dto.CustomerId)
 
 	return nil
 }
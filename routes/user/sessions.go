@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/tokens"
+)
+
+// @description -> Rotate the refresh token and get a new access token
+//
+// @route -> /api/v1/auth/refresh/
+//
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		refreshToken: string
+//	}
+//
+// @response 200 {object} tokens.TokenPair
+//
+//	{
+//		sessionId: string
+//		accessToken: string
+//		refreshToken: string
+//		expiresIn: int64
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 403 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) HandleAuthRefresh(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var dto struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err = json.Unmarshal(body, &dto); err != nil || dto.RefreshToken == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "refresh token is required"})
+		return
+	}
+
+	pair, err := h.sessions.Refresh(r.Context(), dto.RefreshToken, tokens.SessionMeta{Ip: audit.ClientIp(r), UserAgent: r.UserAgent()})
+	if err != nil {
+		if errors.Is(err, tokens.ErrInvalidRefreshToken) || errors.Is(err, tokens.ErrRefreshTokenReuse) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, tokens.ErrCustomerBlocked) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pair)
+}
+
+// @description -> Log out of the current session
+//
+// @route -> /api/v1/auth/logout/
+//
+// @method -> POST
+//
+// @response 204
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) HandleAuthLogout(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	sessionId, _ := r.Context().Value(middlewares.SessionKey).(string)
+	token, _ := middlewares.BearerToken(r)
+
+	if err := h.sessions.Logout(r.Context(), customerId, sessionId, token); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @description -> Get the list of the customer active sessions
+//
+// @route -> /api/v1/profile/security/sessions/
+//
+// @method -> GET
+//
+// @response 200 {object} []cache.Session
+//
+//	[{
+//		sessionId: string
+//		customerId: uint64
+//		email: string
+//		ip: string
+//		userAgent: string
+//		createdAt: string
+//		lastUsedAt: string
+//		current: bool
+//	}]
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) GetSessionsListHandler(w http.ResponseWriter, r *http.Request) {
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	sessionId, _ := r.Context().Value(middlewares.SessionKey).(string)
+
+	sessions, err := h.sessions.List(r.Context(), customerId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	type sessionResponse struct {
+		Current bool `json:"current"`
+		*cache.Session
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			Current: session.SessionId == sessionId,
+			Session: session,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @description -> Revoke one of the customer sessions
+//
+// @route -> /api/v1/profile/security/sessions/{sessionId}/
+//
+// @method -> DELETE
+//
+// @response 204
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 404 {object} ErrorResponse {error: err text}
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.PathValue("sessionId") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "session id is required"})
+		return
+	}
+
+	customerId := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto).CustomerId
+	if err := h.sessions.Revoke(r.Context(), customerId, r.PathValue("sessionId")); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
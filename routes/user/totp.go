@@ -0,0 +1,296 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	profilePb "github.com/noo8xl/anvil-api/main/profile"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/bruteforce"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/otp"
+)
+
+// totpIssuer -> the issuer name shown by authenticator apps
+const totpIssuer = "Anvil"
+
+// recoveryCodesCount -> number of recovery codes issued at once
+const recoveryCodesCount = 10
+
+var errInvalidTwoStepCode = errors.New("invalid code")
+
+// @description -> Start an authenticator app enrollment, the secret
+// has to be confirmed by a code within 10 minutes
+//
+// @route -> /api/v1/profile/security/totp/enroll/
+//
+// @method -> POST
+//
+// @response 200
+//
+//	{
+//		secret: string
+//		uri: string // otpauth://totp/...
+//	}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+
+	customer := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto)
+
+	secret, err := otp.GenerateSecret()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err = h.cacheService.SetTOTPSecret(r.Context(), customer.CustomerId, secret, true); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret": secret,
+		"uri":    otp.KeyURI(totpIssuer, customer.Email, secret),
+	})
+}
+
+// @description -> Confirm the authenticator app by a code, switch the two-step
+// verification to TOTP and get the recovery codes (shown only once)
+//
+// @route -> /api/v1/profile/security/totp/confirm/
+//
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		code: string
+//	}
+//
+// @response 200
+//
+//	{
+//		recoveryCodes: []string
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 429 {object} ErrorResponse {error: err text} with a Retry-After header
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+
+	customer := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto)
+
+	code, err := readTwoStepCode(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	attemptKey := bruteforce.EmailKey(customer.Email)
+	if err = h.guard.Check(r.Context(), bruteforce.TwoStep, attemptKey); err != nil {
+		writeAttemptsError(w, err)
+		return
+	}
+
+	secret, err := h.cacheService.GetTOTPSecret(r.Context(), customer.CustomerId, true)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "enrollment not found or expired"})
+		return
+	}
+
+	step, ok := otp.Validate(secret, code, time.Now(), 0)
+	if !ok {
+		h.guard.Fail(r.Context(), bruteforce.TwoStep, attemptKey)
+		h.record(r, "profile.totp.enable", audit.Target("customer", customer.CustomerId), errInvalidTwoStepCode)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": errInvalidTwoStepCode.Error()})
+		return
+	}
+
+	_, err = h.profileClient.ChangeTwoStepStatus(r.Context(), &profilePb.ChangeTwoStepStatusRequest{
+		CustomerId: customer.CustomerId,
+		IsEnabled:  true,
+		Email:      customer.Email,
+		Code:       code,
+	})
+	// switching from the emailed codes keeps the two-step enabled
+	if err != nil && !strings.Contains(err.Error(), "customer already has two step enabled") {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err = h.cacheService.SetTOTPSecret(r.Context(), customer.CustomerId, secret, false); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	h.cacheService.SetTOTPLastStep(r.Context(), customer.CustomerId, step)
+	h.cacheService.SetTwoStepMethod(r.Context(), customer.CustomerId, otp.MethodTOTP)
+
+	codes, err := h.issueRecoveryCodes(r.Context(), customer.CustomerId)
+	h.record(r, "profile.totp.enable", audit.Target("customer", customer.CustomerId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.cacheService.ClearPublicProfile(r.Context(), customer.CustomerId)
+	h.cacheService.ClearCustomerProfile(r.Context(), customer.CustomerId)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"recoveryCodes": codes})
+}
+
+// @description -> Replace the recovery codes, the previous ones stop working
+//
+// @route -> /api/v1/profile/security/totp/recovery-codes/
+//
+// @method -> POST
+//
+// @body -> body should follow the following structure:
+//
+//	{
+//		code: string // a code from the authenticator app
+//	}
+//
+// @response 200
+//
+//	{
+//		recoveryCodes: []string
+//	}
+//
+// @response 400 {object} ErrorResponse {error: err text}
+//
+// @response 401 {object} ErrorResponse {error: err text}
+//
+// @response 429 {object} ErrorResponse {error: err text} with a Retry-After header
+//
+// @response 500 {object} ErrorResponse {error: err text}
+func (h *Handler) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+
+	customer := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto)
+
+	code, err := readTwoStepCode(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if otp.IsRecoveryCode(code) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "a code from the authenticator app is required"})
+		return
+	}
+
+	attemptKey := bruteforce.EmailKey(customer.Email)
+	if err = h.guard.Check(r.Context(), bruteforce.TwoStep, attemptKey); err != nil {
+		writeAttemptsError(w, err)
+		return
+	}
+
+	if err = h.verifyTOTPCode(r.Context(), customer.CustomerId, code); err != nil {
+		h.guard.Fail(r.Context(), bruteforce.TwoStep, attemptKey)
+		h.record(r, "profile.recovery_codes.regenerate", audit.Target("customer", customer.CustomerId), err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.issueRecoveryCodes(r.Context(), customer.CustomerId)
+	h.record(r, "profile.recovery_codes.regenerate", audit.Target("customer", customer.CustomerId), err)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"recoveryCodes": codes})
+}
+
+// ############################################################
+
+// verifyTOTPCode -> check an authenticator app code or consume a recovery code
+func (h *Handler) verifyTOTPCode(ctx context.Context, customerId uint64, code string) error {
+	if otp.IsRecoveryCode(code) {
+		used, err := h.cacheService.UseRecoveryCode(ctx, customerId, otp.HashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidTwoStepCode
+		}
+		return nil
+	}
+
+	secret, err := h.cacheService.GetTOTPSecret(ctx, customerId, false)
+	if err != nil {
+		return errors.New("authenticator app is not enrolled")
+	}
+
+	lastStep, err := h.cacheService.GetTOTPLastStep(ctx, customerId)
+	if err != nil {
+		return err
+	}
+
+	step, ok := otp.Validate(secret, code, time.Now(), lastStep)
+	if !ok {
+		return errInvalidTwoStepCode
+	}
+	return h.cacheService.SetTOTPLastStep(ctx, customerId, step)
+}
+
+func (h *Handler) issueRecoveryCodes(ctx context.Context, customerId uint64) ([]string, error) {
+	codes := otp.GenerateRecoveryCodes(recoveryCodesCount)
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, otp.HashRecoveryCode(code))
+	}
+
+	if err := h.cacheService.SetRecoveryCodes(ctx, customerId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// readTwoStepCode -> get a required code from the {code: string} body
+func readTwoStepCode(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	var dto struct {
+		Code string `json:"code"`
+	}
+	if err = json.Unmarshal(body, &dto); err != nil || strings.TrimSpace(dto.Code) == "" {
+		return "", errors.New("code is required")
+	}
+	return strings.TrimSpace(dto.Code), nil
+}
@@ -0,0 +1,119 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Phase -> a single step of the shutdown, run with its own timeout
+type Phase struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Coordinator -> run the shutdown phases in the order they were added,
+// a failed or timed out phase is logged and the next one still runs
+type Coordinator struct {
+	logger *zap.Logger
+	phases []Phase
+}
+
+func InitCoordinator(logger *zap.Logger) *Coordinator {
+	return &Coordinator{logger: logger}
+}
+
+// Add -> append a phase to the shutdown
+func (c *Coordinator) Add(name string, timeout time.Duration, run func(ctx context.Context) error) {
+	c.phases = append(c.phases, Phase{Name: name, Timeout: timeout, Run: run})
+}
+
+// Shutdown -> run every phase, the errors of the failed ones are joined
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, phase := range c.phases {
+		start := time.Now()
+		if err := c.run(ctx, phase); err != nil {
+			c.logger.Error("shutdown phase failed", zap.String("phase", phase.Name), zap.Duration("duration", time.Since(start)), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", phase.Name, err))
+			continue
+		}
+		c.logger.Info("shutdown phase done", zap.String("phase", phase.Name), zap.Duration("duration", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
+
+// run -> run the phase until it returns or its timeout expires,
+// a phase ignoring its context is left behind once it times out
+func (c *Coordinator) run(ctx context.Context, phase Phase) error {
+	ctx, cancel := context.WithTimeout(ctx, phase.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- phase.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Delay -> wait for d or until ctx is done
+func Delay(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ############################################################
+
+// Jobs -> the background jobs started by the requests, e.g. the emails sent after
+// the response, waited for on shutdown so they aren't cut off by the exit
+type Jobs struct {
+	wg sync.WaitGroup
+}
+
+func InitJobs() *Jobs {
+	return &Jobs{}
+}
+
+// Go -> run the job in the background, the job gets a context detached
+// from the request so it outlives the response
+func (j *Jobs) Go(ctx context.Context, job func(ctx context.Context)) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		job(context.WithoutCancel(ctx))
+	}()
+}
+
+// Wait -> wait for the running jobs until ctx is done
+func (j *Jobs) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
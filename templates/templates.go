@@ -0,0 +1,250 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	textTemplate "text/template"
+
+	"github.com/noo8xl/anvil-gateway/config"
+	"golang.org/x/text/language"
+)
+
+// the template of every notification event
+const (
+	OrderInvited        = "order_invited"
+	OrderUpdated        = "order_updated"
+	OrderApplied        = "order_applied"
+	OrderRejected       = "order_rejected"
+	OfferApplied        = "offer_applied"
+	ComplianceRequested = "compliance_requested"
+	ComplianceApproved  = "compliance_approved"
+	ComplianceRejected  = "compliance_rejected"
+	TwoStepCode         = "two_step_code"
+	PasswordReset       = "password_reset"
+)
+
+// Names -> every template the default locale has to provide
+var Names = []string{
+	OrderInvited, OrderUpdated, OrderApplied, OrderRejected, OfferApplied,
+	ComplianceRequested, ComplianceApproved, ComplianceRejected, TwoStepCode, PasswordReset,
+}
+
+// ErrUnknownTemplate -> the template isn't provided by any locale
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// files -> the built-in templates, "{locale}/{name}.tmpl"
+//
+//go:embed files
+var files embed.FS
+
+// Data -> the values of a template, e.g. {"OrderTitle": "..."}
+type Data map[string]any
+
+// Rendered -> a template rendered in a locale
+//
+//   - Title -> the notification title and the email subject
+//   - Text -> the notification body and the plain text email
+//   - Html -> the html email, the text one if the template has none
+type Rendered struct {
+	Title string
+	Text  string
+	Html  string
+
+	emailFormat string
+}
+
+// EmailBody -> the email variant chosen by the config
+func (r Rendered) EmailBody() string {
+	if r.emailFormat == "html" {
+		return r.Html
+	}
+	return r.Text
+}
+
+// template -> a parsed template file, "title" and "text" are rendered as text
+// and "html" with the contextual escaping of html/template
+type template struct {
+	text *textTemplate.Template
+	html *htmlTemplate.Template
+}
+
+// Catalog -> the templates of every locale
+type Catalog struct {
+	defaultLocale string
+	emailFormat   string
+	locales       []string
+	matcher       language.Matcher
+	templates     map[string]map[string]*template
+}
+
+// Load -> parse the embedded templates and the overrides from the config dir,
+// every template of Names has to be provided by the default locale
+func Load(cfg config.TemplatesConfig) (*Catalog, error) {
+	sources := map[string][]byte{}
+
+	embedded, err := fs.Sub(files, "files")
+	if err != nil {
+		return nil, err
+	}
+	if err = readSources(embedded, sources); err != nil {
+		return nil, err
+	}
+	if cfg.Dir != "" {
+		if err = readSources(os.DirFS(cfg.Dir), sources); err != nil {
+			return nil, fmt.Errorf("failed to read the templates of %s: %w", cfg.Dir, err)
+		}
+	}
+
+	c := &Catalog{
+		defaultLocale: cfg.DefaultLocale,
+		emailFormat:   cfg.EmailFormat,
+		templates:     map[string]map[string]*template{},
+	}
+
+	for file, source := range sources {
+		locale, name := path.Dir(file), strings.TrimSuffix(path.Base(file), ".tmpl")
+		parsed, err := parse(file, source)
+		if err != nil {
+			return nil, err
+		}
+		if c.templates[locale] == nil {
+			c.templates[locale] = map[string]*template{}
+		}
+		c.templates[locale][name] = parsed
+	}
+
+	for _, name := range Names {
+		if c.templates[c.defaultLocale][name] == nil {
+			return nil, fmt.Errorf("template %s/%s.tmpl is missing", c.defaultLocale, name)
+		}
+	}
+
+	// the default locale goes first, it's the match when nothing else is
+	c.locales = append(c.locales, c.defaultLocale)
+	for locale := range c.templates {
+		if locale != c.defaultLocale {
+			c.locales = append(c.locales, locale)
+		}
+	}
+	slices.Sort(c.locales[1:])
+
+	tags := make([]language.Tag, 0, len(c.locales))
+	for _, locale := range c.locales {
+		tags = append(tags, language.Make(locale))
+	}
+	c.matcher = language.NewMatcher(tags)
+
+	return c, nil
+}
+
+// Locales -> every locale having a template, the default one first
+func (c *Catalog) Locales() []string {
+	return slices.Clone(c.locales)
+}
+
+// Match -> the locale best matching an Accept-Language header value,
+// the default one if none of them matches
+func (c *Catalog) Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return c.defaultLocale
+	}
+
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return c.defaultLocale
+	}
+	return c.locales[index]
+}
+
+// Resolve -> the supported locale of a language tag, e.g. "en-GB" -> "en"
+func (c *Catalog) Resolve(locale string) (string, bool) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", false
+	}
+
+	_, index, confidence := c.matcher.Match(tag)
+	if confidence == language.No {
+		return "", false
+	}
+	return c.locales[index], true
+}
+
+// Render -> render the template in the locale, the default locale is used
+// if the template has no translation to it
+func (c *Catalog) Render(name, locale string, data Data) (Rendered, error) {
+	tmpl := c.templates[locale][name]
+	if tmpl == nil {
+		tmpl = c.templates[c.defaultLocale][name]
+	}
+	if tmpl == nil {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	var title, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&title, "title", data); err != nil {
+		return Rendered{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Rendered{}, err
+	}
+	if tmpl.html.Lookup("html") != nil {
+		if err := tmpl.html.ExecuteTemplate(&html, "html", data); err != nil {
+			return Rendered{}, err
+		}
+	} else {
+		html.WriteString(htmlTemplate.HTMLEscapeString(text.String()))
+	}
+
+	return Rendered{
+		Title:       strings.TrimSpace(title.String()),
+		Text:        strings.TrimSpace(text.String()),
+		Html:        strings.TrimSpace(html.String()),
+		emailFormat: c.emailFormat,
+	}, nil
+}
+
+// readSources -> collect the "{locale}/{name}.tmpl" files, replacing the ones already read
+func readSources(fsys fs.FS, sources map[string][]byte) error {
+	matches, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range matches {
+		source, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		sources[strings.ToLower(file)] = source
+	}
+	return nil
+}
+
+// parse -> parse a template file by both engines, it has to define "title" and "text"
+func parse(file string, source []byte) (*template, error) {
+	text, err := textTemplate.New(file).Option("missingkey=error").Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmlTemplate.New(file).Option("missingkey=error").Parse(string(source))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"title", "text"} {
+		if text.Lookup(name) == nil {
+			return nil, fmt.Errorf("template %s doesn't define %q", file, name)
+		}
+	}
+	return &template{text: text, html: html}, nil
+}
@@ -0,0 +1,145 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"go.uber.org/zap"
+
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+// memorySink -> keeps the written events, fails every write if err is set
+type memorySink struct {
+	events []audit.Event
+	err    error
+}
+
+func (s *memorySink) Write(ctx context.Context, event audit.Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestFilterMatches(t *testing.T) {
+	now := time.Now()
+	event := audit.Event{
+		Time:    now,
+		ActorId: 7,
+		Action:  "orders.compliance.approve",
+		Target:  "order:3",
+		Outcome: audit.OutcomeSuccess,
+	}
+
+	tests := []struct {
+		name   string
+		filter audit.Filter
+		want   bool
+	}{
+		{"empty filter", audit.Filter{}, true},
+		{"actor", audit.Filter{ActorId: 7}, true},
+		{"other actor", audit.Filter{ActorId: 8}, false},
+		{"exact action", audit.Filter{Action: "orders.compliance.approve"}, true},
+		{"action prefix", audit.Filter{Action: "orders."}, true},
+		{"action without the dot is exact", audit.Filter{Action: "orders"}, false},
+		{"target", audit.Filter{Target: "order:3"}, true},
+		{"outcome", audit.Filter{Outcome: audit.OutcomeFailure}, false},
+		{"within the range", audit.Filter{From: now.Add(-time.Minute), To: now.Add(time.Minute)}, true},
+		{"before the range", audit.Filter{From: now.Add(time.Minute)}, false},
+		{"after the range", audit.Filter{To: now.Add(-time.Minute)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(event); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	r := httptest.NewRequest("PATCH", "/api/v1/profile/security/update/change-password/", nil)
+	r.RemoteAddr = "192.0.2.10:5555"
+	ctx := context.WithValue(r.Context(), middlewares.CustomerKey, &authPb.CustomerDto{CustomerId: 5, Role: "CUSTOMER"})
+	ctx = context.WithValue(ctx, middlewares.RequestIdKey, "req-1")
+	r = r.WithContext(ctx)
+
+	event := audit.NewEvent(r, "profile.password.change", audit.Target("customer", 5), errors.New("boom"))
+	if event.ActorId != 5 || event.ActorRole != "CUSTOMER" || event.Ip != "192.0.2.10" || event.RequestId != "req-1" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Target != "customer:5" || event.Outcome != audit.OutcomeFailure {
+		t.Errorf("unexpected target or outcome: %+v", event)
+	}
+}
+
+func TestTrailRecord(t *testing.T) {
+	failing := &memorySink{err: errors.New("sink is down")}
+	sink := &memorySink{}
+	trail := audit.InitTrail(zap.NewNop(), failing, sink)
+
+	ctx := context.WithValue(context.Background(), middlewares.RequestIdKey, "req-2")
+	trail.Record(ctx, audit.Event{Action: "orders.delete"})
+
+	if len(sink.events) != 1 {
+		t.Fatalf("a failed sink should not stop the others, got %d events", len(sink.events))
+	}
+	if sink.events[0].Time.IsZero() || sink.events[0].RequestId != "req-2" {
+		t.Errorf("time and request id should be set: %+v", sink.events[0])
+	}
+
+	if _, err := trail.Query(ctx, audit.Filter{}); !errors.Is(err, audit.ErrNotQueryable) {
+		t.Errorf("Query() error = %v, want ErrNotQueryable", err)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	sink, err := audit.InitFileSink(filepath.Join(t.TempDir(), "logs", "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("InitFileSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	trail := audit.InitTrail(zap.NewNop(), sink)
+	for i := 1; i <= 5; i++ {
+		trail.Record(context.Background(), audit.Event{ActorId: uint64(i % 2), Action: "orders.update", Target: audit.Target("order", uint64(i))})
+	}
+
+	events, err := trail.Query(context.Background(), audit.Filter{ActorId: 1, Limit: 2})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Target != "order:5" || events[1].Target != "order:3" {
+		t.Errorf("want the two newest events of the actor, got %+v", events)
+	}
+}
+
+func TestRedisSink(t *testing.T) {
+	sink := audit.InitRedisSink(cache.InitCacheService(), 0)
+	action := fmt.Sprintf("test.%d", time.Now().UnixNano())
+
+	for i := 1; i <= 3; i++ {
+		if err := sink.Write(context.Background(), audit.Event{Time: time.Now(), Action: action, Target: audit.Target("order", uint64(i))}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	events, err := sink.Query(context.Background(), audit.Filter{Action: action, Limit: 2})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Target != "order:3" || events[1].Target != "order:2" {
+		t.Errorf("want the two newest events, got %+v", events)
+	}
+}
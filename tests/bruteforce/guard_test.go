@@ -0,0 +1,96 @@
+package bruteforce_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/bruteforce"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"go.uber.org/zap"
+)
+
+func initTestGuard() *bruteforce.Guard {
+	cfg := config.AuthConfig{
+		MaxFailedAttempts:    3,
+		FailedAttemptsWindow: time.Minute,
+		LockoutDuration:      time.Minute,
+		MaxAttemptDelay:      2 * time.Second,
+		MaxCodeAttempts:      2,
+	}
+	return bruteforce.InitGuard(cfg, cache.InitCacheService(), zap.NewNop())
+}
+
+func testEmail() string {
+	return fmt.Sprintf("guard-%d@test.com", time.Now().UnixNano())
+}
+
+func TestGuardLockout(t *testing.T) {
+	guard := initTestGuard()
+	key := bruteforce.EmailKey(testEmail())
+
+	if err := guard.Check(context.Background(), bruteforce.SignIn, key); err != nil {
+		t.Fatalf("expected no block before failures, got %v", err)
+	}
+
+	if err := guard.Fail(context.Background(), bruteforce.SignIn, key); err != nil {
+		t.Fatalf("error registering failure: %v", err)
+	}
+	if err := guard.Check(context.Background(), bruteforce.SignIn, key); err != nil {
+		t.Fatalf("expected no delay after the first failure, got %v", err)
+	}
+
+	guard.Fail(context.Background(), bruteforce.SignIn, key)
+	var blocked *bruteforce.BlockedError
+	if err := guard.Check(context.Background(), bruteforce.SignIn, key); !errors.As(err, &blocked) || blocked.Locked {
+		t.Fatalf("expected a delay after the second failure, got %v", err)
+	}
+
+	guard.Fail(context.Background(), bruteforce.SignIn, key)
+	if err := guard.Check(context.Background(), bruteforce.SignIn, key); !errors.As(err, &blocked) || !blocked.Locked {
+		t.Fatalf("expected a lockout after the third failure, got %v", err)
+	}
+	if blocked.RetryAfter <= 2*time.Second {
+		t.Errorf("expected the lockout duration, got %s", blocked.RetryAfter)
+	}
+
+	// the scopes are counted apart
+	if err := guard.Check(context.Background(), bruteforce.TwoStep, key); err != nil {
+		t.Errorf("expected another scope not to be locked, got %v", err)
+	}
+
+	if err := guard.Reset(context.Background(), bruteforce.SignIn, key); err != nil {
+		t.Fatalf("error resetting attempts: %v", err)
+	}
+	if err := guard.Check(context.Background(), bruteforce.SignIn, key); err != nil {
+		t.Errorf("expected no block after reset, got %v", err)
+	}
+}
+
+func TestGuardFailCode(t *testing.T) {
+	guard := initTestGuard()
+	cs := cache.InitCacheService()
+	email := testEmail()
+
+	cs.Set2FACode(context.Background(), email, "123456")
+
+	guard.FailCode(context.Background(), email)
+	if _, err := cs.Get2FACode(context.Background(), email); err != nil {
+		t.Fatalf("expected the code to survive the first wrong guess, got %v", err)
+	}
+
+	guard.FailCode(context.Background(), email)
+	if _, err := cs.Get2FACode(context.Background(), email); err == nil || err.Error() != "code not found" {
+		t.Fatalf("expected the code to be invalidated, got %v", err)
+	}
+
+	// a new code starts a new counter
+	cs.Set2FACode(context.Background(), email, "654321")
+	guard.FailCode(context.Background(), email)
+	if _, err := cs.Get2FACode(context.Background(), email); err != nil {
+		t.Errorf("expected the new code to be valid, got %v", err)
+	}
+}
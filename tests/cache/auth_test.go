@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/noo8xl/anvil-gateway/cache"
@@ -16,11 +17,11 @@ func init() {
 }
 
 func TestSet2FACode(t *testing.T) {
-	svc.Set2FACode(email, code)
+	svc.Set2FACode(context.Background(), email, code)
 }
 
 func TestGet2FACode(t *testing.T) {
-	c, err := svc.Get2FACode(email)
+	c, err := svc.Get2FACode(context.Background(), email)
 	if err != nil {
 		t.Errorf("Error getting 2FA code: %v", err)
 	}
@@ -30,5 +31,5 @@ func TestGet2FACode(t *testing.T) {
 }
 
 func TestClear2FACode(t *testing.T) {
-	svc.Clear2FACode(email)
+	svc.Clear2FACode(context.Background(), email)
 }
@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -37,14 +38,14 @@ var (
 )
 
 func TestSetBlog(t *testing.T) {
-	err := svc.SetBlog(customerId, blog)
+	err := svc.SetBlog(context.Background(), customerId, blog)
 	if err != nil {
 		t.Errorf("error setting blog: Expected nil, got %v", err)
 	}
 }
 
 func TestGetBlog(t *testing.T) {
-	blog, err := svc.GetBlog(customerId)
+	blog, err := svc.GetBlog(context.Background(), customerId)
 	if err != nil {
 		t.Errorf("error getting blog: Expected nil, got %v", err)
 	}
@@ -56,7 +57,7 @@ func TestGetBlog(t *testing.T) {
 }
 
 func TestClearBlog(t *testing.T) {
-	err := svc.ClearBlog(customerId)
+	err := svc.ClearBlog(context.Background(), customerId)
 	if err != nil {
 
 		t.Errorf("error cleaning blog: Expected nil, got %v", err)
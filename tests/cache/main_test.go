@@ -1,6 +1,9 @@
 package cache_test
 
 import (
+	"context"
+	"testing"
+
 	"github.com/noo8xl/anvil-gateway/cache"
 )
 
@@ -11,3 +14,16 @@ var (
 func init() {
 	svc = cache.InitCacheService()
 }
+
+func TestClose(t *testing.T) {
+	if err := svc.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	// the pools are opened again on the next use
+	if err := svc.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() after Close() error = %v", err)
+	}
+}
@@ -1,10 +1,14 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	notificationPb "github.com/noo8xl/anvil-api/main/notifications"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/noo8xl/anvil-gateway/config"
 )
 
 var (
@@ -34,31 +38,119 @@ var (
 
 func TestSetNotificationsList(t *testing.T) {
 
-	err := svc.SetNotificationsList(notificationsList)
+	err := svc.SetNotificationsList(context.Background(), customerId, 0, notificationsList)
 	if err != nil {
 		t.Errorf("error setting notifications list: Expected nil, got %v", err)
 	}
 
+	// an empty page is cached too
+	err = svc.SetNotificationsList(context.Background(), customerId, 20, &notificationPb.GetNotificationsListResponse{})
+	if err != nil {
+		t.Errorf("error setting an empty notifications list: Expected nil, got %v", err)
+	}
+
 }
 
 func TestGetNotificationsList(t *testing.T) {
 
-	list, err := svc.GetNotificationsList(customerId)
+	svc.SetNotificationsList(context.Background(), customerId, 0, notificationsList)
+
+	list, err := svc.GetNotificationsList(context.Background(), customerId, 0)
 	if err != nil {
 		t.Errorf("error getting notifications list: Expected nil, got %v", err)
 	}
 
-	if list == nil {
-		t.Errorf("error getting notifications list: Expected a list of notifications, got nil")
+	if list == nil || len(list.List) != len(notificationsList.List) {
+		t.Errorf("error getting notifications list: Expected a list of notifications, got %v", list)
+	}
+
+	list, err = svc.GetNotificationsList(context.Background(), customerId, 40)
+	if err != nil || list != nil {
+		t.Errorf("error getting a page not cached: Expected nil, got %v, %v", list, err)
 	}
 
 }
 
 func TestClearNotifications(t *testing.T) {
 
-	err := svc.ClearNotifications(customerId)
+	err := svc.ClearNotifications(context.Background(), customerId)
 	if err != nil {
 		t.Errorf("error clearing notifications: Expected nil, got %v", err)
 	}
 
+	if list, _ := svc.GetNotificationsList(context.Background(), customerId, 0); list != nil {
+		t.Errorf("error clearing notifications: Expected no cached list, got %v", list)
+	}
+
+}
+
+func TestUnreadNotifications(t *testing.T) {
+	const readerId uint64 = 5
+	ctx := context.Background()
+
+	// the counter is unknown until it's set
+	svc.ResetNotifications(ctx, readerId)
+	rdb := redis.NewClient(config.GetNotificationsRedisConfig())
+	defer rdb.Close()
+	rdb.Del(ctx, "notifications:unread:5")
+
+	if err := svc.CountNewNotification(ctx, readerId); err != nil {
+		t.Fatalf("CountNewNotification() error = %v", err)
+	}
+	if _, known, _ := svc.GetUnreadNotifications(ctx, readerId); known {
+		t.Fatal("an unknown counter is counted")
+	}
+
+	if unread, err := svc.SetUnreadNotifications(ctx, readerId, 2); err != nil || unread != 2 {
+		t.Fatalf("SetUnreadNotifications() = %d, %v, want 2", unread, err)
+	}
+	// a counter set meanwhile is kept
+	if unread, _ := svc.SetUnreadNotifications(ctx, readerId, 7); unread != 2 {
+		t.Errorf("SetUnreadNotifications() = %d, want the counter kept", unread)
+	}
+
+	svc.CountNewNotification(ctx, readerId)
+	if unread, err := svc.MarkNotificationsRead(ctx, readerId, 10); err != nil || unread != 2 {
+		t.Errorf("MarkNotificationsRead() = %d, %v, want 2", unread, err)
+	}
+	// marking a read one again doesn't change the counter
+	if unread, _ := svc.MarkNotificationsRead(ctx, readerId, 10); unread != 2 {
+		t.Errorf("MarkNotificationsRead() again = %d, want 2", unread)
+	}
+
+	read, err := svc.GetReadNotifications(ctx, readerId, 10, 11)
+	if err != nil || !read[10] || read[11] {
+		t.Errorf("GetReadNotifications() = %v, %v", read, err)
+	}
+
+	// a deleted read notification isn't uncounted, an unread one is
+	svc.ForgetNotification(ctx, readerId, 10)
+	svc.ForgetNotification(ctx, readerId, 11)
+	if unread, _, _ := svc.GetUnreadNotifications(ctx, readerId); unread != 1 {
+		t.Errorf("unread after the deletes = %d, want 1", unread)
+	}
+
+	svc.MarkAllNotificationsRead(ctx, readerId, 12, 13)
+	read, _ = svc.GetReadNotifications(ctx, readerId, 12, 13)
+	if unread, _, _ := svc.GetUnreadNotifications(ctx, readerId); unread != 0 || !read[12] || !read[13] {
+		t.Errorf("unread after marking all = %d, read = %v", unread, read)
+	}
+
+	// the counter doesn't go below zero
+	svc.ForgetNotification(ctx, readerId, 14)
+	if unread, _, _ := svc.GetUnreadNotifications(ctx, readerId); unread != 0 {
+		t.Errorf("unread = %d, want 0", unread)
+	}
+
+	// the read ones expire and are pruned to the listed ones
+	if ttl := rdb.TTL(ctx, "notifications:read:5").Val(); ttl <= 0 {
+		t.Errorf("TTL of the read notifications = %v", ttl)
+	}
+	if err = svc.PruneReadNotifications(ctx, readerId, 13, 15); err != nil {
+		t.Fatalf("PruneReadNotifications() error = %v", err)
+	}
+	read, _ = svc.GetReadNotifications(ctx, readerId, 12, 13)
+	if read[12] || !read[13] {
+		t.Errorf("read after the prune = %v, want only the listed one", read)
+	}
 }
@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -29,14 +30,14 @@ var (
 )
 
 func TestSetOfferDetails(t *testing.T) {
-	err := svc.SetOfferDetails(offer.OfferId, offer)
+	err := svc.SetOfferDetails(context.Background(), offer.OfferId, offer)
 	if err != nil {
 		t.Errorf("Error setting offer details: %v", err)
 	}
 }
 
 func TestGetOfferDetails(t *testing.T) {
-	offer, err := svc.GetOfferDetails(offer.OfferId)
+	offer, err := svc.GetOfferDetails(context.Background(), offer.OfferId)
 	if err != nil {
 		t.Errorf("Error getting offer details: %v", err)
 	}
@@ -49,7 +50,7 @@ func TestGetOfferDetails(t *testing.T) {
 }
 
 func TestGetOfferDetailsWithInvalidOfferId(t *testing.T) {
-	offer, err := svc.GetOfferDetails(666)
+	offer, err := svc.GetOfferDetails(context.Background(), 666)
 	if err != nil {
 		t.Errorf("Error getting offer details: %v", err)
 	}
@@ -60,7 +61,7 @@ func TestGetOfferDetailsWithInvalidOfferId(t *testing.T) {
 }
 
 func TestClearOfferDetails(t *testing.T) {
-	err := svc.ClearOfferDetails(offer.OfferId)
+	err := svc.ClearOfferDetails(context.Background(), offer.OfferId)
 	if err != nil {
 		t.Errorf("Error clearing offer details: %v", err)
 	}
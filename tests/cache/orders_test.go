@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -81,31 +82,31 @@ var (
 )
 
 func TestSetOrdersList(t *testing.T) {
-	if err := svc.SetOrdersList(orders); err != nil {
+	if err := svc.SetOrdersList(context.Background(), orders); err != nil {
 		t.Fatalf("TestSetOrdersList error: failed to set orders list: %v", err)
 	}
 }
 
 func TestSetOrderDetails(t *testing.T) {
-	if err := svc.SetOrderDetails(orderId, order); err != nil {
+	if err := svc.SetOrderDetails(context.Background(), orderId, order); err != nil {
 		t.Fatalf("TestSetOrderDetails error: failed to set order details: %v", err)
 	}
 }
 
 func TestSetFilteredOrdersList(t *testing.T) {
-	if err := svc.SetFilteredOrdersList(customerId, orders); err != nil {
+	if err := svc.SetFilteredOrdersList(context.Background(), customerId, orders); err != nil {
 		t.Fatalf("TestSetFilteredOrdersList error: failed to set filtered orders list: %v", err)
 	}
 }
 
 func TestSetComplianceRequestsList(t *testing.T) {
-	if err := svc.SetComplianceRequestsList(customerId, complianceRequests); err != nil {
+	if err := svc.SetComplianceRequestsList(context.Background(), customerId, complianceRequests); err != nil {
 		t.Fatalf("TestSetComplianceRequestsList error: failed to set compliance requests list: %v", err)
 	}
 }
 
 func TestGetOrderDetails(t *testing.T) {
-	order, err := svc.GetOrderDetails(orderId)
+	order, err := svc.GetOrderDetails(context.Background(), orderId)
 	if err != nil {
 		t.Fatalf("TestGetOrderDetails error: failed to get order details: %v", err)
 	}
@@ -116,7 +117,7 @@ func TestGetOrderDetails(t *testing.T) {
 }
 
 func TestGetOrdersList(t *testing.T) {
-	orders, err := svc.GetOrdersList(customerId)
+	orders, err := svc.GetOrdersList(context.Background(), customerId)
 	if err != nil {
 		t.Fatalf("TestGetOrdersList error: failed to get orders list: %v", err)
 	}
@@ -129,7 +130,7 @@ func TestGetOrdersList(t *testing.T) {
 }
 
 func TestGetFilteredOrdersList(t *testing.T) {
-	orders, err := svc.GetFilteredOrdersList(customerId)
+	orders, err := svc.GetFilteredOrdersList(context.Background(), customerId)
 	if err != nil {
 		t.Fatalf("TestGetFilteredOrdersList error: failed to get filtered orders list: %v", err)
 	}
@@ -140,7 +141,7 @@ func TestGetFilteredOrdersList(t *testing.T) {
 }
 
 func TestGetComplianceRequestsList(t *testing.T) {
-	list, err := svc.GetComplianceRequestsList(customerId)
+	list, err := svc.GetComplianceRequestsList(context.Background(), customerId)
 	if err != nil {
 		t.Fatalf("TestGetComplianceRequestsList error: failed to get compliance requests list: %v", err)
 	}
@@ -151,25 +152,25 @@ func TestGetComplianceRequestsList(t *testing.T) {
 }
 
 func TestClearComplianceRequestsList(t *testing.T) {
-	if err := svc.ClearComplianceRequestsList(customerId); err != nil {
+	if err := svc.ClearComplianceRequestsList(context.Background(), customerId); err != nil {
 		t.Fatalf("TestClearComplianceRequestsList error: failed to clear compliance requests list: %v", err)
 	}
 }
 
 func TestClearOrdersList(t *testing.T) {
-	if err := svc.ClearOrdersList(customerId); err != nil {
+	if err := svc.ClearOrdersList(context.Background(), customerId); err != nil {
 		t.Fatalf("TestClearOrdersList error: failed to clear orders list: %v", err)
 	}
 }
 
 func TestClearFilteredOrdersList(t *testing.T) {
-	if err := svc.ClearFilteredOrdersList(customerId); err != nil {
+	if err := svc.ClearFilteredOrdersList(context.Background(), customerId); err != nil {
 		t.Fatalf("TestClearFilteredOrdersList error: failed to clear filtered orders list: %v", err)
 	}
 }
 
 func TestClearOrderDetails(t *testing.T) {
-	if err := svc.ClearOrderDetails(orderId); err != nil {
+	if err := svc.ClearOrderDetails(context.Background(), orderId); err != nil {
 		t.Fatalf("TestClearOrderDetails error: failed to clear order details: %v", err)
 	}
 }
@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -62,13 +63,13 @@ var (
 )
 
 func TestSetCustomerProfile(t *testing.T) {
-	if err := svc.SetCustomerProfile(customerId, profile); err != nil {
+	if err := svc.SetCustomerProfile(context.Background(), customerId, profile); err != nil {
 		t.Fatalf("TestSetCustomerProfile error: failed to set customer profile: %v", err)
 	}
 }
 
 func TestGetCustomerProfile(t *testing.T) {
-	profile, err := svc.GetCustomerProfile(customerId)
+	profile, err := svc.GetCustomerProfile(context.Background(), customerId)
 	if err != nil {
 		t.Fatalf("TestGetCustomerProfile error: failed to get customer profile: %v", err)
 	}
@@ -83,19 +84,19 @@ func TestGetCustomerProfile(t *testing.T) {
 }
 
 func TestClearCustomerProfile(t *testing.T) {
-	if err := svc.ClearCustomerProfile(customerId); err != nil {
+	if err := svc.ClearCustomerProfile(context.Background(), customerId); err != nil {
 		t.Fatalf("TestClearCustomerProfile error: failed to clear customer profile: %v", err)
 	}
 }
 
 func TestSetPublicProfile(t *testing.T) {
-	if err := svc.SetPublicProfile(customerId, publicProfile); err != nil {
+	if err := svc.SetPublicProfile(context.Background(), customerId, publicProfile); err != nil {
 		t.Fatalf("TestSetPublicProfile error: failed to set public profile: %v", err)
 	}
 }
 
 func TestGetPublicProfile(t *testing.T) {
-	pp, err := svc.GetPublicProfile(customerId)
+	pp, err := svc.GetPublicProfile(context.Background(), customerId)
 	if err != nil {
 		t.Fatalf("TestGetPublicProfile error: failed to get public profile: %v", err)
 	}
@@ -110,7 +111,7 @@ func TestGetPublicProfile(t *testing.T) {
 }
 
 func TestClearPublicProfile(t *testing.T) {
-	if err := svc.ClearPublicProfile(customerId); err != nil {
+	if err := svc.ClearPublicProfile(context.Background(), customerId); err != nil {
 		t.Fatalf("TestClearPublicProfile error: failed to clear public profile: %v", err)
 	}
 }
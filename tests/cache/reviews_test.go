@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -60,7 +61,7 @@ var (
 
 func TestSetReviewCommentsList(t *testing.T) {
 
-	err := svc.SetReviewCommentsList(reviewId, reviewCommentsList)
+	err := svc.SetReviewCommentsList(context.Background(), reviewId, reviewCommentsList)
 	if err != nil {
 		t.Errorf("error setting review comments list: Expected nil, got %v", err)
 	}
@@ -68,7 +69,7 @@ func TestSetReviewCommentsList(t *testing.T) {
 
 func TestGetReviewCommentsList(t *testing.T) {
 
-	reviewCommentsList, err := svc.GetReviewCommentsList(reviewId)
+	reviewCommentsList, err := svc.GetReviewCommentsList(context.Background(), reviewId)
 	if err != nil {
 		t.Errorf("error getting review comments list: Expected nil, got %v", err)
 	}
@@ -85,14 +86,14 @@ func TestGetReviewCommentsList(t *testing.T) {
 // ###########################################
 
 func TestSetReviewDetails(t *testing.T) {
-	err := svc.SetReviewDetails(reviewId, reviewDetails)
+	err := svc.SetReviewDetails(context.Background(), reviewId, reviewDetails)
 	if err != nil {
 		t.Errorf("error setting review details: Expected nil, got %v", err)
 	}
 }
 
 func TestGetReviewDetails(t *testing.T) {
-	details, err := svc.GetReviewDetails(reviewId)
+	details, err := svc.GetReviewDetails(context.Background(), reviewId)
 	if err != nil {
 		t.Errorf("error getting review details: Expected nil, got %v", err)
 	}
@@ -105,14 +106,14 @@ func TestGetReviewDetails(t *testing.T) {
 }
 
 func TestClearReviewCommentsList(t *testing.T) {
-	err := svc.ClearReviewCommentsList(reviewId)
+	err := svc.ClearReviewCommentsList(context.Background(), reviewId)
 	if err != nil {
 		t.Errorf("error clearing review comments list: Expected nil, got %v", err)
 	}
 }
 
 func TestClearReviewDetails(t *testing.T) {
-	err := svc.ClearReviewDetails(reviewId)
+	err := svc.ClearReviewDetails(context.Background(), reviewId)
 	if err != nil {
 		t.Errorf("error clearing review details: Expected nil, got %v", err)
 	}
@@ -0,0 +1,151 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/chat"
+	"github.com/noo8xl/anvil-gateway/config"
+)
+
+var testConfig = config.StreamConfig{
+	Heartbeat:      time.Minute,
+	BufferSize:     4,
+	MaxConnections: 2,
+}
+
+func TestMemoryClient(t *testing.T) {
+	ctx := context.Background()
+	client := chat.InitMemoryClient()
+
+	first, err := client.CreateChat(ctx, chat.SubjectOrder, 1, []uint64{7, 8})
+	if err != nil {
+		t.Fatalf("CreateChat() error = %v", err)
+	}
+	again, err := client.CreateChat(ctx, chat.SubjectOrder, 1, []uint64{8, 7})
+	if err != nil || again.Id != first.Id {
+		t.Errorf("CreateChat() of the same participants = %+v, %v, want the chat %d", again, err, first.Id)
+	}
+	second, _ := client.CreateChat(ctx, chat.SubjectOffer, 1, []uint64{7, 9})
+
+	if _, err = client.CreateChat(ctx, "blog", 1, []uint64{7, 8}); !errors.Is(err, chat.ErrInvalidSubject) {
+		t.Errorf("CreateChat() of a post error = %v, want ErrInvalidSubject", err)
+	}
+	if _, err = client.CreateChat(ctx, chat.SubjectOrder, 1, []uint64{7, 7}); !errors.Is(err, chat.ErrInvalidSubject) {
+		t.Errorf("CreateChat() with oneself error = %v, want ErrInvalidSubject", err)
+	}
+
+	for _, body := range []string{"hello", " how are you? "} {
+		if _, err = client.SendMessage(ctx, first.Id, 7, body); err != nil {
+			t.Fatalf("SendMessage() error = %v", err)
+		}
+	}
+	for _, body := range []string{"  ", strings.Repeat("a", chat.MaxMessageLength+1)} {
+		if _, err = client.SendMessage(ctx, first.Id, 7, body); !errors.Is(err, chat.ErrInvalidMessage) {
+			t.Errorf("SendMessage() of %d characters error = %v, want ErrInvalidMessage", len(body), err)
+		}
+	}
+	if _, err = client.SendMessage(ctx, 404, 7, "hello"); !errors.Is(err, chat.ErrNotFound) {
+		t.Errorf("SendMessage() to a missing chat error = %v, want ErrNotFound", err)
+	}
+
+	messages, err := client.GetMessagesList(ctx, first.Id, 0)
+	if err != nil || len(messages) != 2 || messages[0].Body != "how are you?" || messages[1].SenderId != 7 {
+		t.Errorf("GetMessagesList() = %+v, %v, want the newest first", messages, err)
+	}
+
+	chats, err := client.GetChatsList(ctx, 7, 0)
+	if err != nil || len(chats) != 2 || chats[0].Id != first.Id || chats[1].Id != second.Id {
+		t.Errorf("GetChatsList() = %+v, %v, want the active chat first", chats, err)
+	}
+	if chats, _ = client.GetChatsList(ctx, 7, 2); len(chats) != 0 {
+		t.Errorf("GetChatsList() past the end = %+v", chats)
+	}
+	if chats, _ = client.GetChatsList(ctx, 9, 0); len(chats) != 1 || chats[0].Id != second.Id {
+		t.Errorf("GetChatsList() of another customer = %+v", chats)
+	}
+}
+
+// start -> a hub listening to the published messages, stopped with the test
+func start(t *testing.T, cfg config.StreamConfig) *chat.Hub {
+	t.Helper()
+
+	hub := chat.InitHub(cfg, cache.InitCacheService(), zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		hub.Close()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hub.Listening() {
+		if time.Now().After(deadline) {
+			t.Fatal("the hub isn't listening")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hub
+}
+
+func TestServeWebSocket(t *testing.T) {
+	cfg := testConfig
+	cfg.MaxConnections = 1
+	hub := start(t, cfg)
+	client := chat.InitMemoryClient()
+	opened, _ := client.CreateChat(context.Background(), chat.SubjectOrder, 1, []uint64{7, 8})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := hub.Subscribe(opened.Id, 7)
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		defer s.Close()
+		hub.ServeWebSocket(w, r, s, func(ctx context.Context, body string) error {
+			message, err := client.SendMessage(ctx, opened.Id, 7, body)
+			if err != nil {
+				return err
+			}
+			return hub.Publish(ctx, message)
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.CloseNow()
+
+	// the sent message comes back by the stream
+	conn.Write(ctx, websocket.MessageText, []byte(`{"body": "hello"}`))
+	var message chat.Message
+	if _, payload, err := conn.Read(ctx); err != nil || json.Unmarshal(payload, &message) != nil || message.Body != "hello" || message.SenderId != 7 {
+		t.Errorf("Read() = %s, %v, want the sent message", payload, err)
+	}
+
+	conn.Write(ctx, websocket.MessageText, []byte(`{"body": " "}`))
+	var reply map[string]string
+	if _, payload, err := conn.Read(ctx); err != nil || json.Unmarshal(payload, &reply) != nil || reply["error"] == "" {
+		t.Errorf("Read() = %s, %v, want an error of the empty message", payload, err)
+	}
+
+	hub.Close()
+	if _, _, err = conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Errorf("Read() after Close() error = %v, want going away", err)
+	}
+}
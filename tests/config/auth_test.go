@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/config"
+)
+
+func TestGetAuthConfig(t *testing.T) {
+	t.Setenv("AUTH_KEYS_REFRESH_INTERVAL", "")
+	t.Setenv("AUTH_TOKEN_CACHE_TTL", "1m")
+	t.Setenv("AUTH_REMOTE_FALLBACK", "false")
+	t.Setenv("AUTH_MAX_FAILED_ATTEMPTS", "-1")
+	t.Setenv("AUTH_MAX_CODE_ATTEMPTS", "10")
+
+	cfg := config.GetAuthConfig()
+
+	if cfg.KeysRefreshInterval != 15*time.Minute {
+		t.Errorf("error: KeysRefreshInterval is %s, expected the default 15m", cfg.KeysRefreshInterval)
+	}
+	if cfg.TokenCacheTTL != time.Minute {
+		t.Errorf("error: TokenCacheTTL is %s, expected 1m", cfg.TokenCacheTTL)
+	}
+	if cfg.RemoteFallback {
+		t.Errorf("error: RemoteFallback is enabled, expected disabled")
+	}
+	if cfg.MaxFailedAttempts != 5 {
+		t.Errorf("error: MaxFailedAttempts is %d, expected the default 5", cfg.MaxFailedAttempts)
+	}
+	if cfg.MaxCodeAttempts != 10 {
+		t.Errorf("error: MaxCodeAttempts is %d, expected 10", cfg.MaxCodeAttempts)
+	}
+}
+
+func TestGetRBACConfig(t *testing.T) {
+	t.Setenv("RBAC_CONFIG_FILE", "")
+
+	cfg, err := config.GetRBACConfig()
+	if err != nil {
+		t.Fatalf("error getting the default rbac config: %v", err)
+	}
+	if len(cfg.Roles["ADMIN"]) == 0 || len(cfg.Roles["SUPERVISOR"]) == 0 {
+		t.Errorf("error: expected default ADMIN and SUPERVISOR permissions, got %v", cfg.Roles)
+	}
+
+	path := filepath.Join(t.TempDir(), "rbac.json")
+	os.WriteFile(path, []byte(`{"SUPPORT": ["admin:access"]}`), 0600)
+	t.Setenv("RBAC_CONFIG_FILE", path)
+
+	cfg, err = config.GetRBACConfig()
+	if err != nil {
+		t.Fatalf("error loading the rbac config file: %v", err)
+	}
+	if len(cfg.Roles) != 1 || cfg.Roles["SUPPORT"][0] != "admin:access" {
+		t.Errorf("error: unexpected roles %v", cfg.Roles)
+	}
+}
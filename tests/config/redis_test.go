@@ -101,3 +101,27 @@ func TestGetNotificationsRedisConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestGetSessionsRedisConfig(t *testing.T) {
+	redisConfig := config.GetSessionsRedisConfig()
+
+	if redisConfig == nil {
+		t.Errorf("error: redisConfig is nil, expected not nil options struct")
+	} else {
+		if redisConfig.DB != 8 {
+			t.Errorf("error: redisConfig.DB is %d, expected 8", redisConfig.DB)
+		}
+	}
+}
+
+func TestGetRateLimitRedisConfig(t *testing.T) {
+	redisConfig := config.GetRateLimitRedisConfig()
+
+	if redisConfig == nil {
+		t.Errorf("error: redisConfig is nil, expected not nil options struct")
+	} else {
+		if redisConfig.DB != 9 {
+			t.Errorf("error: redisConfig.DB is %d, expected 9", redisConfig.DB)
+		}
+	}
+}
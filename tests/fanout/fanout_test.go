@@ -0,0 +1,268 @@
+package fanout_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/fanout"
+)
+
+var testConfig = config.StreamConfig{
+	Heartbeat:      time.Minute,
+	BufferSize:     4,
+	MaxConnections: 2,
+}
+
+// item -> a streamed test payload
+type item struct {
+	Id   string `json:"id"`
+	Text string `json:"text"`
+}
+
+var lastId atomic.Uint64
+
+// testId -> a channel or an owner id no other test run has used,
+// the channels of the chats are shared with the other test packages
+func testId() uint64 {
+	lastId.CompareAndSwap(0, uint64(time.Now().UnixNano()))
+	return lastId.Add(1)
+}
+
+// start -> a hub listening to the published items, stopped with the test
+func start(t *testing.T, cfg config.StreamConfig) *fanout.Hub[item] {
+	t.Helper()
+
+	store := cache.InitCacheService()
+	kind := fanout.Kind{Name: "test", Items: "items", Publish: store.PublishChatMessage, Listen: store.ListenChatMessages}
+	hub := fanout.InitHub[item](cfg, kind, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		hub.Close()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hub.Listening() {
+		if time.Now().After(deadline) {
+			t.Fatal("the hub isn't listening")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hub
+}
+
+func subscribe(t *testing.T, hub *fanout.Hub[item], channelId, ownerId uint64) *fanout.Subscription[item] {
+	t.Helper()
+
+	s, err := hub.Subscribe(channelId, ownerId)
+	if err != nil {
+		t.Fatalf("Subscribe(%d, %d) error = %v", channelId, ownerId, err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func receive(t *testing.T, s *fanout.Subscription[item]) item {
+	t.Helper()
+
+	select {
+	case received := <-s.Items():
+		return received
+	case <-time.After(5 * time.Second):
+		t.Fatal("no item received")
+		return item{}
+	}
+}
+
+func TestPublishFansOutAcrossHubs(t *testing.T) {
+	first, second := start(t, testConfig), start(t, testConfig)
+	channel, otherChannel := testId(), testId()
+	onFirst, onSecond := subscribe(t, first, channel, testId()), subscribe(t, second, channel, testId())
+	other := subscribe(t, second, otherChannel, testId())
+
+	if err := first.Publish(context.Background(), channel, item{Id: "1", Text: "hello"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	for _, s := range []*fanout.Subscription[item]{onFirst, onSecond} {
+		if received := receive(t, s); received.Id != "1" || received.Text != "hello" {
+			t.Errorf("item = %+v", received)
+		}
+	}
+
+	select {
+	case received := <-other.Items():
+		t.Errorf("another channel got %+v", received)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSlowStreamDropsItems(t *testing.T) {
+	cfg := testConfig
+	cfg.BufferSize = 1
+	hub := start(t, cfg)
+	channel, otherChannel := testId(), testId()
+	slow, other := subscribe(t, hub, channel, testId()), subscribe(t, hub, otherChannel, testId())
+
+	for range 3 {
+		hub.Publish(context.Background(), channel, item{Text: "text"})
+	}
+	// the items are received in order, so the earlier ones are dispatched by now
+	hub.Publish(context.Background(), otherChannel, item{Text: "text"})
+	receive(t, other)
+
+	receive(t, slow)
+	select {
+	case received := <-slow.Items():
+		t.Errorf("got %+v over the buffer", received)
+	default:
+	}
+}
+
+func TestSubscribeLimits(t *testing.T) {
+	hub := start(t, testConfig)
+	owner := testId()
+	first := subscribe(t, hub, testId(), owner)
+	subscribe(t, hub, testId(), owner)
+
+	// the limit is of the owner's streams to any channel
+	if _, err := hub.Subscribe(testId(), owner); !errors.Is(err, fanout.ErrTooManyStreams) {
+		t.Errorf("Subscribe() over the limit error = %v, want ErrTooManyStreams", err)
+	}
+	subscribe(t, hub, first.ChannelId(), testId())
+
+	// a closed stream frees its slot
+	first.Close()
+	subscribe(t, hub, testId(), owner)
+
+	hub.Close()
+	if _, err := hub.Subscribe(testId(), testId()); !errors.Is(err, fanout.ErrClosed) {
+		t.Errorf("Subscribe() after Close() error = %v, want ErrClosed", err)
+	}
+	if _, ok := <-first.Items(); ok {
+		t.Error("the items of a closed hub are open")
+	}
+}
+
+// serve -> a server streaming the items of the channel over a WebSocket
+func serve(t *testing.T, hub *fanout.Hub[item], channel uint64, receive fanout.Receiver) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := hub.Subscribe(channel, channel)
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		defer s.Close()
+		hub.ServeWebSocket(w, r, s, receive)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+
+	// the subscription is made once the connection is accepted
+	for streaming(hub, channel) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+// streaming -> whether the stream of the channel isn't opened yet
+func streaming(hub *fanout.Hub[item], channel uint64) bool {
+	s, err := hub.Subscribe(channel, channel)
+	if err != nil {
+		return false
+	}
+	s.Close()
+	return true
+}
+
+func TestServeWebSocket(t *testing.T) {
+	cfg := testConfig
+	cfg.MaxConnections = 1
+	hub := start(t, cfg)
+	channel := testId()
+
+	// the messages of the client are sent back as the channel items
+	conn := serve(t, hub, channel, func(ctx context.Context, payload []byte) error {
+		var sent item
+		if err := json.Unmarshal(payload, &sent); err != nil {
+			return err
+		}
+		if sent.Text == "" {
+			return errors.New("an empty item")
+		}
+		return hub.Publish(ctx, channel, sent)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn.Write(ctx, websocket.MessageText, []byte(`{"id": "1", "text": "hello"}`))
+	var received item
+	if kind, payload, err := conn.Read(ctx); err != nil || kind != websocket.MessageText || json.Unmarshal(payload, &received) != nil || received.Text != "hello" {
+		t.Errorf("Read() = %s, %v, want the sent item", payload, err)
+	}
+
+	var reply map[string]string
+	for _, message := range []struct {
+		kind    websocket.MessageType
+		payload string
+	}{
+		{websocket.MessageText, `{"text": ""}`},
+		{websocket.MessageBinary, `{"text": "hello"}`},
+	} {
+		conn.Write(ctx, message.kind, []byte(message.payload))
+		if _, payload, err := conn.Read(ctx); err != nil || json.Unmarshal(payload, &reply) != nil || reply["error"] == "" {
+			t.Errorf("Read() = %s, %v, want an error of %s", payload, err, message.payload)
+		}
+	}
+
+	hub.Close()
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Errorf("Read() after Close() error = %v, want going away", err)
+	}
+}
+
+func TestServeOneWayWebSocket(t *testing.T) {
+	cfg := testConfig
+	cfg.MaxConnections = 1
+	hub := start(t, cfg)
+	channel := testId()
+	conn := serve(t, hub, channel, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hub.Publish(context.Background(), channel, item{Id: "1", Text: "hello"})
+	var received item
+	if _, payload, err := conn.Read(ctx); err != nil || json.Unmarshal(payload, &received) != nil || received.Id != "1" {
+		t.Errorf("Read() = %s, %v, want the published item", payload, err)
+	}
+
+	// the stream doesn't take the messages of the client
+	conn.Write(ctx, websocket.MessageText, []byte(`{"text": "hello"}`))
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+		t.Errorf("Read() after a client message error = %v, want policy violation", err)
+	}
+}
@@ -0,0 +1,116 @@
+package health_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcHealth "google.golang.org/grpc/health"
+	healthPb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/noo8xl/anvil-gateway/health"
+)
+
+// startServer -> a grpc server on a random local port, with the standard health service if given
+func startServer(t *testing.T, healthServer *grpcHealth.Server) *grpc.ClientConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	if healthServer != nil {
+		healthPb.RegisterHealthServer(server, healthServer)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return dial(t, listener.Addr().String())
+}
+
+func dial(t *testing.T, address string) *grpc.ClientConn {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGrpcProbe(t *testing.T) {
+	healthServer := grpcHealth.NewServer()
+	conn := startServer(t, healthServer)
+
+	var fallbacks atomic.Int32
+	probe := health.GrpcProbe(conn, func(ctx context.Context) error {
+		fallbacks.Add(1)
+		return nil
+	})
+
+	if err := probe(context.Background()); err != nil {
+		t.Errorf("a serving backend should be up, got %v", err)
+	}
+
+	healthServer.SetServingStatus("", healthPb.HealthCheckResponse_NOT_SERVING)
+	if err := probe(context.Background()); err == nil {
+		t.Errorf("a not serving backend should be down")
+	}
+	if fallbacks.Load() != 0 {
+		t.Errorf("the fallback should not be used by a backend implementing the protocol")
+	}
+}
+
+func TestGrpcProbeFallback(t *testing.T) {
+	conn := startServer(t, nil)
+
+	var fallbacks atomic.Int32
+	probe := health.GrpcProbe(conn, func(ctx context.Context) error {
+		fallbacks.Add(1)
+		return nil
+	})
+
+	for range 2 {
+		if err := probe(context.Background()); err != nil {
+			t.Errorf("the fallback result should be returned, got %v", err)
+		}
+	}
+	if fallbacks.Load() != 2 {
+		t.Errorf("want the fallback used on every check, got %d", fallbacks.Load())
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	// nothing listens on the port once the listener is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := health.InitWatcher()
+	watcher.Watch(ctx, "down", dial(t, listener.Addr().String()))
+	watcher.Watch(ctx, "up", startServer(t, grpcHealth.NewServer()))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for watcher.Available("down") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if state, _ := watcher.State("down"); state != connectivity.TransientFailure {
+		t.Errorf("a backend that refuses connections should be in a transient failure, got %v", state)
+	}
+
+	for state, _ := watcher.State("up"); state != connectivity.Ready && time.Now().Before(deadline); state, _ = watcher.State("up") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !watcher.Available("up") || !watcher.Available("unknown") {
+		t.Errorf("a connected or not watched backend should be available")
+	}
+}
@@ -0,0 +1,124 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/health"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes map[string]health.Probe
+		status string
+		code   int
+	}{
+		{"every dependency is up", map[string]health.Probe{"auth": up, "redis": up, "blog": up}, health.StatusUp, http.StatusOK},
+		{"an optional dependency is down", map[string]health.Probe{"auth": up, "redis": up, "blog": down}, health.StatusDegraded, http.StatusOK},
+		{"a critical dependency is down", map[string]health.Probe{"auth": down, "redis": up, "blog": up}, health.StatusDown, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.InitChecker(config.HealthConfig{CheckTimeout: time.Second, Critical: []string{"auth", "redis"}})
+			for name, probe := range tt.probes {
+				checker.Add(name, probe)
+			}
+
+			w := httptest.NewRecorder()
+			checker.DetailsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+
+			var report health.Report
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatalf("failed to decode the report: %v", err)
+			}
+			if w.Code != tt.code || report.Status != tt.status || len(report.Checks) != len(tt.probes) {
+				t.Errorf("got %d %+v, want %d %s", w.Code, report, tt.code, tt.status)
+			}
+
+			w = httptest.NewRecorder()
+			checker.ReadyHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.code {
+				t.Errorf("ReadyHandler() status = %d, want %d", w.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestChecksRunConcurrentlyWithTimeout(t *testing.T) {
+	checker := health.InitChecker(config.HealthConfig{CheckTimeout: 50 * time.Millisecond})
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	checker.Add("orders", hang)
+	checker.Add("reviews", hang)
+	checker.Add("offers", hang)
+
+	start := time.Now()
+	report := checker.Report(context.Background())
+	if elapsed := time.Since(start); elapsed > 120*time.Millisecond {
+		t.Errorf("the checks should run concurrently, took %v", elapsed)
+	}
+	for _, result := range report.Checks {
+		if result.Status != health.StatusDown || result.Error == "" {
+			t.Errorf("a timed out check should be down: %+v", result)
+		}
+	}
+}
+
+func TestReportIsCached(t *testing.T) {
+	var calls atomic.Int32
+	checker := health.InitChecker(config.HealthConfig{CheckTimeout: time.Second, CacheTTL: time.Minute})
+	checker.Add("redis", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	for range 3 {
+		checker.Report(context.Background())
+	}
+	if calls.Load() != 1 {
+		t.Errorf("the report should be reused within the ttl, got %d checks", calls.Load())
+	}
+}
+
+func TestLiveHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	health.LiveHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("LiveHandler() status = %d, want 200", w.Code)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	checker := health.InitChecker(config.HealthConfig{CheckTimeout: time.Second, CacheTTL: time.Minute, Critical: []string{"redis"}})
+	checker.Add("redis", up)
+	checker.Report(context.Background())
+
+	checker.Drain()
+
+	w := httptest.NewRecorder()
+	checker.ReadyHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("a draining gateway should not be ready, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	health.LiveHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("a draining gateway should be alive, got %d", w.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package middlewares_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/router"
+)
+
+// fakeValidator -> resolves a token to the customer with the same role
+type fakeValidator struct{}
+
+func (f *fakeValidator) ValidateToken(ctx context.Context, token string) (*authPb.CustomerDto, middlewares.TokenGrant, error) {
+	switch token {
+	case "customer", "ADMIN":
+		return &authPb.CustomerDto{CustomerId: 1, Role: token}, middlewares.TokenGrant{}, nil
+	default:
+		return nil, middlewares.TokenGrant{}, errors.New("invalid token")
+	}
+}
+
+func customerHandler(w http.ResponseWriter, r *http.Request) {
+	customer, ok := r.Context().Value(middlewares.CustomerKey).(*authPb.CustomerDto)
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]any{"customerId": 0})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"customerId": customer.CustomerId})
+}
+
+func initTestServer(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	policies := middlewares.InitPolicies()
+
+	api := router.InitRouter(mux, policies)
+	api.HandleFunc("/health/", middlewares.Public(), customerHandler)
+
+	v1 := api.Version("v1")
+	v1.HandleFunc("POST /auth/sign-in/", middlewares.Public(), customerHandler)
+	v1.HandleFunc("GET /profile/get/", middlewares.Authenticated(), customerHandler)
+	// a path containing /auth/ is not public unless declared so
+	v1.HandleFunc("GET /profile/auth/sessions/", middlewares.Authenticated(), customerHandler)
+	v1.HandleFunc("GET /notifications/stream/", middlewares.Authenticated().WithQueryToken(), customerHandler)
+	v1.HandleFunc("GET /admin/orders/{skip}/", middlewares.RequireRoles("ADMIN", "SUPERVISOR"), customerHandler)
+	v1.HandleFunc("POST /admin/cache/purge/", middlewares.RequireRoles("ADMIN").WithScopes("cache:purge"), customerHandler)
+
+	if err := api.Mount(); err != nil {
+		t.Fatalf("error mounting routes: %v", err)
+	}
+	return middlewares.AuthMiddleware(mux, policies, &fakeValidator{})
+}
+
+func TestAuthMiddlewarePolicies(t *testing.T) {
+	handler := initTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{name: "public route", method: http.MethodPost, path: "/api/v1/auth/sign-in/", status: http.StatusOK},
+		{name: "public unversioned route", method: http.MethodGet, path: "/health/", status: http.StatusOK},
+		{name: "missing token", method: http.MethodGet, path: "/api/v1/profile/get/", status: http.StatusUnauthorized},
+		{name: "malformed header", method: http.MethodGet, path: "/api/v1/profile/get/", token: "customer", status: http.StatusUnauthorized},
+		{name: "invalid token", method: http.MethodGet, path: "/api/v1/profile/get/", token: "Bearer unknown", status: http.StatusUnauthorized},
+		{name: "authenticated route", method: http.MethodGet, path: "/api/v1/profile/get/", token: "Bearer customer", status: http.StatusOK},
+		{name: "auth substring is not public", method: http.MethodGet, path: "/api/v1/profile/auth/sessions/", status: http.StatusUnauthorized},
+		{name: "query token", method: http.MethodGet, path: "/api/v1/notifications/stream/?token=customer", status: http.StatusOK},
+		{name: "invalid query token", method: http.MethodGet, path: "/api/v1/notifications/stream/?token=unknown", status: http.StatusUnauthorized},
+		{name: "query token not accepted", method: http.MethodGet, path: "/api/v1/profile/get/?token=customer", status: http.StatusUnauthorized},
+		{name: "admin route as customer", method: http.MethodGet, path: "/api/v1/admin/orders/0/", token: "Bearer customer", status: http.StatusForbidden},
+		{name: "admin route as admin", method: http.MethodGet, path: "/api/v1/admin/orders/0/", token: "Bearer ADMIN", status: http.StatusOK},
+		{name: "missing scope", method: http.MethodPost, path: "/api/v1/admin/cache/purge/", token: "Bearer ADMIN", status: http.StatusForbidden},
+		{name: "unknown route", method: http.MethodGet, path: "/api/v1/unknown/", status: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodDelete, path: "/api/v1/profile/get/", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("error: status is %d, expected %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareSetsCustomerOnAdminRoutes(t *testing.T) {
+	handler := initTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/orders/0/", nil)
+	req.Header.Set("Authorization", "Bearer ADMIN")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body map[string]uint64
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	if body["customerId"] != 1 {
+		t.Errorf("error: customer is not set in the admin route context")
+	}
+}
@@ -0,0 +1,87 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+func initAccessLogServer(t *testing.T, cfg config.AccessLogConfig) (http.Handler, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	handler := middlewares.InitAccessLogger(zap.New(core), cfg).Handler(initTestServer(t))
+	return middlewares.RequestId(handler), logs
+}
+
+func TestAccessLogFields(t *testing.T) {
+	handler, logs := initAccessLogServer(t, config.AccessLogConfig{Redact: []string{"token", "email"}})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/?token=secret&Email=a%40b.c&page=2", nil)
+	r.Header.Set("Authorization", "Bearer customer")
+	r.Header.Set(middlewares.RequestIdHeader, "req-7")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("want 1 access log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+
+	want := map[string]any{
+		"method":     "GET",
+		"path":       "/api/v1/profile/get/",
+		"query":      "token=[REDACTED]&Email=[REDACTED]&page=2",
+		"route":      "GET /api/v1/profile/get/",
+		"status":     int64(http.StatusOK),
+		"requestId":  "req-7",
+		"customerId": uint64(1),
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+	if fields["bytes"].(int64) == 0 {
+		t.Error("bytes of the response body should be logged")
+	}
+}
+
+func TestAccessLogLevels(t *testing.T) {
+	handler, logs := initAccessLogServer(t, config.AccessLogConfig{})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/", nil))
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 || entries[0].Level != zapcore.WarnLevel {
+		t.Fatalf("an unauthorized request should be logged as a warning, got %+v", entries)
+	}
+	if entries[0].ContextMap()["status"] != int64(http.StatusUnauthorized) {
+		t.Errorf("status = %v, want 401", entries[0].ContextMap()["status"])
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	handler, logs := initAccessLogServer(t, config.AccessLogConfig{Sampling: map[string]uint64{"/health/": 10}})
+
+	for range 25 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/", nil))
+	}
+	if n := logs.Len(); n != 3 {
+		t.Errorf("want 1 of every 10 requests logged, got %d of 25", n)
+	}
+
+	logs.TakeAll()
+	for range 3 {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if n := logs.Len(); n != 3 {
+		t.Errorf("routes without sampling should be always logged, got %d of 3", n)
+	}
+}
@@ -0,0 +1,104 @@
+package middlewares_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/metrics"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+// scrapeMetrics -> the exported metrics as served on /metrics/
+func scrapeMetrics(t *testing.T) string {
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics/", nil))
+	body, _ := io.ReadAll(w.Body)
+	return string(body)
+}
+
+func assertMetric(t *testing.T, exported, line string) {
+	t.Helper()
+	if !strings.Contains(exported, line) {
+		t.Errorf("metric %q is not exported", line)
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics-test/{id}/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /metrics-test/{id}/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	handler := middlewares.MetricsMiddleware(mux, mux)
+
+	for _, id := range []string{"1", "2", "3"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test/"+id+"/", nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/metrics-test/1/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test-random-url", nil))
+
+	exported := scrapeMetrics(t)
+	assertMetric(t, exported, `http_requests_total{method="GET",route="/metrics-test/{id}/",status="200"} 3`)
+	assertMetric(t, exported, `http_requests_total{method="POST",route="/metrics-test/{id}/",status="201"} 1`)
+	assertMetric(t, exported, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assertMetric(t, exported, `http_response_size_bytes_sum{method="POST",route="/metrics-test/{id}/"} 7`)
+	assertMetric(t, exported, `http_requests_in_flight{route="/metrics-test/{id}/"} 0`)
+	assertMetric(t, exported, "go_goroutines")
+}
+
+func TestUnaryClientMetrics(t *testing.T) {
+	interceptor := middlewares.UnaryClientMetrics()
+	invoke := func(err error) {
+		interceptor(context.Background(), "/metrics.TestService/Get", nil, nil, nil,
+			func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return err
+			})
+	}
+	invoke(nil)
+	invoke(status.Error(codes.NotFound, "not found"))
+
+	exported := scrapeMetrics(t)
+	assertMetric(t, exported, `grpc_client_requests_total{code="OK",method="Get",service="metrics.TestService"} 1`)
+	assertMetric(t, exported, `grpc_client_requests_total{code="NotFound",method="Get",service="metrics.TestService"} 1`)
+	assertMetric(t, exported, `grpc_client_request_duration_seconds_count{method="Get",service="metrics.TestService"} 2`)
+}
+
+func TestCacheMetrics(t *testing.T) {
+	cacheService := cache.InitCacheService()
+	counter := func(exported, result string) string {
+		prefix := fmt.Sprintf(`cache_lookups_total{cache="orders",result="%s"} `, result)
+		for _, line := range strings.Split(exported, "\n") {
+			if strings.HasPrefix(line, prefix) {
+				return strings.TrimPrefix(line, prefix)
+			}
+		}
+		return "0"
+	}
+
+	before := scrapeMetrics(t)
+	orderId := uint64(time.Now().UnixNano())
+	if _, err := cacheService.GetOrderDetails(context.Background(), orderId); err != nil {
+		t.Fatalf("GetOrderDetails() error = %v", err)
+	}
+	after := scrapeMetrics(t)
+
+	var missesBefore, missesAfter int
+	fmt.Sscan(counter(before, "miss"), &missesBefore)
+	fmt.Sscan(counter(after, "miss"), &missesAfter)
+	if missesAfter != missesBefore+1 {
+		t.Errorf("a missing order should count a cache miss, got %d -> %d", missesBefore, missesAfter)
+	}
+	assertMetric(t, after, `cache_command_duration_seconds_count{cache="orders",command="get"}`)
+}
@@ -0,0 +1,107 @@
+package middlewares_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/router"
+)
+
+func initRateLimitedServer(t *testing.T, store string) http.Handler {
+	mux := http.NewServeMux()
+	policies := middlewares.InitPolicies()
+
+	api := router.InitRouter(mux, policies)
+	api.UseRateLimiter(middlewares.InitRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Store:   store,
+		Groups: map[string]config.RateLimit{
+			middlewares.RateGroupDefault: {Requests: 5, Window: time.Minute},
+			middlewares.RateGroupAuth:    {Requests: 2, Window: time.Minute},
+		},
+	}, cache.InitCacheService()))
+
+	v1 := api.Version("v1")
+	v1.HandleFunc("POST /auth/sign-in/", middlewares.Public().WithRateLimit(middlewares.RateGroupAuth), customerHandler)
+	v1.HandleFunc("GET /profile/get/", middlewares.Authenticated(), customerHandler)
+
+	if err := api.Mount(); err != nil {
+		t.Fatalf("error mounting routes: %v", err)
+	}
+	return middlewares.AuthMiddleware(mux, policies, &fakeValidator{})
+}
+
+// uniqueAddr -> a client address not limited by the previous runs against redis
+func uniqueAddr() string {
+	n := time.Now().UnixNano()
+	return fmt.Sprintf("10.%d.%d.%d:1234", n>>16%250, n>>8%250, n%250)
+}
+
+func TestRateLimit(t *testing.T) {
+	for _, store := range []string{"memory", "redis"} {
+		t.Run(store, func(t *testing.T) {
+			handler := initRateLimitedServer(t, store)
+			ip := uniqueAddr()
+
+			signIn := func(remoteAddr string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/sign-in/", nil)
+				req.RemoteAddr = remoteAddr
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			for i := range 2 {
+				rec := signIn(ip)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+				}
+				if rec.Header().Get("RateLimit-Limit") != "2" {
+					t.Errorf("expected RateLimit-Limit 2, got %q", rec.Header().Get("RateLimit-Limit"))
+				}
+			}
+			if remaining := signIn(ip).Header(); remaining.Get("RateLimit-Remaining") != "0" || remaining.Get("Retry-After") == "" {
+				t.Errorf("expected no remaining requests and Retry-After, got %v", remaining)
+			}
+
+			rec := signIn(ip)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Errorf("expected 429 over the quota, got %d", rec.Code)
+			}
+
+			// another ip has its own quota
+			if rec = signIn(uniqueAddr()); rec.Code != http.StatusOK {
+				t.Errorf("expected 200 for another ip, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestRateLimitPerCustomer(t *testing.T) {
+	handler := initRateLimitedServer(t, "memory")
+
+	get := func(token, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// the quota follows the customer across ips
+	for i := range 5 {
+		if code := get("customer", fmt.Sprintf("10.1.0.%d:1234", i)); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := get("customer", "10.1.0.99:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for the customer over the quota, got %d", code)
+	}
+}
@@ -0,0 +1,111 @@
+package middlewares_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+func TestRequestId(t *testing.T) {
+	var seen string
+	handler := middlewares.RequestId(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middlewares.GetRequestId(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"kept", "trace-42.a_b", true},
+		{"too long", strings.Repeat("a", 65), false},
+		{"unsafe characters", "abc\r\nforged", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/health/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(middlewares.RequestIdHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if seen == "" || w.Header().Get(middlewares.RequestIdHeader) != seen {
+				t.Fatalf("request id %q should be set and returned, got header %q", seen, w.Header().Get(middlewares.RequestIdHeader))
+			}
+			if (seen == tt.incoming) != tt.keep {
+				t.Errorf("incoming id %q, got %q", tt.incoming, seen)
+			}
+		})
+	}
+}
+
+func TestRequestIdErrorBody(t *testing.T) {
+	handler := middlewares.RequestId(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error/":
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "orders: unavailable"})
+		case "/text/":
+			http.Error(w, "not found", http.StatusNotFound)
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		}
+	}))
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/error/", http.StatusInternalServerError, `{"error":"orders: unavailable","requestId":"req-9"}`},
+		{"/text/", http.StatusNotFound, "not found"},
+		{"/ok/", http.StatusOK, `{"status":"ok"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Header.Set(middlewares.RequestIdHeader, "req-9")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status || strings.TrimSpace(w.Body.String()) != tt.body {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestUnaryClientRequestId(t *testing.T) {
+	var forwarded []string
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		forwarded = md.Get(middlewares.RequestIdMetadataKey)
+		return nil
+	}
+
+	ctx := context.WithValue(context.Background(), middlewares.RequestIdKey, "req-3")
+	if err := middlewares.UnaryClientRequestId(ctx, "/orders.OrdersService/CreateOrder", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("interceptor error = %v", err)
+	}
+	if len(forwarded) != 1 || forwarded[0] != "req-3" {
+		t.Errorf("forwarded request id = %v, want [req-3]", forwarded)
+	}
+
+	forwarded = nil
+	middlewares.UnaryClientRequestId(context.Background(), "/orders.OrdersService/CreateOrder", nil, nil, nil, invoker)
+	if len(forwarded) != 0 {
+		t.Errorf("no request id should be forwarded outside of a request, got %v", forwarded)
+	}
+}
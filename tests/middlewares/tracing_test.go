@@ -0,0 +1,115 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/tracing"
+)
+
+func initTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.InitProvider(config.TracingConfig{SampleRatio: 1, ServiceName: "test"}, sdktrace.NewSimpleSpanProcessor(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return exporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
+
+func TestTracingServerSpan(t *testing.T) {
+	exporter := initTracing(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/orders/{orderId}/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	handler := middlewares.RequestId(middlewares.Tracing(mux, mux))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/orders/3/", nil)
+	r.Header.Set(middlewares.RequestIdHeader, "req-9")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	span, ok := findSpan(exporter.GetSpans(), "GET /api/v1/orders/{orderId}/")
+	if !ok {
+		t.Fatalf("no span named by the route pattern: %+v", exporter.GetSpans())
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("SpanKind = %v, want server", span.SpanKind)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("the incoming trace should be continued, got trace %s", got)
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range span.Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs[string(middlewares.RequestIdAttribute)] != "req-9" || attrs["http.route"] != "/api/v1/orders/{orderId}/" {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+}
+
+func TestTracingCacheSpans(t *testing.T) {
+	exporter := initTracing(t)
+	cacheService := cache.InitCacheService()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/orders/details/{orderId}/", func(w http.ResponseWriter, r *http.Request) {
+		// the same fan-out as the handlers gathering the order or the profile details
+		var wg sync.WaitGroup
+		for _, orderId := range []uint64{1, 2} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := cacheService.GetOrderDetails(r.Context(), orderId); err != nil {
+					t.Errorf("GetOrderDetails() error = %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+	handler := middlewares.Tracing(mux, mux)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/orders/details/1/", nil))
+
+	spans := exporter.GetSpans()
+	server, ok := findSpan(spans, "GET /api/v1/orders/details/{orderId}/")
+	if !ok {
+		t.Fatalf("no server span: %+v", spans)
+	}
+
+	gets := 0
+	for _, span := range spans {
+		if span.Name != "redis.get" {
+			continue
+		}
+		gets++
+		if span.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("the parallel cache calls should be siblings under the request span")
+		}
+		if span.Status.Code == codes.Error {
+			t.Errorf("a cache miss should not fail the span")
+		}
+	}
+	if gets != 2 {
+		t.Errorf("want a span of each cache call, got %d", gets)
+	}
+}
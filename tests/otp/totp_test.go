@@ -0,0 +1,100 @@
+package otp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/otp"
+)
+
+// rfcSecret -> base32 of the RFC 6238 SHA-1 test key "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+	}
+
+	for _, tt := range tests {
+		code, err := otp.Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("error generating code: %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("expected %s at %d, got %s", tt.code, tt.unix, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := otp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+
+	now := time.Now()
+	code, err := otp.Code(secret, now)
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+
+	step, ok := otp.Validate(secret, code, now, 0)
+	if !ok {
+		t.Fatal("expected the current code to be valid")
+	}
+
+	if _, ok = otp.Validate(secret, code, now, step); ok {
+		t.Error("expected a used code to be rejected")
+	}
+
+	if _, ok = otp.Validate(secret, code, now.Add(5*time.Minute), 0); ok {
+		t.Error("expected an outdated code to be rejected")
+	}
+
+	previous, _ := otp.Code(secret, now.Add(-30*time.Second))
+	if _, ok = otp.Validate(secret, previous, now, 0); !ok {
+		t.Error("expected the previous step code to be accepted")
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := otp.KeyURI("Anvil", "test@test.com", rfcSecret)
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Anvil:test@test.com?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfcSecret) || !strings.Contains(uri, "issuer=Anvil") {
+		t.Errorf("expected secret and issuer in %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := otp.GenerateRecoveryCodes(10)
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !otp.IsRecoveryCode(code) {
+			t.Errorf("expected %s to be a recovery code", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicated code %s", code)
+		}
+		seen[code] = true
+	}
+
+	if otp.HashRecoveryCode(codes[0]) != otp.HashRecoveryCode(strings.ToUpper(codes[0])) {
+		t.Error("expected the hash to ignore the code case")
+	}
+	if otp.IsRecoveryCode("123456") {
+		t.Error("expected a totp code not to be a recovery code")
+	}
+}
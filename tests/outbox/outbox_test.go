@@ -0,0 +1,209 @@
+package outbox_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/outbox"
+	"github.com/noo8xl/anvil-gateway/preferences"
+)
+
+// fakeNotifications -> fail the first calls with the given errors, then succeed
+type fakeNotifications struct {
+	notificationsPb.NotificationsServiceClient
+
+	mu       sync.Mutex
+	failures []error
+	calls    int
+	created  []*notificationsPb.CreateNotificationRequest
+	emails   []*notificationsPb.SendEmailRequest
+}
+
+func (f *fakeNotifications) result() error {
+	f.calls++
+	if len(f.failures) == 0 {
+		return nil
+	}
+	err := f.failures[0]
+	f.failures = f.failures[1:]
+	return err
+}
+
+func (f *fakeNotifications) CreateNotification(ctx context.Context, in *notificationsPb.CreateNotificationRequest, opts ...grpc.CallOption) (*notificationsPb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.result(); err != nil {
+		return nil, err
+	}
+	f.created = append(f.created, in)
+	return &notificationsPb.Empty{}, nil
+}
+
+func (f *fakeNotifications) SendEmail(ctx context.Context, in *notificationsPb.SendEmailRequest, opts ...grpc.CallOption) (*notificationsPb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.result(); err != nil {
+		return nil, err
+	}
+	f.emails = append(f.emails, in)
+	return &notificationsPb.Empty{}, nil
+}
+
+func (f *fakeNotifications) delivered() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.created) + len(f.emails)
+}
+
+var testConfig = config.OutboxConfig{
+	Workers:         2,
+	PollInterval:    10 * time.Millisecond,
+	DeliveryTimeout: time.Second,
+	MaxAttempts:     3,
+	RetryBackoff:    10 * time.Millisecond,
+	MaxBackoff:      50 * time.Millisecond,
+	ClaimIdle:       time.Minute,
+}
+
+// mutedEmails -> the customer 7 has muted the emails of the orders
+type mutedEmails struct{}
+
+func (mutedEmails) Allows(ctx context.Context, customerId uint64, area, channel string) (bool, error) {
+	return !(customerId == 7 && area == "orders" && channel == preferences.ChannelEmail), nil
+}
+
+// start -> a dispatcher over an empty outbox, stopped with the test
+func start(t *testing.T, client *fakeNotifications) *cache.CacheService {
+	t.Helper()
+
+	rdb := redis.NewClient(config.GetOutboxRedisConfig())
+	defer rdb.Close()
+	if err := rdb.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("FlushDB() error = %v", err)
+	}
+
+	store := cache.InitCacheService()
+	dispatcher := outbox.InitDispatcher(testConfig, store, client, zap.NewNop())
+	dispatcher.UsePreferences(mutedEmails{})
+	if err := dispatcher.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { dispatcher.Stop(context.Background()) })
+	return store
+}
+
+// waitDepth -> wait until the outbox depth matches
+func waitDepth(t *testing.T, store *cache.CacheService, want cache.OutboxDepth) {
+	t.Helper()
+
+	var depth cache.OutboxDepth
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var err error
+		if depth, err = store.GetOutboxDepth(context.Background()); err != nil {
+			t.Fatalf("GetOutboxDepth() error = %v", err)
+		}
+		if depth == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("outbox depth = %+v, want %+v", depth, want)
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	client := &fakeNotifications{}
+	store := start(t, client)
+
+	err := outbox.InitOutbox(store).Enqueue(context.Background(),
+		outbox.Notification(7, "Order Rejected", "body", "orders"),
+		outbox.Email("jane@example.com", "New Order Invitation", "body"),
+	)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	waitDepth(t, store, cache.OutboxDepth{})
+	if client.delivered() != 2 {
+		t.Fatalf("delivered %d messages, want 2", client.delivered())
+	}
+	if client.created[0].CustomerId != 7 || client.created[0].Area != "orders" || client.created[0].CreatedAt == "" {
+		t.Errorf("notification = %+v", client.created[0])
+	}
+	if client.emails[0].Email != "jane@example.com" || client.emails[0].Subject != "New Order Invitation" {
+		t.Errorf("email = %+v", client.emails[0])
+	}
+}
+
+func TestDispatcherSkipsMuted(t *testing.T) {
+	client := &fakeNotifications{}
+	store := start(t, client)
+
+	err := outbox.InitOutbox(store).Enqueue(context.Background(),
+		outbox.CustomerEmail(7, "jane@example.com", "Order Updated", "body", "orders"),
+		outbox.CustomerEmail(8, "john@example.com", "Order Updated", "body", "orders"),
+		outbox.Notification(7, "Order Updated", "body", "orders"),
+	)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// the muted email is dropped, not dead-lettered
+	waitDepth(t, store, cache.OutboxDepth{})
+	if client.delivered() != 2 || client.calls != 2 {
+		t.Fatalf("delivered %d messages in %d calls, want 2 in 2", client.delivered(), client.calls)
+	}
+	if client.emails[0].Email != "john@example.com" {
+		t.Errorf("email = %+v, want the one not muted", client.emails[0])
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "notifications service is unavailable")
+	client := &fakeNotifications{failures: []error{unavailable, unavailable}}
+	store := start(t, client)
+
+	if err := outbox.InitOutbox(store).Enqueue(context.Background(), outbox.Notification(7, "title", "body", "orders")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	waitDepth(t, store, cache.OutboxDepth{})
+	if client.delivered() != 1 || client.calls != 3 {
+		t.Errorf("delivered %d messages in %d calls, want 1 in 3", client.delivered(), client.calls)
+	}
+}
+
+func TestDispatcherDeadLetters(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "notifications service is unavailable")
+	rejected := status.Error(codes.InvalidArgument, "invalid email")
+	client := &fakeNotifications{failures: []error{rejected, unavailable, unavailable, unavailable}}
+	store := start(t, client)
+
+	messages := outbox.InitOutbox(store)
+	// a rejected message isn't retried
+	if err := messages.Enqueue(context.Background(), outbox.Email("not an email", "title", "body")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	waitDepth(t, store, cache.OutboxDepth{DeadLetters: 1})
+
+	// a failing one is given up after the last attempt
+	if err := messages.Enqueue(context.Background(), outbox.Notification(7, "title", "body", "orders")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	waitDepth(t, store, cache.OutboxDepth{DeadLetters: 2})
+
+	if client.delivered() != 0 || client.calls != 1+int(testConfig.MaxAttempts) {
+		t.Errorf("delivered %d messages in %d calls, want none in %d", client.delivered(), client.calls, 1+testConfig.MaxAttempts)
+	}
+}
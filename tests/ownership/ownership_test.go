@@ -0,0 +1,244 @@
+package ownership_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	blogPb "github.com/noo8xl/anvil-api/main/blog"
+	notificationsPb "github.com/noo8xl/anvil-api/main/notifications"
+	offersPb "github.com/noo8xl/anvil-api/main/offers"
+	ordersPb "github.com/noo8xl/anvil-api/main/orders"
+	reviewsPb "github.com/noo8xl/anvil-api/main/reviews"
+	"google.golang.org/grpc"
+
+	"github.com/noo8xl/anvil-gateway/ownership"
+)
+
+// fakeOffers -> serves the offers by id and counts the calls
+type fakeOffers struct {
+	offersPb.OffersServiceClient
+	offers map[uint64]*offersPb.Offer
+	calls  int
+}
+
+func (f *fakeOffers) GetOfferDetails(ctx context.Context, in *offersPb.GetOfferDetailsRequest, opts ...grpc.CallOption) (*offersPb.Offer, error) {
+	f.calls++
+	if offer, ok := f.offers[in.OfferId]; ok {
+		return offer, nil
+	}
+	return nil, errors.New("rpc error: code = NotFound desc = offer not found")
+}
+
+// fakeOrders -> serves the orders by id
+type fakeOrders struct {
+	ordersPb.OrdersServiceClient
+	orders map[uint64]*ordersPb.Order
+	err    error
+}
+
+func (f *fakeOrders) GetOrderDetails(ctx context.Context, in *ordersPb.GetOrderDetailsRequest, opts ...grpc.CallOption) (*ordersPb.Order, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if order, ok := f.orders[in.OrderId]; ok {
+		return order, nil
+	}
+	return nil, errors.New("rpc error: code = NotFound desc = order not found")
+}
+
+// fakeReviews -> serves the reviews of a customer by pages of two
+type fakeReviews struct {
+	reviewsPb.ReviewsServiceClient
+	reviews map[uint64][]*reviewsPb.ReviewResponse
+	calls   int
+}
+
+func (f *fakeReviews) GetReviewsList(ctx context.Context, in *reviewsPb.GetReviewsListRequest, opts ...grpc.CallOption) (*reviewsPb.GetReviewsListResponse, error) {
+	f.calls++
+	list := f.reviews[in.CustomerId]
+	start := min(int(in.Skip), len(list))
+	end := min(start+2, len(list))
+	return &reviewsPb.GetReviewsListResponse{List: list[start:end]}, nil
+}
+
+// fakeBlog -> serves the posts of a customer in a single page
+type fakeBlog struct {
+	blogPb.BlogServiceClient
+	posts map[uint64][]*blogPb.BlogItem
+}
+
+func (f *fakeBlog) GetBlog(ctx context.Context, in *blogPb.GetBlogRequest, opts ...grpc.CallOption) (*blogPb.Blog, error) {
+	if in.Skip > 0 {
+		return &blogPb.Blog{}, nil
+	}
+	return &blogPb.Blog{Blog: f.posts[in.CustomerId]}, nil
+}
+
+// fakeNotifications -> serves the notifications of a customer in a single page
+type fakeNotifications struct {
+	notificationsPb.NotificationsServiceClient
+	notifications map[uint64][]*notificationsPb.Notification
+}
+
+func (f *fakeNotifications) GetNotificationsList(ctx context.Context, in *notificationsPb.GetNotificationsListRequest, opts ...grpc.CallOption) (*notificationsPb.GetNotificationsListResponse, error) {
+	if in.Skip > 0 {
+		return &notificationsPb.GetNotificationsListResponse{}, nil
+	}
+	return &notificationsPb.GetNotificationsListResponse{List: f.notifications[in.CustomerId]}, nil
+}
+
+func review(reviewId, customerId, reviewerId uint64) *reviewsPb.ReviewResponse {
+	return &reviewsPb.ReviewResponse{Review: &reviewsPb.Review{ReviewId: reviewId, CustomerId: customerId, ReviewerId: reviewerId}}
+}
+
+func initTestGuard() (*ownership.Guard, *fakeOffers, *fakeOrders) {
+	offers := &fakeOffers{offers: map[uint64]*offersPb.Offer{
+		1: {OfferId: 1, PostedBy: 10, AppliedBy: []uint64{20, 21}},
+		2: {OfferId: 2, PostedBy: 10, ApprovedFor: 22},
+	}}
+	orders := &fakeOrders{orders: map[uint64]*ordersPb.Order{
+		1: {OrderBasics: &ordersPb.OrderBasics{OrderId: 1, CustomerId: 10, ApplicantId: 20}},
+		2: {OrderBasics: &ordersPb.OrderBasics{OrderId: 2, CustomerId: 11}},
+	}}
+	reviews := &fakeReviews{reviews: map[uint64][]*reviewsPb.ReviewResponse{
+		10: {review(1, 10, 20), review(2, 10, 21), review(3, 10, 22)},
+	}}
+	blog := &fakeBlog{posts: map[uint64][]*blogPb.BlogItem{
+		10: {{Id: 1}, {Id: 2}},
+	}}
+	notifications := &fakeNotifications{notifications: map[uint64][]*notificationsPb.Notification{
+		10: {{Id: 1, CustomerId: 10}},
+	}}
+
+	guard := ownership.InitGuard(offers, orders, reviews, blog, notifications, time.Minute)
+	return guard, offers, orders
+}
+
+func TestGuardChecks(t *testing.T) {
+	guard, _, _ := initTestGuard()
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		resolve     func() (*ownership.Resource, error)
+		customerId  uint64
+		owner       bool
+		participant bool
+		err         error
+	}{
+		{"offer poster", func() (*ownership.Resource, error) { return guard.Offer(ctx, 1) }, 10, true, true, nil},
+		{"offer applicant", func() (*ownership.Resource, error) { return guard.Offer(ctx, 1) }, 21, false, true, nil},
+		{"offer approved", func() (*ownership.Resource, error) { return guard.Offer(ctx, 2) }, 22, false, true, nil},
+		{"offer stranger", func() (*ownership.Resource, error) { return guard.Offer(ctx, 1) }, 30, false, false, nil},
+		{"missing offer", func() (*ownership.Resource, error) { return guard.Offer(ctx, 9) }, 10, false, false, ownership.ErrNotFound},
+		{"order customer", func() (*ownership.Resource, error) { return guard.Order(ctx, 1) }, 10, true, true, nil},
+		{"order applicant", func() (*ownership.Resource, error) { return guard.Order(ctx, 1) }, 20, false, true, nil},
+		{"order without applicant", func() (*ownership.Resource, error) { return guard.Order(ctx, 2) }, 0, false, false, nil},
+		{"order stranger", func() (*ownership.Resource, error) { return guard.Order(ctx, 1) }, 11, false, false, nil},
+		{"missing order", func() (*ownership.Resource, error) { return guard.Order(ctx, 9) }, 10, false, false, ownership.ErrNotFound},
+		{"reviewer", func() (*ownership.Resource, error) { return guard.Review(ctx, 3, 10) }, 22, true, true, nil},
+		{"reviewed customer", func() (*ownership.Resource, error) { return guard.Review(ctx, 3, 10) }, 10, false, true, nil},
+		{"review stranger", func() (*ownership.Resource, error) { return guard.Review(ctx, 1, 10) }, 21, false, false, nil},
+		{"foreign review", func() (*ownership.Resource, error) { return guard.Review(ctx, 1, 11) }, 11, false, false, ownership.ErrNotFound},
+		{"post author", func() (*ownership.Resource, error) { return guard.Post(ctx, 2, 10) }, 10, true, true, nil},
+		{"foreign post", func() (*ownership.Resource, error) { return guard.Post(ctx, 2, 11) }, 11, false, false, ownership.ErrNotFound},
+		{"notification receiver", func() (*ownership.Resource, error) { return guard.Notification(ctx, 1, 10) }, 10, true, true, nil},
+		{"foreign notification", func() (*ownership.Resource, error) { return guard.Notification(ctx, 1, 11) }, 11, false, false, ownership.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, err := tt.resolve()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := resource.IsOwner(tt.customerId); got != tt.owner {
+				t.Errorf("IsOwner(%d) = %v, expected %v", tt.customerId, got, tt.owner)
+			}
+			if got := resource.IsParticipant(tt.customerId); got != tt.participant {
+				t.Errorf("IsParticipant(%d) = %v, expected %v", tt.customerId, got, tt.participant)
+			}
+
+			if err := resource.RequireOwner(tt.customerId); (err == nil) != tt.owner || (err != nil && !errors.Is(err, ownership.ErrForbidden)) {
+				t.Errorf("RequireOwner(%d) = %v", tt.customerId, err)
+			}
+			if err := resource.RequireParticipant(tt.customerId); (err == nil) != tt.participant || (err != nil && !errors.Is(err, ownership.ErrForbidden)) {
+				t.Errorf("RequireParticipant(%d) = %v", tt.customerId, err)
+			}
+		})
+	}
+}
+
+func TestGuardCache(t *testing.T) {
+	guard, offers, _ := initTestGuard()
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := guard.Offer(ctx, 1); err != nil {
+			t.Fatalf("Offer: %v", err)
+		}
+	}
+	if offers.calls != 1 {
+		t.Fatalf("expected a single call to the offers service, got %d", offers.calls)
+	}
+
+	// a new applicant is seen once the cached offer is dropped
+	offers.offers[1].AppliedBy = append(offers.offers[1].AppliedBy, 30)
+	resource, _ := guard.Offer(ctx, 1)
+	if resource.IsParticipant(30) {
+		t.Fatal("expected the cached offer to be served")
+	}
+
+	guard.ForgetOffer(1)
+	resource, _ = guard.Offer(ctx, 1)
+	if !resource.IsParticipant(30) {
+		t.Fatal("expected the offer to be fetched again after ForgetOffer")
+	}
+	if offers.calls != 2 {
+		t.Fatalf("expected two calls to the offers service, got %d", offers.calls)
+	}
+
+	// missing resources are not cached
+	guard.Offer(ctx, 9)
+	guard.Offer(ctx, 9)
+	if offers.calls != 4 {
+		t.Fatalf("expected missing offers to be fetched every time, got %d calls", offers.calls)
+	}
+}
+
+func TestGuardReviewsScannedOnce(t *testing.T) {
+	reviews := &fakeReviews{reviews: map[uint64][]*reviewsPb.ReviewResponse{
+		10: {review(1, 10, 20), review(2, 10, 21), review(3, 10, 22)},
+	}}
+	guard := ownership.InitGuard(nil, nil, reviews, nil, nil, time.Minute)
+	ctx := context.Background()
+
+	// three pages of two: the two full ones and the empty end
+	for _, reviewId := range []uint64{1, 3, 9, 9} {
+		guard.Review(ctx, reviewId, 10)
+	}
+	if reviews.calls != 3 {
+		t.Fatalf("expected the reviews list to be scanned once, got %d calls", reviews.calls)
+	}
+
+	guard.ForgetReview(3, 10)
+	if _, err := guard.Review(ctx, 3, 10); err != nil || reviews.calls != 6 {
+		t.Fatalf("expected the reviews to be scanned again after ForgetReview, got %v and %d calls", err, reviews.calls)
+	}
+}
+
+func TestGuardServiceError(t *testing.T) {
+	guard, _, orders := initTestGuard()
+	orders.err = errors.New("rpc error: code = Unavailable desc = connection refused")
+
+	_, err := guard.Order(context.Background(), 1)
+	if err == nil || errors.Is(err, ownership.ErrNotFound) || errors.Is(err, ownership.ErrForbidden) {
+		t.Fatalf("expected the service error to be passed through, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package preferences_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/preferences"
+)
+
+func TestSettingDefaults(t *testing.T) {
+	var setting preferences.Setting
+	if err := json.Unmarshal([]byte(`{"email": false}`), &setting); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !setting.InApp || setting.Email || !setting.Push {
+		t.Errorf("setting = %+v, want only the emails off", setting)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	p := preferences.Preferences{Areas: map[string]preferences.Setting{
+		"orders": {InApp: true, Email: false, Push: true},
+		"offers": {InApp: true, Email: true, Push: true, MutedUntil: &later},
+	}}
+
+	tests := []struct {
+		area    string
+		channel string
+		at      time.Time
+		want    bool
+	}{
+		{area: "orders", channel: preferences.ChannelInApp, at: now, want: true},
+		{area: "orders", channel: preferences.ChannelEmail, at: now, want: false},
+		{area: "offers", channel: preferences.ChannelInApp, at: now, want: false},
+		// the mute is over
+		{area: "offers", channel: preferences.ChannelInApp, at: later.Add(time.Second), want: true},
+		// an area not configured is on
+		{area: "blog", channel: preferences.ChannelEmail, at: now, want: true},
+	}
+	for _, tt := range tests {
+		if got := p.Allows(tt.area, tt.channel, tt.at); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tt.area, tt.channel, got, tt.want)
+		}
+	}
+
+	p.Areas[preferences.AreaSecurity] = preferences.Setting{}
+	if !p.Allows(preferences.AreaSecurity, preferences.ChannelEmail, now) {
+		t.Error("the security emails are muted")
+	}
+}
+
+func TestStore(t *testing.T) {
+	const customerId = 4242
+	ctx := context.Background()
+
+	rdb := redis.NewClient(config.GetProfileRedisConfig())
+	defer rdb.Close()
+	rdb.Del(ctx, "notification-preferences:4242")
+
+	store := preferences.InitStore(cache.InitCacheService())
+
+	got, err := store.Get(ctx, customerId)
+	if err != nil || len(got.Areas) != 0 {
+		t.Fatalf("Get() = %+v, %v, want no areas", got, err)
+	}
+	if allowed, _ := store.Allows(ctx, customerId, "orders", preferences.ChannelEmail); !allowed {
+		t.Error("Allows() without the preferences = false")
+	}
+
+	past := time.Now().Add(-time.Hour)
+	_, err = store.Set(ctx, customerId, preferences.Preferences{Areas: map[string]preferences.Setting{
+		"offers": {InApp: true, Email: true, Push: true, MutedUntil: &past},
+	}})
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	saved, err := store.SetArea(ctx, customerId, "orders", preferences.Setting{InApp: true})
+	if err != nil {
+		t.Fatalf("SetArea() error = %v", err)
+	}
+	if saved.Areas["offers"].MutedUntil != nil {
+		t.Error("the expired mute is kept")
+	}
+	if allowed, _ := store.Allows(ctx, customerId, "orders", preferences.ChannelEmail); allowed {
+		t.Error("Allows() of the muted emails = true")
+	}
+	if allowed, _ := store.Allows(ctx, customerId, "orders", preferences.ChannelInApp); !allowed {
+		t.Error("Allows() of the in-app notifications = false")
+	}
+
+	for _, area := range []string{preferences.AreaSecurity, "Orders", ""} {
+		if _, err = store.SetArea(ctx, customerId, area, preferences.Default()); !errors.Is(err, preferences.ErrInvalidArea) {
+			t.Errorf("SetArea(%q) error = %v, want ErrInvalidArea", area, err)
+		}
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	p := preferences.Preferences{Areas: map[string]preferences.Setting{"orders": {}}}.WithDefaults()
+
+	if p.Areas["orders"].InApp {
+		t.Error("the configured area is replaced by the default")
+	}
+	if p.Areas["offers"] != preferences.Default() {
+		t.Errorf("offers = %+v, want the default", p.Areas["offers"])
+	}
+}
@@ -0,0 +1,125 @@
+package rbac_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/rbac"
+	"github.com/noo8xl/anvil-gateway/router"
+)
+
+// fakeAuditor -> keeps the recorded events
+type fakeAuditor struct {
+	events []audit.Event
+}
+
+func (f *fakeAuditor) Record(ctx context.Context, event audit.Event) {
+	f.events = append(f.events, event)
+}
+
+// fakeValidator -> resolves a token to the customer with the same role
+type fakeValidator struct{}
+
+func (f *fakeValidator) ValidateToken(ctx context.Context, token string) (*authPb.CustomerDto, middlewares.TokenGrant, error) {
+	return &authPb.CustomerDto{CustomerId: 7, Role: token}, middlewares.TokenGrant{}, nil
+}
+
+func initTestAuthorizer() (*rbac.Authorizer, *fakeAuditor) {
+	auditor := &fakeAuditor{}
+	cfg := config.RBACConfig{Roles: map[string][]string{
+		"ADMIN":      {"*"},
+		"SUPERVISOR": {rbac.AdminAccess, "orders:*"},
+		"SUPPORT":    {rbac.AdminAccess},
+	}}
+	return rbac.InitAuthorizer(cfg, auditor), auditor
+}
+
+func TestCan(t *testing.T) {
+	authorizer, _ := initTestAuthorizer()
+
+	tests := []struct {
+		role       string
+		permission string
+		expected   bool
+	}{
+		{"ADMIN", rbac.CustomersBlock, true},
+		{"SUPERVISOR", rbac.OrdersUpdateApplied, true},
+		{"SUPERVISOR", rbac.CustomersBlock, false},
+		{"SUPPORT", rbac.AdminAccess, true},
+		{"SUPPORT", rbac.OrdersDeleteApplied, false},
+		{"CUSTOMER", rbac.AdminAccess, false},
+		{"", rbac.AdminAccess, false},
+	}
+
+	for _, tt := range tests {
+		if got := authorizer.Can(tt.role, tt.permission); got != tt.expected {
+			t.Errorf("%s %s: expected %v, got %v", tt.role, tt.permission, tt.expected, got)
+		}
+	}
+}
+
+func TestAuthorizeRecordsDenials(t *testing.T) {
+	authorizer, auditor := initTestAuthorizer()
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/orders/update/", nil)
+	req.RemoteAddr = "192.0.2.10:5555"
+	ctx := context.WithValue(req.Context(), middlewares.CustomerKey, &authPb.CustomerDto{CustomerId: 3, Role: "SUPPORT"})
+	req = req.WithContext(ctx)
+
+	if !authorizer.Authorize(req, rbac.AdminAccess) {
+		t.Fatal("expected the granted permission to be authorized")
+	}
+	if len(auditor.events) != 0 {
+		t.Fatalf("expected no audit events, got %d", len(auditor.events))
+	}
+
+	if authorizer.Authorize(req, rbac.OrdersUpdateApplied) {
+		t.Fatal("expected the permission to be denied")
+	}
+	if len(auditor.events) != 1 {
+		t.Fatalf("expected one audit event, got %d", len(auditor.events))
+	}
+
+	event := auditor.events[0]
+	if event.ActorId != 3 || event.Action != rbac.OrdersUpdateApplied || event.Outcome != audit.OutcomeDenied || event.Ip != "192.0.2.10" {
+		t.Errorf("unexpected audit event %+v", event)
+	}
+}
+
+func TestRequirePermissionsPolicy(t *testing.T) {
+	authorizer, auditor := initTestAuthorizer()
+
+	mux := http.NewServeMux()
+	policies := middlewares.InitPolicies()
+	policies.UsePermissions(authorizer)
+
+	api := router.InitRouter(mux, policies)
+	api.Version("v1").HandleFunc("POST /admin/customers/block/{customerId}/", middlewares.RequirePermissions(rbac.CustomersBlock), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	if err := api.Mount(); err != nil {
+		t.Fatalf("error mounting routes: %v", err)
+	}
+	handler := middlewares.AuthMiddleware(mux, policies, &fakeValidator{})
+
+	for role, expected := range map[string]int{"ADMIN": http.StatusOK, "SUPERVISOR": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/customers/block/7/", nil)
+		req.Header.Set("Authorization", "Bearer "+role)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != expected {
+			t.Errorf("%s: expected %d, got %d", role, expected, rec.Code)
+		}
+	}
+
+	if len(auditor.events) != 1 || auditor.events[0].ActorRole != "SUPERVISOR" {
+		t.Errorf("expected the denied request to be audited, got %+v", auditor.events)
+	}
+}
@@ -0,0 +1,159 @@
+package realtime_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/fanout"
+	"github.com/noo8xl/anvil-gateway/realtime"
+)
+
+var testConfig = config.StreamConfig{
+	Heartbeat:      time.Minute,
+	RetryDelay:     3 * time.Second,
+	BufferSize:     4,
+	MaxConnections: 2,
+	WebSocket:      true,
+}
+
+// start -> a hub listening to the published events, stopped with the test
+func start(t *testing.T, cfg config.StreamConfig) *realtime.Hub {
+	t.Helper()
+
+	hub := realtime.InitHub(cfg, cache.InitCacheService(), zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		hub.Close()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !hub.Listening() {
+		if time.Now().After(deadline) {
+			t.Fatal("the hub isn't listening")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hub
+}
+
+func subscribe(t *testing.T, hub *realtime.Hub, customerId uint64) *realtime.Subscription {
+	t.Helper()
+
+	s, err := hub.Subscribe(customerId)
+	if err != nil {
+		t.Fatalf("Subscribe(%d) error = %v", customerId, err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestPublishFillsEvent(t *testing.T) {
+	hub := start(t, testConfig)
+	s := subscribe(t, hub, 7)
+
+	if err := hub.Publish(context.Background(), 7, realtime.Event{Title: "Order Rejected", Body: "body", Area: "orders"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case event := <-s.Items():
+		if event.Id == "" || event.CreatedAt == "" || event.Title != "Order Rejected" || event.Area != "orders" {
+			t.Errorf("event = %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	// the limit is of the customer's streams
+	subscribe(t, hub, 7)
+	if _, err := hub.Subscribe(7); !errors.Is(err, fanout.ErrTooManyStreams) {
+		t.Errorf("Subscribe() over the limit error = %v, want ErrTooManyStreams", err)
+	}
+}
+
+// serve -> a server streaming the events of the customer 7
+func serve(t *testing.T, hub *realtime.Hub, stream func(http.ResponseWriter, *http.Request, *realtime.Subscription)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := hub.Subscribe(7)
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		defer s.Close()
+		stream(w, r, s)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServeSSE(t *testing.T) {
+	cfg := testConfig
+	cfg.Heartbeat = 50 * time.Millisecond
+	hub := start(t, cfg)
+	server := serve(t, hub, hub.ServeSSE)
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer response.Body.Close()
+
+	if ct := response.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	lines := bufio.NewScanner(response.Body)
+	next := func() string {
+		for lines.Scan() {
+			if line := lines.Text(); line != "" {
+				return line
+			}
+		}
+		return ""
+	}
+
+	if line := next(); line != "retry: 3000" {
+		t.Errorf("first line = %q, want the retry delay", line)
+	}
+	if line := next(); line != ": heartbeat" {
+		t.Errorf("idle stream line = %q, want a heartbeat", line)
+	}
+
+	hub.Publish(context.Background(), 7, realtime.Event{Id: "1", Title: "Order Rejected"})
+
+	line := next()
+	for line == ": heartbeat" {
+		line = next()
+	}
+	if line != "id: 1" || next() != "event: notification" {
+		t.Fatalf("event line = %q", line)
+	}
+	data, ok := strings.CutPrefix(next(), "data: ")
+	var event realtime.Event
+	if !ok || json.Unmarshal([]byte(data), &event) != nil || event.Title != "Order Rejected" {
+		t.Errorf("data = %q", data)
+	}
+
+	// the stream ends with the hub
+	hub.Close()
+	for lines.Scan() {
+	}
+	if err = lines.Err(); err != nil {
+		t.Errorf("stream error = %v, want the end of it", err)
+	}
+}
@@ -0,0 +1,191 @@
+package router_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/middlewares"
+	"github.com/noo8xl/anvil-gateway/router"
+)
+
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"Name": "John Doe"})
+}
+
+func initTestRouter(t *testing.T) *http.ServeMux {
+	mux := http.NewServeMux()
+	api := router.InitRouter(mux, middlewares.InitPolicies())
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	v1 := api.Version("v1")
+	v1.HandleFunc("GET /profile/get/", middlewares.Authenticated(), profileHandler)
+	v1.HandleFunc("GET /profile/legacy/", middlewares.Authenticated(), profileHandler).Deprecate(router.Deprecation{Sunset: sunset})
+
+	v2 := api.Version("v2").Inherit(v1)
+	v2.Remove("GET /profile/legacy/")
+	if _, err := v2.Map("GET /profile/get/", nil, router.MapJSONResponse(func(body map[string]any) (map[string]any, error) {
+		return map[string]any{"profile": body}, nil
+	})); err != nil {
+		t.Fatalf("error mapping v2 route: %v", err)
+	}
+
+	if err := api.Mount(); err != nil {
+		t.Fatalf("error mounting routes: %v", err)
+	}
+	return mux
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	mux := initTestRouter(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{name: "v1 route", path: "/api/v1/profile/get/", status: http.StatusOK, body: `{"Name":"John Doe"}`},
+		{name: "v2 mapped route", path: "/api/v2/profile/get/", status: http.StatusOK, body: `{"profile":{"Name":"John Doe"}}`},
+		{name: "v1 deprecated route", path: "/api/v1/profile/legacy/", status: http.StatusOK, body: `{"Name":"John Doe"}`},
+		{name: "v2 removed route", path: "/api/v2/profile/legacy/", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("error: status is %d, expected %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+				t.Errorf("error: body is %s, expected %s", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	mux := initTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/profile/legacy/", nil))
+
+	if rec.Header().Get("Deprecation") == "" {
+		t.Errorf("error: Deprecation header is empty")
+	}
+	if rec.Header().Get("Sunset") != "Tue, 01 Jan 2030 00:00:00 GMT" {
+		t.Errorf("error: Sunset header is %q", rec.Header().Get("Sunset"))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/", nil))
+	if rec.Header().Get("Deprecation") != "" {
+		t.Errorf("error: not deprecated route has a Deprecation header")
+	}
+}
+
+func TestMountWithoutPolicy(t *testing.T) {
+	api := router.InitRouter(http.NewServeMux(), middlewares.InitPolicies())
+	api.Handle("/health/", middlewares.Public(), http.HandlerFunc(profileHandler))
+
+	v1 := api.Version("v1")
+	v1.HandleFunc("GET /profile/get/", middlewares.Authenticated(), profileHandler)
+	v1.HandleFunc("GET /profile/unsafe/", middlewares.Policy{}, profileHandler)
+
+	err := api.Mount()
+	if err == nil {
+		t.Fatalf("error: routes without a policy are mounted")
+	}
+	if !strings.Contains(err.Error(), "GET /api/v1/profile/unsafe/") {
+		t.Errorf("error: undeclared route is not reported: %v", err)
+	}
+}
+
+// backends -> the availability of the backend services by name
+type backends map[string]bool
+
+func (b backends) Available(service string) bool {
+	available, ok := b[service]
+	return !ok || available
+}
+
+func TestRequiredBackendUnavailable(t *testing.T) {
+	mux := http.NewServeMux()
+	api := router.InitRouter(mux, middlewares.InitPolicies())
+	api.UseAvailability(backends{"profile": true, "orders": false})
+
+	v1 := api.Version("v1")
+	v1.HandleFunc("GET /profile/get/", middlewares.Public(), profileHandler).Requires("profile")
+	v1.HandleFunc("GET /orders/get/", middlewares.Public(), profileHandler).Requires("profile", "orders")
+	if err := api.Mount(); err != nil {
+		t.Fatalf("error mounting routes: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("error: a route with available backends got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/orders/get/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("error: want 503 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "orders service is unavailable") {
+		t.Errorf("error: unexpected body %s", rec.Body.String())
+	}
+}
+
+func TestMappedRouteRequires(t *testing.T) {
+	mux := http.NewServeMux()
+	api := router.InitRouter(mux, middlewares.InitPolicies())
+	api.UseAvailability(backends{"profile": true, "orders": false})
+
+	v1 := api.Version("v1")
+	v1.HandleFunc("GET /profile/get/", middlewares.Public(), profileHandler).Requires("profile")
+
+	v2 := api.Version("v2").Inherit(v1)
+	mapped, err := v2.Map("GET /profile/get/", nil, nil)
+	if err != nil {
+		t.Fatalf("error mapping v2 route: %v", err)
+	}
+	mapped.Requires("orders")
+	if err := api.Mount(); err != nil {
+		t.Fatalf("error mounting routes: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/profile/get/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("error: the base route got %d after the mapped one required orders", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/profile/get/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("error: the mapped route got %d, expected 503", rec.Code)
+	}
+}
+
+func TestMapJSONBodyKeepsEmptyBody(t *testing.T) {
+	mapper := router.MapJSONBody(func(body map[string]any) (map[string]any, error) {
+		return body, nil
+	})
+
+	mapped, err := mapper(httptest.NewRequest(http.MethodPost, "/api/v2/profile/get/", nil))
+	if err != nil {
+		t.Fatalf("error mapping the body: %v", err)
+	}
+	if mapped.ContentLength != 0 || mapped.Body != http.NoBody {
+		t.Errorf("error: an empty body is mapped to %d bytes", mapped.ContentLength)
+	}
+}
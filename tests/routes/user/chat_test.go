@@ -0,0 +1,148 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	ordersPb "github.com/noo8xl/anvil-api/main/orders"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/chat"
+	"github.com/noo8xl/anvil-gateway/config"
+	routes "github.com/noo8xl/anvil-gateway/routes/user"
+)
+
+// the order 1 of the customer 10 is taken by the applicant 20
+const (
+	orderId     = 1
+	orderOwner  = 10
+	applicantId = 20
+	strangerId  = 30
+)
+
+// fakeOrders -> serves the orders by id
+type fakeOrders struct {
+	ordersPb.OrdersServiceClient
+	orders map[uint64]*ordersPb.Order
+}
+
+func (f *fakeOrders) GetOrderDetails(ctx context.Context, in *ordersPb.GetOrderDetailsRequest, opts ...grpc.CallOption) (*ordersPb.Order, error) {
+	if order, ok := f.orders[in.OrderId]; ok {
+		return order, nil
+	}
+	return nil, errors.New("rpc error: code = NotFound desc = order not found")
+}
+
+// initChatHandler -> a handler of the chats kept in memory about the orders
+func initChatHandler() (*routes.Handler, *fakeOrders) {
+	orders := &fakeOrders{orders: map[uint64]*ordersPb.Order{
+		orderId: {OrderBasics: &ordersPb.OrderBasics{OrderId: orderId, CustomerId: orderOwner, ApplicantId: applicantId}},
+	}}
+	chats := chat.InitHub(config.StreamConfig{BufferSize: 1, MaxConnections: 1}, cache.InitCacheService(), zap.NewNop())
+	h := routes.InitHandler(nil, nil, orders, nil, nil, nil, nil, nil,
+		nil, nil, "", nil, nil, &recorder{}, nil, nil, nil, nil, nil, chat.InitMemoryClient(), chats)
+	return h, orders
+}
+
+func createChat(h *routes.Handler, customerId, participantId uint64) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"subject": "order", "subjectId": %d, "participantId": %d}`, orderId, participantId)
+	rec := httptest.NewRecorder()
+	h.CreateChatHandler(rec, request(http.MethodPost, "/api/v1/chat/create-chat/", body, &authPb.CustomerDto{CustomerId: customerId}))
+	return rec
+}
+
+func getMessages(h *routes.Handler, chatId, customerId uint64) *httptest.ResponseRecorder {
+	r := request(http.MethodGet, "/api/v1/chat/get-chat-messages-list/", "", &authPb.CustomerDto{CustomerId: customerId})
+	r.SetPathValue("chatId", strconv.FormatUint(chatId, 10))
+	r.SetPathValue("skip", "0")
+	rec := httptest.NewRecorder()
+	h.GetChatMessagesListHandler(rec, r)
+	return rec
+}
+
+func sendMessage(h *routes.Handler, chatId, customerId uint64) *httptest.ResponseRecorder {
+	r := request(http.MethodPost, "/api/v1/chat/send-message/", `{"body": "hello"}`, &authPb.CustomerDto{CustomerId: customerId})
+	r.SetPathValue("chatId", strconv.FormatUint(chatId, 10))
+	rec := httptest.NewRecorder()
+	h.SendChatMessageHandler(rec, r)
+	return rec
+}
+
+// openChat -> the chat of the order owner with the applicant
+func openChat(t *testing.T, h *routes.Handler) uint64 {
+	t.Helper()
+
+	rec := createChat(h, orderOwner, 0)
+	var opened chat.Chat
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &opened) != nil {
+		t.Fatalf("create chat got %d: %s", rec.Code, rec.Body.String())
+	}
+	return opened.Id
+}
+
+func TestChatOfParticipants(t *testing.T) {
+	h, _ := initChatHandler()
+	chatId := openChat(t, h)
+
+	for _, customerId := range []uint64{orderOwner, applicantId} {
+		if rec := getMessages(h, chatId, customerId); rec.Code != http.StatusOK {
+			t.Errorf("messages of the participant %d got %d", customerId, rec.Code)
+		}
+		if rec := sendMessage(h, chatId, customerId); rec.Code != http.StatusCreated {
+			t.Errorf("message of the participant %d got %d", customerId, rec.Code)
+		}
+	}
+}
+
+func TestChatOfNonParticipant(t *testing.T) {
+	h, _ := initChatHandler()
+	chatId := openChat(t, h)
+
+	if rec := createChat(h, strangerId, orderOwner); rec.Code != http.StatusForbidden {
+		t.Errorf("create chat of a non-participant got %d, want 403", rec.Code)
+	}
+	if rec := getMessages(h, chatId, strangerId); rec.Code != http.StatusForbidden {
+		t.Errorf("messages of a non-participant got %d, want 403", rec.Code)
+	}
+	if rec := sendMessage(h, chatId, strangerId); rec.Code != http.StatusForbidden {
+		t.Errorf("message of a non-participant got %d, want 403", rec.Code)
+	}
+}
+
+func TestChatOfRemovedParticipant(t *testing.T) {
+	h, orders := initChatHandler()
+	chatId := openChat(t, h)
+
+	// the order is given to another applicant
+	orders.orders[orderId].OrderBasics.ApplicantId = strangerId
+	h.Owners().ForgetOrder(orderId)
+
+	if rec := getMessages(h, chatId, applicantId); rec.Code != http.StatusForbidden {
+		t.Errorf("messages of a removed participant got %d, want 403", rec.Code)
+	}
+	if rec := sendMessage(h, chatId, applicantId); rec.Code != http.StatusForbidden {
+		t.Errorf("message of a removed participant got %d, want 403", rec.Code)
+	}
+	if rec := getMessages(h, chatId, orderOwner); rec.Code != http.StatusOK {
+		t.Errorf("messages of the owner got %d", rec.Code)
+	}
+}
+
+func TestCreateChatWithNonParticipant(t *testing.T) {
+	h, _ := initChatHandler()
+
+	for _, participantId := range []uint64{strangerId, orderOwner} {
+		if rec := createChat(h, orderOwner, participantId); rec.Code != http.StatusBadRequest {
+			t.Errorf("owner picking the participant %d got %d, want 400", participantId, rec.Code)
+		}
+	}
+}
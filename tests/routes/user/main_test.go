@@ -0,0 +1,67 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"go.uber.org/zap"
+
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/bruteforce"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+// recorder -> an auditor keeping the recorded events
+type recorder struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (r *recorder) Record(ctx context.Context, event audit.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// last -> the latest recorded event
+func (r *recorder) last() audit.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return audit.Event{}
+	}
+	return r.events[len(r.events)-1]
+}
+
+var lastCustomerId atomic.Uint64
+
+// testCustomerId -> a customer id no other test run has used
+func testCustomerId() uint64 {
+	lastCustomerId.CompareAndSwap(0, uint64(time.Now().UnixNano()))
+	return lastCustomerId.Add(1)
+}
+
+func initTestGuard() *bruteforce.Guard {
+	cfg := config.AuthConfig{
+		MaxFailedAttempts:    2,
+		FailedAttemptsWindow: time.Minute,
+		LockoutDuration:      time.Minute,
+		MaxAttemptDelay:      time.Second,
+		MaxCodeAttempts:      2,
+	}
+	return bruteforce.InitGuard(cfg, cache.InitCacheService(), zap.NewNop())
+}
+
+// request -> a request of the authenticated customer
+func request(method, target, body string, customer *authPb.CustomerDto) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	return r.WithContext(context.WithValue(r.Context(), middlewares.CustomerKey, customer))
+}
@@ -0,0 +1,104 @@
+package routes_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+
+	"github.com/noo8xl/anvil-gateway/audit"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/otp"
+	routes "github.com/noo8xl/anvil-gateway/routes/user"
+)
+
+// initTwoStepHandler -> a handler of the two-step status, the switch from
+// the authenticator app doesn't reach any backend service
+func initTwoStepHandler() (*routes.Handler, *recorder) {
+	auditor := &recorder{}
+	h := routes.InitHandler(nil, nil, nil, nil, nil, nil, nil, nil,
+		nil, nil, "", initTestGuard(), nil, auditor, nil, nil, nil, nil, nil, nil, nil)
+	return h, auditor
+}
+
+// enrollTOTP -> a customer with the authenticator app enabled
+func enrollTOTP(t *testing.T) (*authPb.CustomerDto, string) {
+	t.Helper()
+
+	id := testCustomerId()
+	secret, err := otp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	store := cache.InitCacheService()
+	if err = store.SetTOTPSecret(context.Background(), id, secret, false); err != nil {
+		t.Fatalf("SetTOTPSecret() error = %v", err)
+	}
+	if err = store.SetTwoStepMethod(context.Background(), id, otp.MethodTOTP); err != nil {
+		t.Fatalf("SetTwoStepMethod() error = %v", err)
+	}
+	return &authPb.CustomerDto{CustomerId: id, Email: fmt.Sprintf("switch-%d@test.com", id)}, secret
+}
+
+func switchToEmail(h *routes.Handler, customer *authPb.CustomerDto, code string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"customerId": %d, "isEnabled": true, "email": %q, "code": %q, "method": "email"}`,
+		customer.CustomerId, customer.Email, code)
+	rec := httptest.NewRecorder()
+	h.ChangeTwoStepStatusHandler(rec, request(http.MethodPatch, "/api/v1/profile/security/change-two-step-status/", body, customer))
+	return rec
+}
+
+func TestSwitchTwoStepRequiresCode(t *testing.T) {
+	h, auditor := initTwoStepHandler()
+	customer, _ := enrollTOTP(t)
+	store := cache.InitCacheService()
+
+	for _, code := range []string{"", "000000"} {
+		if rec := switchToEmail(h, customer, code); rec.Code != http.StatusBadRequest {
+			t.Errorf("switch with the code %q got %d, want 400", code, rec.Code)
+		}
+		if event := auditor.last(); event.Action != "profile.two_step.switch" || event.Outcome != audit.OutcomeFailure {
+			t.Errorf("switch with the code %q recorded %+v", code, event)
+		}
+	}
+
+	if method, _ := store.GetTwoStepMethod(context.Background(), customer.CustomerId); method != otp.MethodTOTP {
+		t.Errorf("two-step method = %q, want the authenticator app kept", method)
+	}
+	if _, err := store.GetTOTPSecret(context.Background(), customer.CustomerId, false); err != nil {
+		t.Errorf("the authenticator secret is dropped: %v", err)
+	}
+
+	// the rejected codes are counted as the failed two-step attempts
+	if rec := switchToEmail(h, customer, "000000"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("switch after the failed attempts got %d, want 429", rec.Code)
+	}
+}
+
+func TestSwitchTwoStepByCode(t *testing.T) {
+	h, auditor := initTwoStepHandler()
+	customer, secret := enrollTOTP(t)
+	store := cache.InitCacheService()
+
+	code, err := otp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+	if rec := switchToEmail(h, customer, code); rec.Code != http.StatusOK {
+		t.Fatalf("switch with a valid code got %d: %s", rec.Code, rec.Body.String())
+	}
+	if event := auditor.last(); event.Action != "profile.two_step.switch" || event.Outcome != audit.OutcomeSuccess {
+		t.Errorf("switch recorded %+v", event)
+	}
+
+	if method, _ := store.GetTwoStepMethod(context.Background(), customer.CustomerId); method != otp.MethodEmail {
+		t.Errorf("two-step method = %q, want email", method)
+	}
+	if _, err := store.GetTOTPSecret(context.Background(), customer.CustomerId, false); err == nil {
+		t.Errorf("the authenticator secret is kept after the switch")
+	}
+}
@@ -0,0 +1,72 @@
+package shutdown_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/noo8xl/anvil-gateway/shutdown"
+)
+
+func TestCoordinatorPhases(t *testing.T) {
+	coordinator := shutdown.InitCoordinator(zap.NewNop())
+
+	var order []string
+	coordinator.Add("first", time.Second, func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	coordinator.Add("stuck", 20*time.Millisecond, func(ctx context.Context) error {
+		order = append(order, "stuck")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	coordinator.Add("failed", time.Second, func(ctx context.Context) error {
+		order = append(order, "failed")
+		return errors.New("boom")
+	})
+	coordinator.Add("last", time.Second, func(ctx context.Context) error {
+		order = append(order, "last")
+		return nil
+	})
+
+	err := coordinator.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || err == nil {
+		t.Errorf("want the timed out and the failed phases reported, got %v", err)
+	}
+	if len(order) != 4 || order[0] != "first" || order[3] != "last" {
+		t.Errorf("every phase should run in order, got %v", order)
+	}
+}
+
+func TestJobsWait(t *testing.T) {
+	jobs := shutdown.InitJobs()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var done atomic.Bool
+	jobs.Go(ctx, func(ctx context.Context) {
+		time.Sleep(30 * time.Millisecond)
+		if ctx.Err() == nil {
+			done.Store(true)
+		}
+	})
+	// the request is gone right after the job is started
+	cancel()
+
+	if err := jobs.Wait(context.Background()); err != nil || !done.Load() {
+		t.Errorf("the job should finish with a live context, err = %v", err)
+	}
+
+	jobs.Go(context.Background(), func(ctx context.Context) {
+		time.Sleep(time.Second)
+	})
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	if err := jobs.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want the deadline exceeded", err)
+	}
+}
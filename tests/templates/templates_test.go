@@ -0,0 +1,169 @@
+package templates_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/templates"
+)
+
+func load(t *testing.T, cfg config.TemplatesConfig) *templates.Catalog {
+	t.Helper()
+
+	catalog, err := templates.Load(cfg)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return catalog
+}
+
+func TestRenderLocales(t *testing.T) {
+	catalog := load(t, config.TemplatesConfig{DefaultLocale: "en", EmailFormat: "text"})
+	data := templates.Data{"OrderTitle": "Logo design"}
+
+	tests := []struct {
+		locale string
+		title  string
+	}{
+		{locale: "en", title: "New Order Invitation"},
+		{locale: "ru", title: "Новое приглашение к заказу"},
+		// no translation, the default locale is used
+		{locale: "de", title: "New Order Invitation"},
+	}
+
+	for _, tt := range tests {
+		rendered, err := catalog.Render(templates.OrderInvited, tt.locale, data)
+		if err != nil {
+			t.Fatalf("Render(%s) error = %v", tt.locale, err)
+		}
+		if rendered.Title != tt.title {
+			t.Errorf("Render(%s) title = %q, want %q", tt.locale, rendered.Title, tt.title)
+		}
+		if !strings.Contains(rendered.Text, "Logo design") {
+			t.Errorf("Render(%s) text = %q, want the order title", tt.locale, rendered.Text)
+		}
+	}
+
+	if _, err := catalog.Render("unknown", "en", nil); err == nil {
+		t.Error("Render() of an unknown template succeeded")
+	}
+	if _, err := catalog.Render(templates.OrderInvited, "en", nil); err == nil {
+		t.Error("Render() without the template data succeeded")
+	}
+}
+
+func TestEveryTemplateRenders(t *testing.T) {
+	catalog := load(t, config.TemplatesConfig{DefaultLocale: "en", EmailFormat: "text"})
+	data := templates.Data{"OrderTitle": "title", "OrderId": 1, "Completed": true, "Code": "123456", "Link": "https://example.com"}
+
+	for _, locale := range catalog.Locales() {
+		for _, name := range templates.Names {
+			rendered, err := catalog.Render(name, locale, data)
+			if err != nil {
+				t.Errorf("Render(%s, %s) error = %v", name, locale, err)
+				continue
+			}
+			if rendered.Title == "" || rendered.Text == "" || rendered.Html == "" {
+				t.Errorf("Render(%s, %s) = %+v, want every variant", name, locale, rendered)
+			}
+		}
+	}
+}
+
+func TestEmailVariants(t *testing.T) {
+	data := templates.Data{"OrderTitle": "<script>alert(1)</script>"}
+
+	rendered, err := load(t, config.TemplatesConfig{DefaultLocale: "en", EmailFormat: "html"}).Render(templates.OrderInvited, "en", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(rendered.Html, "<script>") || !strings.Contains(rendered.Html, "&lt;script&gt;") {
+		t.Errorf("html = %q, want the data escaped", rendered.Html)
+	}
+	if !strings.Contains(rendered.Text, "<script>") {
+		t.Errorf("text = %q, want the data as is", rendered.Text)
+	}
+	if rendered.EmailBody() != rendered.Html {
+		t.Errorf("EmailBody() = %q, want the html variant", rendered.EmailBody())
+	}
+
+	rendered, _ = load(t, config.TemplatesConfig{DefaultLocale: "en", EmailFormat: "text"}).Render(templates.OrderInvited, "en", data)
+	if rendered.EmailBody() != rendered.Text {
+		t.Errorf("EmailBody() = %q, want the text variant", rendered.EmailBody())
+	}
+}
+
+func TestMatch(t *testing.T) {
+	catalog := load(t, config.TemplatesConfig{DefaultLocale: "en", EmailFormat: "text"})
+
+	tests := map[string]string{
+		"ru-RU,ru;q=0.9,en;q=0.8": "ru",
+		"de-DE,en;q=0.5":          "en",
+		"de":                      "en",
+		"en-GB":                   "en",
+		"":                        "en",
+		"not a header;;":          "en",
+	}
+	for header, want := range tests {
+		if got := catalog.Match(header); got != want {
+			t.Errorf("Match(%q) = %q, want %q", header, got, want)
+		}
+	}
+
+	if locale, ok := catalog.Resolve("ru-RU"); !ok || locale != "ru" {
+		t.Errorf("Resolve(ru-RU) = %q, %v, want ru", locale, ok)
+	}
+	if _, ok := catalog.Resolve("de"); ok {
+		t.Error("Resolve(de) succeeded without the german templates")
+	}
+}
+
+func TestOverrideFromDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file, source string) {
+		path := filepath.Join(dir, file)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("en/order_rejected.tmpl", `{{define "title"}}Rejected{{end}}{{define "text"}}Sorry!{{end}}`)
+	write("de/order_rejected.tmpl", `{{define "title"}}Abgelehnt{{end}}{{define "text"}}Leider!{{end}}`)
+
+	catalog := load(t, config.TemplatesConfig{Dir: dir, DefaultLocale: "en", EmailFormat: "html"})
+
+	rendered, err := catalog.Render(templates.OrderRejected, "en", nil)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	// a template without the html variant is emailed as the escaped text
+	if rendered.Title != "Rejected" || rendered.Text != "Sorry!" || rendered.EmailBody() != "Sorry!" {
+		t.Errorf("Render() = %+v, want the overridden template", rendered)
+	}
+
+	if !slices.Contains(catalog.Locales(), "de") || catalog.Match("de-AT") != "de" {
+		t.Errorf("Locales() = %v, want the added locale", catalog.Locales())
+	}
+	rendered, _ = catalog.Render(templates.OrderRejected, "de", nil)
+	if rendered.Title != "Abgelehnt" {
+		t.Errorf("Render(de) title = %q", rendered.Title)
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	// the default locale has to provide every template
+	if _, err := templates.Load(config.TemplatesConfig{DefaultLocale: "de", EmailFormat: "text"}); err == nil {
+		t.Error("Load() succeeded without the templates of the default locale")
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "en"), 0o755)
+	os.WriteFile(filepath.Join(dir, "en", "order_rejected.tmpl"), []byte(`{{define "title"}}Rejected{{end}}`), 0o644)
+	if _, err := templates.Load(config.TemplatesConfig{Dir: dir, DefaultLocale: "en", EmailFormat: "text"}); err == nil {
+		t.Error("Load() succeeded with a template missing its text")
+	}
+}
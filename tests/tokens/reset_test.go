@@ -0,0 +1,77 @@
+package tokens_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/tokens"
+)
+
+func initTestResets(t *testing.T) *tokens.PasswordResets {
+	cfg := config.AuthConfig{SessionSecret: "test-secret", PasswordResetTTL: time.Minute}
+
+	verifier, err := tokens.InitVerifier(cfg, cache.InitCacheService(), &fakeAuthClient{})
+	if err != nil {
+		t.Fatalf("error initializing verifier: %v", err)
+	}
+	return tokens.InitPasswordResets(cfg, verifier, cache.InitCacheService())
+}
+
+func TestPasswordResetToken(t *testing.T) {
+	resets := initTestResets(t)
+
+	token, err := resets.Issue(context.Background(), 42, "test@test.com")
+	if err != nil {
+		t.Fatalf("error issuing reset token: %v", err)
+	}
+
+	reset, err := resets.Consume(context.Background(), token)
+	if err != nil {
+		t.Fatalf("error consuming reset token: %v", err)
+	}
+	if reset.CustomerId != 42 || reset.Email != "test@test.com" {
+		t.Errorf("error: unexpected reset request %+v", reset)
+	}
+
+	if _, err = resets.Consume(context.Background(), token); !errors.Is(err, tokens.ErrInvalidResetToken) {
+		t.Errorf("error: reset token is accepted twice")
+	}
+}
+
+func TestRejectedPasswordResetTokens(t *testing.T) {
+	resets := initTestResets(t)
+
+	replaced, err := resets.Issue(context.Background(), 43, "test@test.com")
+	if err != nil {
+		t.Fatalf("error issuing reset token: %v", err)
+	}
+	latest, err := resets.Issue(context.Background(), 43, "test@test.com")
+	if err != nil {
+		t.Fatalf("error issuing reset token: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "replaced token", token: replaced},
+		{name: "forged signature", token: latest[:len(latest)-2] + "xx"},
+		{name: "unsigned token", token: "random-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := resets.Consume(context.Background(), tt.token); !errors.Is(err, tokens.ErrInvalidResetToken) {
+				t.Errorf("error: token is accepted")
+			}
+		})
+	}
+
+	if _, err = resets.Consume(context.Background(), latest); err != nil {
+		t.Errorf("error: the latest reset token is rejected: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package tokens_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/tokens"
+)
+
+func initTestSessions(t *testing.T) (*tokens.Sessions, *tokens.Verifier) {
+	cfg := config.AuthConfig{
+		SessionSecret:   "test-secret",
+		TokenCacheTTL:   time.Minute,
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}
+
+	verifier, err := tokens.InitVerifier(cfg, cache.InitCacheService(), &fakeAuthClient{})
+	if err != nil {
+		t.Fatalf("error initializing verifier: %v", err)
+	}
+	return tokens.InitSessions(cfg, verifier, cache.InitCacheService()), verifier
+}
+
+var sessionCustomer = &authPb.CustomerDto{CustomerId: 42, Email: "test@test.com", Role: "USER"}
+
+func TestSessionAccessToken(t *testing.T) {
+	sessions, verifier := initTestSessions(t)
+
+	pair, err := sessions.Create(context.Background(), sessionCustomer, tokens.SessionMeta{Ip: "127.0.0.1", UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("error creating session: %v", err)
+	}
+
+	customer, grant, err := verifier.ValidateToken(context.Background(), pair.AccessToken)
+	if err != nil {
+		t.Fatalf("error validating session access token: %v", err)
+	}
+	if customer.CustomerId != 42 || grant.SessionId != pair.SessionId {
+		t.Errorf("error: unexpected token customer %+v and session %s", customer, grant.SessionId)
+	}
+
+	list, err := sessions.List(context.Background(), 42)
+	if err != nil {
+		t.Fatalf("error listing sessions: %v", err)
+	}
+	found := false
+	for _, session := range list {
+		found = found || session.SessionId == pair.SessionId
+	}
+	if !found {
+		t.Errorf("error: created session is not listed")
+	}
+
+	if err = sessions.Revoke(context.Background(), 42, pair.SessionId); err != nil {
+		t.Fatalf("error revoking session: %v", err)
+	}
+	if _, _, err = verifier.ValidateToken(context.Background(), pair.AccessToken); !errors.Is(err, tokens.ErrSessionRevoked) {
+		t.Errorf("error: access token of a revoked session is accepted, err: %v", err)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	sessions, verifier := initTestSessions(t)
+
+	first, err := sessions.Create(context.Background(), sessionCustomer, tokens.SessionMeta{})
+	if err != nil {
+		t.Fatalf("error creating session: %v", err)
+	}
+
+	second, err := sessions.Refresh(context.Background(), first.RefreshToken, tokens.SessionMeta{})
+	if err != nil {
+		t.Fatalf("error refreshing session: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.SessionId != first.SessionId {
+		t.Errorf("error: refresh token is not rotated within the session")
+	}
+
+	customer, _, err := verifier.ValidateToken(context.Background(), second.AccessToken)
+	if err != nil {
+		t.Fatalf("error validating refreshed access token: %v", err)
+	}
+	if customer.Role != "ADMIN" {
+		t.Errorf("error: refreshed token role is %s, expected the current one", customer.Role)
+	}
+
+	// presenting the rotated token again revokes the whole family
+	if _, err = sessions.Refresh(context.Background(), first.RefreshToken, tokens.SessionMeta{}); !errors.Is(err, tokens.ErrRefreshTokenReuse) {
+		t.Fatalf("error: reused refresh token is not detected, err: %v", err)
+	}
+	if _, err = sessions.Refresh(context.Background(), second.RefreshToken, tokens.SessionMeta{}); !errors.Is(err, tokens.ErrInvalidRefreshToken) {
+		t.Errorf("error: refresh token of a revoked family is accepted, err: %v", err)
+	}
+	if _, _, err = verifier.ValidateToken(context.Background(), second.AccessToken); err == nil {
+		t.Errorf("error: access token of a revoked family is accepted")
+	}
+}
+
+func TestFailedRefreshKeepsToken(t *testing.T) {
+	cfg := config.AuthConfig{
+		SessionSecret:   "test-secret",
+		TokenCacheTTL:   time.Minute,
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}
+	auth := &fakeAuthClient{}
+	verifier, err := tokens.InitVerifier(cfg, cache.InitCacheService(), auth)
+	if err != nil {
+		t.Fatalf("error initializing verifier: %v", err)
+	}
+	sessions := tokens.InitSessions(cfg, verifier, cache.InitCacheService())
+
+	pair, err := sessions.Create(context.Background(), sessionCustomer, tokens.SessionMeta{})
+	if err != nil {
+		t.Fatalf("error creating session: %v", err)
+	}
+
+	auth.customerErr = errors.New("auth service is unavailable")
+	if _, err = sessions.Refresh(context.Background(), pair.RefreshToken, tokens.SessionMeta{}); err == nil {
+		t.Fatal("error: refresh succeeded without the customer")
+	}
+
+	// the retry with the same token isn't taken for a reuse
+	auth.customerErr = nil
+	if _, err = sessions.Refresh(context.Background(), pair.RefreshToken, tokens.SessionMeta{}); err != nil {
+		t.Errorf("error: retried refresh is rejected, err: %v", err)
+	}
+}
+
+func TestBlockedCustomer(t *testing.T) {
+	sessions, verifier := initTestSessions(t)
+	store := cache.InitCacheService()
+	blocked := &authPb.CustomerDto{CustomerId: 43, Email: "blocked@test.com", Role: "USER"}
+	defer store.UnblockCustomer(context.Background(), blocked.CustomerId)
+
+	pair, err := sessions.Create(context.Background(), blocked, tokens.SessionMeta{})
+	if err != nil {
+		t.Fatalf("error creating session: %v", err)
+	}
+
+	// the verified token is cached before the block
+	if _, _, err = verifier.ValidateToken(context.Background(), pair.AccessToken); err != nil {
+		t.Fatalf("error validating access token: %v", err)
+	}
+
+	if err = store.BlockCustomer(context.Background(), blocked.CustomerId); err != nil {
+		t.Fatalf("error blocking customer: %v", err)
+	}
+	if _, _, err = verifier.ValidateToken(context.Background(), pair.AccessToken); !errors.Is(err, tokens.ErrCustomerBlocked) {
+		t.Errorf("error: access token of a blocked customer is accepted, err: %v", err)
+	}
+	if _, err = sessions.Refresh(context.Background(), pair.RefreshToken, tokens.SessionMeta{}); !errors.Is(err, tokens.ErrCustomerBlocked) {
+		t.Errorf("error: refresh token of a blocked customer is accepted, err: %v", err)
+	}
+
+	if err = store.UnblockCustomer(context.Background(), blocked.CustomerId); err != nil {
+		t.Fatalf("error unblocking customer: %v", err)
+	}
+	if _, _, err = verifier.ValidateToken(context.Background(), pair.AccessToken); err != nil {
+		t.Errorf("error: access token of an unblocked customer is rejected, err: %v", err)
+	}
+}
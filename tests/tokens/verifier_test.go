@@ -0,0 +1,222 @@
+package tokens_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/tokens"
+	"google.golang.org/grpc"
+)
+
+// jwksServer -> a JWKS endpoint with replaceable keys to emulate a rotation
+type jwksServer struct {
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func (s *jwksServer) setKeys(keys map[string]*rsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var set []map[string]string
+	for kid, key := range s.keys {
+		set = append(set, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]any{"keys": set})
+}
+
+// fakeAuthClient -> validates only the "opaque" token
+type fakeAuthClient struct {
+	authPb.AuthServiceClient
+	calls       int
+	customerErr error
+}
+
+func (f *fakeAuthClient) ValidateToken(ctx context.Context, in *authPb.ValidateTokenRequest, opts ...grpc.CallOption) (*authPb.CustomerDto, error) {
+	f.calls++
+	if in.Token != "opaque" {
+		return nil, errors.New("invalid token")
+	}
+	return &authPb.CustomerDto{CustomerId: 7, Role: "USER"}, nil
+}
+
+func (f *fakeAuthClient) GetCustomer(ctx context.Context, in *authPb.GetCustomerByEmailRequest, opts ...grpc.CallOption) (*authPb.CustomerDto, error) {
+	if f.customerErr != nil {
+		return nil, f.customerErr
+	}
+	return &authPb.CustomerDto{CustomerId: 42, Email: in.Email, Role: "ADMIN"}, nil
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return key
+}
+
+func signToken(t *testing.T, kid string, key *rsa.PrivateKey, claims tokens.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	return signed
+}
+
+func customerClaims(id string, ttl time.Duration) tokens.Claims {
+	return tokens.Claims{
+		Email: "test@test.com",
+		Role:  "USER",
+		Scope: "orders:read orders:write",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   "42",
+			Issuer:    "anvil-auth",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+}
+
+func initTestVerifier(t *testing.T, fallback bool) (*tokens.Verifier, *jwksServer, *rsa.PrivateKey, *fakeAuthClient) {
+	key := generateKey(t)
+	jwks := &jwksServer{keys: map[string]*rsa.PrivateKey{"key-1": key}}
+	server := httptest.NewServer(jwks)
+	t.Cleanup(server.Close)
+
+	authClient := &fakeAuthClient{}
+	verifier, err := tokens.InitVerifier(config.AuthConfig{
+		JWKSUrl:        server.URL,
+		Issuer:         "anvil-auth",
+		TokenCacheTTL:  time.Minute,
+		RemoteFallback: fallback,
+	}, cache.InitCacheService(), authClient)
+	if err != nil {
+		t.Fatalf("error initializing verifier: %v", err)
+	}
+	return verifier, jwks, key, authClient
+}
+
+func TestValidateToken(t *testing.T) {
+	verifier, _, key, authClient := initTestVerifier(t, true)
+
+	customer, grant, err := verifier.ValidateToken(context.Background(), signToken(t, "key-1", key, customerClaims("valid", time.Minute)))
+	if err != nil {
+		t.Fatalf("error validating token: %v", err)
+	}
+	if customer.CustomerId != 42 || customer.Role != "USER" {
+		t.Errorf("error: unexpected customer %+v", customer)
+	}
+	if len(grant.Scopes) != 2 || grant.Scopes[0] != "orders:read" {
+		t.Errorf("error: unexpected scopes %v", grant.Scopes)
+	}
+	if authClient.calls != 0 {
+		t.Errorf("error: locally verified token was validated remotely")
+	}
+}
+
+func TestValidateRejectedTokens(t *testing.T) {
+	verifier, _, key, _ := initTestVerifier(t, true)
+	other := generateKey(t)
+
+	expired := customerClaims("expired", -time.Minute)
+	wrongIssuer := customerClaims("issuer", time.Minute)
+	wrongIssuer.Issuer = "someone-else"
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "expired token", token: signToken(t, "key-1", key, expired)},
+		{name: "wrong issuer", token: signToken(t, "key-1", key, wrongIssuer)},
+		{name: "forged signature", token: signToken(t, "key-1", other, customerClaims("forged", time.Minute))},
+		{name: "unknown opaque token", token: "not-a-jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := verifier.ValidateToken(context.Background(), tt.token); err == nil {
+				t.Errorf("error: token is accepted")
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	verifier, jwks, _, _ := initTestVerifier(t, false)
+
+	rotated := generateKey(t)
+	jwks.setKeys(map[string]*rsa.PrivateKey{"key-2": rotated})
+
+	_, _, err := verifier.ValidateToken(context.Background(), signToken(t, "key-2", rotated, customerClaims("rotated", time.Minute)))
+	if err != nil {
+		t.Errorf("error validating token signed by a rotated key: %v", err)
+	}
+}
+
+func TestRemoteFallback(t *testing.T) {
+	verifier, _, _, authClient := initTestVerifier(t, true)
+
+	customer, _, err := verifier.ValidateToken(context.Background(), "opaque")
+	if err != nil {
+		t.Fatalf("error validating token remotely: %v", err)
+	}
+	if customer.CustomerId != 7 || authClient.calls != 1 {
+		t.Errorf("error: token is not validated by the auth service")
+	}
+
+	// the second call is served from the cache
+	if _, _, err = verifier.ValidateToken(context.Background(), "opaque"); err != nil || authClient.calls != 1 {
+		t.Errorf("error: cached token is validated again, calls: %d, err: %v", authClient.calls, err)
+	}
+
+	strict, _, _, strictClient := initTestVerifier(t, false)
+	if _, _, err = strict.ValidateToken(context.Background(), "opaque"); err == nil || strictClient.calls != 0 {
+		t.Errorf("error: token is validated remotely with the fallback disabled")
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	verifier, _, key, _ := initTestVerifier(t, true)
+	token := signToken(t, "key-1", key, customerClaims("revoked-"+time.Now().Format(time.RFC3339Nano), time.Minute))
+
+	if _, _, err := verifier.ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("error validating token: %v", err)
+	}
+	if err := verifier.Revoke(context.Background(), token); err != nil {
+		t.Fatalf("error revoking token: %v", err)
+	}
+
+	_, _, err := verifier.ValidateToken(context.Background(), token)
+	if !errors.Is(err, tokens.ErrTokenRevoked) {
+		t.Errorf("error: revoked token is accepted, err: %v", err)
+	}
+}
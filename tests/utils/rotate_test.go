@@ -0,0 +1,39 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	loggers "github.com/noo8xl/anvil-gateway/utils/loggers"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "LOGS", "access.log")
+	file, err := loggers.InitRotatingFile(path, 20, 2, time.Hour)
+	if err != nil {
+		t.Fatalf("InitRotatingFile() error = %v", err)
+	}
+	defer file.Close()
+
+	line := []byte(strings.Repeat("x", 15) + "\n")
+	for range 5 {
+		if _, err := file.Write(line); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		// the backups are named by the rotation time
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil || string(current) != string(line) {
+		t.Errorf("the current file should hold the last line only, got %q (%v)", current, err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "access-*.log"))
+	if len(backups) != 2 {
+		t.Errorf("want 2 backups kept, got %d: %v", len(backups), backups)
+	}
+}
@@ -0,0 +1,290 @@
+package tokens
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrKeyUnavailable -> the token is signed by a key the gateway doesn't know (yet)
+var ErrKeyUnavailable = errors.New("tokens: signing key is not available")
+
+// minRefreshInterval -> limit key reloads triggered by unknown key ids
+const minRefreshInterval = 30 * time.Second
+
+// KeySet -> public keys of the auth service loaded from a JWKS endpoint or a PEM file
+//
+// the set is reloaded periodically and on an unknown key id,
+// so a key rotation doesn't require a gateway restart
+type KeySet struct {
+	jwksUrl string
+	keyFile string
+	client  *http.Client
+
+	mu       sync.RWMutex
+	keys     map[string]crypto.PublicKey
+	lastMiss time.Time
+}
+
+func InitKeySet(jwksUrl, keyFile string) *KeySet {
+	return &KeySet{
+		jwksUrl: jwksUrl,
+		keyFile: keyFile,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		keys:    make(map[string]crypto.PublicKey),
+	}
+}
+
+// IsConfigured -> check if any key source is set
+func (k *KeySet) IsConfigured() bool {
+	return k.jwksUrl != "" || k.keyFile != ""
+}
+
+// Refresh -> reload the keys from the configured source,
+// the current keys are kept if the reload fails
+func (k *KeySet) Refresh(ctx context.Context) error {
+	var keys map[string]crypto.PublicKey
+	var err error
+
+	switch {
+	case k.jwksUrl != "":
+		keys, err = k.fetchJWKS(ctx)
+	case k.keyFile != "":
+		keys, err = readKeyFile(k.keyFile)
+	default:
+		return errors.New("tokens: neither a JWKS url nor a key file is configured")
+	}
+
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("tokens: key source has no supported keys")
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// Run -> reload the keys every interval until the context is done
+func (k *KeySet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			k.Refresh(ctx)
+		}
+	}
+}
+
+// Lookup -> get a key by its id, a token without a key id
+// can be verified only if the set has a single key
+func (k *KeySet) Lookup(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := k.get(kid); ok {
+		return key, nil
+	}
+
+	// an unknown key id usually means the keys were rotated
+	k.mu.Lock()
+	stale := time.Since(k.lastMiss) >= minRefreshInterval
+	if stale {
+		k.lastMiss = time.Now()
+	}
+	k.mu.Unlock()
+
+	if stale {
+		if err := k.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyUnavailable, err)
+		}
+		if key, ok := k.get(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, ErrKeyUnavailable
+}
+
+func (k *KeySet) get(kid string) (crypto.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// ############################################################################
+
+// jwk -> a single JSON web key (RFC 7517), only the public parts are used
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *KeySet) fetchJWKS(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.jwksUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tokens: fetch JWKS: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tokens: fetch JWKS: unexpected status %d", res.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("tokens: decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		pub, err := key.publicKey()
+		if err != nil {
+			// skip unsupported keys instead of rejecting the whole set
+			continue
+		}
+		keys[key.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("tokens: unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("tokens: unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("tokens: invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("tokens: unsupported key type %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// readKeyFile -> read every public key or certificate from a PEM file,
+// a key id of the block is taken from the "kid" PEM header if present
+func readKeyFile(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("tokens: read key file: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for i := 0; ; i++ {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var pub crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				pub = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tokens: parse key file: %w", err)
+		}
+
+		kid := block.Headers["kid"]
+		if kid == "" {
+			kid = fmt.Sprintf("file-%d", i)
+		}
+		keys[kid] = pub
+	}
+	return keys, nil
+}
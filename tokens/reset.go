@@ -0,0 +1,74 @@
+package tokens
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+)
+
+// ErrInvalidResetToken -> the reset token is forged, expired or was already used
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResets -> issue and consume single-use password reset tokens
+//
+// a token is "{id}.{signature}", the signature is checked before
+// the redis lookup so forged tokens are rejected without touching the store
+type PasswordResets struct {
+	secret []byte
+	store  *cache.CacheService
+	ttl    time.Duration
+}
+
+func InitPasswordResets(cfg config.AuthConfig, verifier *Verifier, store *cache.CacheService) *PasswordResets {
+	return &PasswordResets{
+		secret: verifier.secret,
+		store:  store,
+		ttl:    cfg.PasswordResetTTL,
+	}
+}
+
+// Issue -> create a reset token for the customer,
+// the previous pending token of the customer stops working
+func (p *PasswordResets) Issue(ctx context.Context, customerId uint64, email string) (string, error) {
+	resetId, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	reset := &cache.PasswordReset{
+		CustomerId: customerId,
+		Email:      email,
+	}
+	if err = p.store.SetPasswordReset(ctx, resetId, reset, p.ttl); err != nil {
+		return "", err
+	}
+
+	return resetId + "." + p.sign(resetId), nil
+}
+
+// Consume -> check the token and get the reset request it was issued for
+func (p *PasswordResets) Consume(ctx context.Context, token string) (*cache.PasswordReset, error) {
+	resetId, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(p.sign(resetId))) {
+		return nil, ErrInvalidResetToken
+	}
+
+	reset, err := p.store.ConsumePasswordReset(ctx, resetId)
+	if err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	return reset, nil
+}
+
+func (p *PasswordResets) sign(resetId string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte("password-reset:" + resetId))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,247 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+)
+
+// ErrInvalidRefreshToken -> the refresh token is unknown, expired or its session is revoked
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReuse -> an already rotated refresh token was presented again,
+// the whole token family (session) is revoked in this case
+var ErrRefreshTokenReuse = errors.New("refresh token reuse detected, session is revoked")
+
+// TokenPair -> tokens issued for a session
+type TokenPair struct {
+	SessionId    string `json:"sessionId"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+// SessionMeta -> client details saved with the session
+type SessionMeta struct {
+	Ip        string
+	UserAgent string
+}
+
+// Sessions -> issue session bound access tokens and rotate refresh tokens
+type Sessions struct {
+	verifier   *Verifier
+	store      *cache.CacheService
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	audience   string
+}
+
+func InitSessions(cfg config.AuthConfig, verifier *Verifier, store *cache.CacheService) *Sessions {
+	return &Sessions{
+		verifier:   verifier,
+		store:      store,
+		accessTTL:  cfg.AccessTokenTTL,
+		refreshTTL: cfg.RefreshTokenTTL,
+		audience:   cfg.Audience,
+	}
+}
+
+// Create -> start a new session for a signed in customer
+func (s *Sessions) Create(ctx context.Context, customer *authPb.CustomerDto, meta SessionMeta) (*TokenPair, error) {
+	sessionId, err := randomId()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &cache.Session{
+		SessionId:  sessionId,
+		CustomerId: customer.CustomerId,
+		Email:      customer.Email,
+		Ip:         meta.Ip,
+		UserAgent:  meta.UserAgent,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+
+	if err = s.store.SetSession(ctx, session, hashToken(refreshToken), s.refreshTTL); err != nil {
+		return nil, err
+	}
+
+	return s.issue(customer, sessionId, refreshToken)
+}
+
+// Refresh -> rotate the refresh token and issue a new access token,
+// the customer is fetched again so a changed role is applied on refresh
+func (s *Sessions) Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (*TokenPair, error) {
+	refreshHash := hashToken(refreshToken)
+
+	sessionId, err := s.store.GetRefreshTokenSession(ctx, refreshHash)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.store.GetSession(ctx, sessionId)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	blocked, err := s.store.IsCustomerBlocked(ctx, session.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrCustomerBlocked
+	}
+
+	customer, err := s.verifier.authClient.GetCustomer(ctx, &authPb.GetCustomerByEmailRequest{Email: session.Email})
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session.Ip = meta.Ip
+	session.UserAgent = meta.UserAgent
+	session.LastUsedAt = time.Now()
+	if err = s.store.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	// the rotation is the last step that can fail, so a failed refresh
+	// leaves the presented token current and the client may retry it
+	rotated, err := s.store.RotateRefreshToken(ctx, sessionId, refreshHash, hashToken(next), s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		if err = s.Revoke(ctx, session.CustomerId, sessionId); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReuse
+	}
+
+	return s.issue(customer, sessionId, next)
+}
+
+// List -> get every active session of the customer
+func (s *Sessions) List(ctx context.Context, customerId uint64) ([]*cache.Session, error) {
+	return s.store.GetCustomerSessions(ctx, customerId)
+}
+
+// Revoke -> end the customer session, its access tokens are rejected
+// by every gateway instance once their cached verification expires
+func (s *Sessions) Revoke(ctx context.Context, customerId uint64, sessionId string) error {
+	session, err := s.store.GetSession(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+	if session.CustomerId != customerId {
+		return errors.New("session not found")
+	}
+
+	if err = s.store.DeleteSession(ctx, customerId, sessionId); err != nil {
+		return err
+	}
+	s.verifier.forgetSession(sessionId)
+	return nil
+}
+
+// RevokeAll -> end every session of the customer, e.g. after a password reset
+func (s *Sessions) RevokeAll(ctx context.Context, customerId uint64) error {
+	sessions, err := s.store.GetCustomerSessions(ctx, customerId)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if err = s.store.DeleteSession(ctx, customerId, session.SessionId); err != nil {
+			return err
+		}
+		s.verifier.forgetSession(session.SessionId)
+	}
+	return nil
+}
+
+// Logout -> end the session of the access token, tokens issued
+// by the auth service are put to the revocation list instead
+func (s *Sessions) Logout(ctx context.Context, customerId uint64, sessionId, accessToken string) error {
+	if sessionId != "" {
+		return s.Revoke(ctx, customerId, sessionId)
+	}
+	return s.verifier.Revoke(ctx, accessToken)
+}
+
+// issue -> sign a session bound access token
+func (s *Sessions) issue(customer *authPb.CustomerDto, sessionId, refreshToken string) (*TokenPair, error) {
+	tokenId, err := randomId()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Email:     customer.Email,
+		Role:      customer.Role,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenId,
+			Issuer:    gatewayIssuer,
+			Subject:   strconv.FormatUint(customer.CustomerId, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+		},
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = gatewayIssuer
+
+	accessToken, err := token.SignedString(s.verifier.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		SessionId:    sessionId,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
+}
+
+// randomId -> a random hex id for sessions and token ids
+func randomId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// randomToken -> an opaque url safe refresh token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
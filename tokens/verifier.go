@@ -0,0 +1,345 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	authPb "github.com/noo8xl/anvil-api/main/auth"
+	"github.com/noo8xl/anvil-gateway/cache"
+	"github.com/noo8xl/anvil-gateway/config"
+	"github.com/noo8xl/anvil-gateway/middlewares"
+)
+
+// ErrTokenRevoked -> the token was revoked by a logout
+var ErrTokenRevoked = errors.New("token is revoked")
+
+// ErrSessionRevoked -> the session of the token was revoked or has expired
+var ErrSessionRevoked = errors.New("session is revoked")
+
+// ErrCustomerBlocked -> the token customer was blocked by an admin
+var ErrCustomerBlocked = errors.New("account is blocked")
+
+// gatewayIssuer -> issuer and key id of the access tokens signed by the gateway itself
+const gatewayIssuer = "anvil-gateway"
+
+// maxCachedTokens -> upper bound of the verified tokens kept in memory
+const maxCachedTokens = 10000
+
+// Claims -> access token claims issued by the auth service or the gateway,
+// only the gateway issued tokens are bound to a session
+type Claims struct {
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Scope     string `json:"scope"`
+	SessionId string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Verifier -> verify access tokens locally by the auth service public keys
+// or by the gateway session secret
+//
+// a verified token is cached for a short time, so a revoked token
+// may be accepted by other gateway instances until its cache entry expires
+type Verifier struct {
+	keys       *KeySet
+	secret     []byte
+	issuer     string
+	parser     *jwt.Parser
+	revocation *cache.CacheService
+	authClient authPb.AuthServiceClient
+	fallback   bool
+	cacheTTL   time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]verifiedToken
+}
+
+// verifiedToken -> a cached verification result
+type verifiedToken struct {
+	customer *authPb.CustomerDto
+	grant    middlewares.TokenGrant
+	tokenId  string
+	expires  time.Time
+}
+
+// InitVerifier -> create a token verifier and load the signing keys,
+// a failed initial load is an error only if the remote fallback is disabled
+//
+// an empty session secret is replaced by a random one,
+// so the gateway issued tokens don't survive a restart
+func InitVerifier(cfg config.AuthConfig, revocation *cache.CacheService, authClient authPb.AuthServiceClient) (*Verifier, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(5 * time.Second),
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	secret := []byte(cfg.SessionSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	v := &Verifier{
+		keys:       InitKeySet(cfg.JWKSUrl, cfg.KeyFile),
+		secret:     secret,
+		issuer:     cfg.Issuer,
+		parser:     jwt.NewParser(opts...),
+		revocation: revocation,
+		authClient: authClient,
+		fallback:   cfg.RemoteFallback,
+		cacheTTL:   cfg.TokenCacheTTL,
+		tokens:     make(map[string]verifiedToken),
+	}
+
+	if !v.keys.IsConfigured() {
+		if !v.fallback && cfg.SessionSecret == "" {
+			return nil, errors.New("tokens: no signing keys configured and the remote fallback is disabled")
+		}
+		return v, nil
+	}
+
+	if err := v.keys.Refresh(context.Background()); err != nil && !v.fallback {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Keys -> get the signing key set to run its rotation
+func (v *Verifier) Keys() *KeySet {
+	return v.keys
+}
+
+// ValidateToken -> get the token customer, its granted scopes and session
+//
+// the token is verified locally if possible; it's validated by the auth service
+// only if the fallback is enabled and the token signing key is not available
+func (v *Verifier) ValidateToken(ctx context.Context, token string) (*authPb.CustomerDto, middlewares.TokenGrant, error) {
+	key := hashToken(token)
+	if cached, ok := v.cached(key); ok {
+		// a block applies to every instance at once, so it's checked on the cached tokens too
+		if err := v.checkBlocked(ctx, cached.customer); err != nil {
+			return nil, middlewares.TokenGrant{}, err
+		}
+		return cached.customer, cached.grant, nil
+	}
+
+	result, err := v.verify(ctx, token)
+	if err != nil {
+		return nil, middlewares.TokenGrant{}, err
+	}
+
+	revoked, err := v.revocation.IsTokenRevoked(ctx, result.tokenId)
+	if err != nil {
+		return nil, middlewares.TokenGrant{}, fmt.Errorf("cannot check the token revocation: %w", err)
+	}
+	if revoked {
+		return nil, middlewares.TokenGrant{}, ErrTokenRevoked
+	}
+
+	if result.grant.SessionId != "" {
+		active, err := v.revocation.IsSessionActive(ctx, result.grant.SessionId)
+		if err != nil {
+			return nil, middlewares.TokenGrant{}, fmt.Errorf("cannot check the token session: %w", err)
+		}
+		if !active {
+			return nil, middlewares.TokenGrant{}, ErrSessionRevoked
+		}
+	}
+
+	if err = v.checkBlocked(ctx, result.customer); err != nil {
+		return nil, middlewares.TokenGrant{}, err
+	}
+
+	v.store(key, result)
+	return result.customer, result.grant, nil
+}
+
+// checkBlocked -> ErrCustomerBlocked if the token customer is blocked
+func (v *Verifier) checkBlocked(ctx context.Context, customer *authPb.CustomerDto) error {
+	if customer == nil {
+		return nil
+	}
+
+	blocked, err := v.revocation.IsCustomerBlocked(ctx, customer.CustomerId)
+	if err != nil {
+		return fmt.Errorf("cannot check the token customer: %w", err)
+	}
+	if blocked {
+		return ErrCustomerBlocked
+	}
+	return nil
+}
+
+// Revoke -> put a valid token to the revocation list until it expires
+func (v *Verifier) Revoke(ctx context.Context, token string) error {
+	result, err := v.verify(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(result.expires)
+	if result.expires.IsZero() {
+		// remotely validated tokens don't expose their expiration
+		ttl = 24 * time.Hour
+	}
+	if err = v.revocation.RevokeToken(ctx, result.tokenId, ttl); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	delete(v.tokens, hashToken(token))
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Verifier) verify(ctx context.Context, token string) (verifiedToken, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		// the key type is checked by the signing method,
+		// so the secret is never used to verify a public key signature
+		if kid == gatewayIssuer {
+			if claims.Issuer != gatewayIssuer {
+				return nil, jwt.ErrTokenInvalidIssuer
+			}
+			return v.secret, nil
+		}
+
+		if v.issuer != "" && claims.Issuer != v.issuer {
+			return nil, jwt.ErrTokenInvalidIssuer
+		}
+		if !v.keys.IsConfigured() {
+			return nil, ErrKeyUnavailable
+		}
+		return v.keys.Lookup(ctx, kid)
+	})
+
+	if err != nil {
+		// opaque or unknown-key tokens can still be issued by the auth service
+		if v.fallback && (errors.Is(err, ErrKeyUnavailable) || errors.Is(err, jwt.ErrTokenMalformed)) {
+			return v.validateRemotely(ctx, token)
+		}
+		return verifiedToken{}, err
+	}
+
+	return claims.verified(token)
+}
+
+func (v *Verifier) validateRemotely(ctx context.Context, token string) (verifiedToken, error) {
+	customer, err := v.authClient.ValidateToken(ctx, &authPb.ValidateTokenRequest{
+		Token: token,
+	})
+	if err != nil {
+		return verifiedToken{}, err
+	}
+
+	return verifiedToken{
+		customer: customer,
+		tokenId:  hashToken(token),
+	}, nil
+}
+
+// verified -> convert the claims to the verification result
+func (c *Claims) verified(token string) (verifiedToken, error) {
+	customerId, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return verifiedToken{}, fmt.Errorf("%w: invalid subject", jwt.ErrTokenInvalidClaims)
+	}
+
+	tokenId := c.ID
+	if tokenId == "" {
+		tokenId = hashToken(token)
+	}
+
+	return verifiedToken{
+		customer: &authPb.CustomerDto{
+			CustomerId: customerId,
+			Email:      c.Email,
+			Role:       c.Role,
+		},
+		grant: middlewares.TokenGrant{
+			Scopes:    strings.Fields(c.Scope),
+			SessionId: c.SessionId,
+		},
+		tokenId: tokenId,
+		expires: c.ExpiresAt.Time,
+	}, nil
+}
+
+// ############################################################################
+
+// forgetSession -> drop the cached tokens of a revoked session
+func (v *Verifier) forgetSession(sessionId string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for key, cached := range v.tokens {
+		if cached.grant.SessionId == sessionId {
+			delete(v.tokens, key)
+		}
+	}
+}
+
+func (v *Verifier) cached(key string) (verifiedToken, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cached, ok := v.tokens[key]
+	if !ok {
+		return verifiedToken{}, false
+	}
+	if time.Now().After(cached.expires) {
+		delete(v.tokens, key)
+		return verifiedToken{}, false
+	}
+	return cached, true
+}
+
+func (v *Verifier) store(key string, result verifiedToken) {
+	if v.cacheTTL <= 0 {
+		return
+	}
+
+	expires := time.Now().Add(v.cacheTTL)
+	if !result.expires.IsZero() && result.expires.Before(expires) {
+		expires = result.expires
+	}
+	result.expires = expires
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.tokens) >= maxCachedTokens {
+		now := time.Now()
+		for k, cached := range v.tokens {
+			if now.After(cached.expires) {
+				delete(v.tokens, k)
+			}
+		}
+		if len(v.tokens) >= maxCachedTokens {
+			v.tokens = make(map[string]verifiedToken)
+		}
+	}
+	v.tokens[key] = result
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/noo8xl/anvil-gateway/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// InitAccessLog -> a json logger writing to the configured access log sinks,
+// the returned func flushes and closes the sinks
+func InitAccessLog(cfg config.AccessLogConfig) (*zap.Logger, func() error, error) {
+	writers := []zapcore.WriteSyncer{}
+	closers := []func() error{}
+
+	for _, sink := range cfg.Sinks {
+		switch sink {
+		case "stdout":
+			writers = append(writers, zapcore.Lock(os.Stdout))
+		case "file":
+			file, err := InitRotatingFile(cfg.FilePath, cfg.MaxSize, cfg.MaxBackups, cfg.MaxAge)
+			if err != nil {
+				return nil, nil, err
+			}
+			writers = append(writers, file)
+			closers = append(closers, file.Close)
+		default:
+			return nil, nil, fmt.Errorf("unknown access log sink %q", sink)
+		}
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "time"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.NewMultiWriteSyncer(writers...), zap.InfoLevel)
+	logger := zap.New(core).Named("access")
+
+	return logger, func() error {
+		logger.Sync()
+		var err error
+		for _, closeSink := range closers {
+			if closeErr := closeSink(); closeErr != nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}